@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"eatsavvy/internal/api"
 	"eatsavvy/internal/config"
 
@@ -15,5 +16,11 @@ func main() {
 		slog.Error("[api.main] Failed to load .env file", "error", err)
 	}
 
-	api.StartServer("8080")
+	dependencies := api.NewDependencies()
+	defer dependencies.Close()
+
+	server := api.NewServer(api.ConfigFromEnv(), dependencies)
+	if err := server.Run(context.Background()); err != nil {
+		slog.Error("[api.main] Server stopped", "error", err)
+	}
 }
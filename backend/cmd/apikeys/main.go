@@ -0,0 +1,89 @@
+package main
+
+import (
+	"eatsavvy/internal/apikeys"
+	"eatsavvy/internal/config"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"log/slog"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage:
+  apikeys create -name <owner> -scopes read,search,enrich [-expires 720h]
+  apikeys list
+  apikeys revoke <id>`
+
+func main() {
+	err := godotenv.Load(config.GetEnvFile())
+	if err != nil {
+		slog.Error("[apikeys.main] Failed to load .env file", "error", err)
+	}
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	keysClient := apikeys.NewApiKeysClient()
+	defer keysClient.Close()
+
+	switch os.Args[1] {
+	case "create":
+		flags := flag.NewFlagSet("create", flag.ExitOnError)
+		name := flags.String("name", "", "who the key belongs to")
+		scopeList := flags.String("scopes", "", "comma separated scopes: read, search, enrich, webhook, vapi, admin")
+		expires := flags.Duration("expires", 0, "how long until the key expires (default never)")
+		flags.Parse(os.Args[2:])
+
+		scopes, err := apikeys.ParseScopes(strings.Split(*scopeList, ","))
+		if err != nil {
+			exit(err)
+		}
+		var expiresAt *time.Time
+		if *expires > 0 {
+			at := time.Now().Add(*expires)
+			expiresAt = &at
+		}
+		key, err := keysClient.CreateKey(*name, scopes, expiresAt)
+		if err != nil {
+			exit(err)
+		}
+		fmt.Printf("Created key %s for %s with scopes %v\n", key.Id, key.Name, key.Scopes)
+		fmt.Printf("%s\n(store it now, it can't be shown again)\n", key.Secret)
+	case "list":
+		keys, err := keysClient.ListKeys()
+		if err != nil {
+			exit(err)
+		}
+		for _, key := range keys {
+			status := "active"
+			if !key.Usable(time.Now()) {
+				status = "expired or revoked"
+			}
+			fmt.Printf("%s  %s...  %-20s %v  %s\n", key.Id, key.Prefix, key.Name, key.Scopes, status)
+		}
+	case "revoke":
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		key, err := keysClient.RevokeKey(os.Args[2])
+		if err != nil {
+			exit(err)
+		}
+		fmt.Printf("Revoked key %s for %s\n", key.Id, key.Name)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"eatsavvy/internal/config"
+	"eatsavvy/internal/oidc"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+// A stand-in OIDC provider for local development. Point OIDC_ISSUER_URL at it (http://localhost:9000 by default)
+// and sign in from the frontend with any email.
+func main() {
+	err := godotenv.Load(config.GetEnvFile())
+	if err != nil {
+		slog.Error("[devissuer.main] Failed to load .env file", "error", err)
+	}
+	issuerUrl := os.Getenv("OIDC_ISSUER_URL")
+	if issuerUrl == "" {
+		issuerUrl = "http://localhost:9000"
+	}
+	port := os.Getenv("DEV_ISSUER_PORT")
+	if port == "" {
+		port = "9000"
+	}
+
+	issuer, err := oidc.NewDevIssuer(issuerUrl)
+	if err != nil {
+		slog.Error("[devissuer.main] Failed to create issuer", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("[devissuer.main] Serving dev OIDC issuer", "issuer", issuerUrl, "port", port)
+	err = http.ListenAndServe(":"+port, issuer.Handler())
+	if err != nil {
+		slog.Error("[devissuer.main] Server stopped", "error", err)
+		os.Exit(1)
+	}
+}
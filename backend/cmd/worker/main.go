@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
 	"eatsavvy/internal/config"
+	"eatsavvy/internal/outbox"
+	"eatsavvy/internal/scheduler"
+	"eatsavvy/internal/webhooks"
 	"eatsavvy/internal/worker"
 
 	"log/slog"
@@ -14,6 +18,15 @@ func main() {
 	if err != nil {
 		slog.Error("[worker.main] Failed to load .env file", "error", err)
 	}
+	relay := outbox.NewRelay()
+	go relay.Start(context.Background())
+
+	refreshScheduler := scheduler.NewScheduler()
+	go refreshScheduler.Start(context.Background())
+
+	webhookDispatcher := webhooks.NewDispatcher()
+	go webhookDispatcher.Start(context.Background())
+
 	worker := worker.NewWorker()
 	worker.Start()
 }
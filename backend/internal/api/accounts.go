@@ -0,0 +1,125 @@
+package api
+
+import (
+	"eatsavvy/internal/apikeys"
+	"eatsavvy/internal/dietary"
+	"eatsavvy/internal/users"
+	netHttp "net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) getMe(c *gin.Context) {
+	user, _ := users.FromContext(c.Request.Context())
+	c.JSON(netHttp.StatusOK, user)
+}
+
+// getUsage returns the caller's enrichments and spend for the current UTC day and month, with their limits
+func (s *Server) getUsage(c *gin.Context) {
+	accountUsage, err := s.Usage.GetUsage(account(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, accountUsage)
+}
+
+func (s *Server) createDietaryProfile(c *gin.Context) {
+	var request dietary.Profile
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+	if _, err := dietary.Normalize(request); err != nil {
+		respondError(c, err)
+		return
+	}
+	profile, err := s.Dietary.CreateProfile(request, ownerId(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusCreated, profile)
+}
+
+func (s *Server) listDietaryProfiles(c *gin.Context) {
+	profiles, err := s.Dietary.ListProfiles(ownerId(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, profiles)
+}
+
+func (s *Server) getDietaryProfile(c *gin.Context) {
+	profile, err := s.Dietary.GetProfile(c.Param("id"), ownerId(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, profile)
+}
+
+func (s *Server) updateDietaryProfile(c *gin.Context) {
+	var request dietary.Profile
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+	if _, err := dietary.Normalize(request); err != nil {
+		respondError(c, err)
+		return
+	}
+	profile, err := s.Dietary.UpdateProfile(c.Param("id"), request, ownerId(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, profile)
+}
+
+func (s *Server) deleteDietaryProfile(c *gin.Context) {
+	err := s.Dietary.DeleteProfile(c.Param("id"), ownerId(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(netHttp.StatusNoContent)
+}
+
+func (s *Server) createApiKey(c *gin.Context) {
+	var request createApiKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+	scopes, err := apikeys.ParseScopes(request.Scopes)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	key, err := s.Keys.CreateKey(request.Name, scopes, request.ExpiresAt)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusCreated, key)
+}
+
+func (s *Server) listApiKeys(c *gin.Context) {
+	keys, err := s.Keys.ListKeys()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, keys)
+}
+
+func (s *Server) revokeApiKey(c *gin.Context) {
+	key, err := s.Keys.RevokeKey(c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, key)
+}
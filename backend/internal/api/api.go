@@ -1,9 +1,23 @@
 package api
 
 import (
+	"context"
+	"eatsavvy/internal/apikeys"
+	"eatsavvy/internal/config"
+	"eatsavvy/internal/dietary"
+	"eatsavvy/internal/enrichment"
+	"eatsavvy/internal/events"
+	"eatsavvy/internal/oidc"
 	"eatsavvy/internal/places"
+	"eatsavvy/internal/ratelimit"
+	"eatsavvy/internal/usage"
+	"eatsavvy/internal/users"
+	"eatsavvy/internal/webhooks"
+	"log/slog"
 	netHttp "net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -11,161 +25,197 @@ import (
 
 const MAX_ENRICHMENTS = 25
 
-func authMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		token := c.GetHeader("Authorization")
-		if token != "Bearer "+os.Getenv("EATSAVVY_API_KEY") {
-			c.JSON(netHttp.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			c.Abort()
+// Config is how the server is exposed
+type Config struct {
+	Port string
+	// AllowedOrigins are the browser origins allowed to call the API
+	AllowedOrigins []string
+	// TrustedPlatform is "cloudflare" behind the Cloudflare tunnel, so client addresses are read from
+	// CF-Connecting-IP
+	TrustedPlatform string
+	// TrustedProxies are the addresses or CIDRs whose X-Forwarded-For is believed. With none, the client address
+	// is the connection's, so callers can't pick their own rate limit bucket.
+	TrustedProxies []string
+}
+
+// ConfigFromEnv reads PORT (default 8080), CORS_ALLOWED_ORIGINS and TRUSTED_PROXIES (comma separated) and
+// TRUSTED_PLATFORM
+func ConfigFromEnv() Config {
+	config := Config{
+		Port:            os.Getenv("PORT"),
+		AllowedOrigins:  []string{"https://eatsavvy.org", "http://localhost:5173"},
+		TrustedPlatform: os.Getenv("TRUSTED_PLATFORM"),
+	}
+	if config.Port == "" {
+		config.Port = "8080"
+	}
+	if origins := os.Getenv("CORS_ALLOWED_ORIGINS"); origins != "" {
+		config.AllowedOrigins = strings.Split(origins, ",")
+	}
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		config.TrustedProxies = strings.Split(proxies, ",")
+	}
+	return config
+}
+
+// Dependencies are the services the handlers use
+type Dependencies struct {
+	Restaurants RestaurantService
+	Jobs        JobService
+	Webhooks    WebhookService
+	Dietary     DietaryService
+	Usage       UsageService
+	Events      *events.Broker
+	// Keys, Users and Verifier authenticate requests (see authMiddleware)
+	Keys     KeyService
+	Users    UserService
+	Verifier TokenVerifier
+	Limiter  RateLimiter
+}
+
+// NewDependencies connects every service using the environment
+func NewDependencies() Dependencies {
+	keys := apikeys.NewApiKeysClient()
+	importLegacyKey(keys)
+	return Dependencies{
+		Restaurants: places.NewRestaurantClient(),
+		Jobs:        enrichment.NewEnrichmentClient(),
+		Webhooks:    webhooks.NewWebhooksClient(),
+		Dietary:     dietary.NewDietaryClient(),
+		Usage:       usage.NewUsageClient(),
+		Events:      events.NewBroker(),
+		Keys:        keys,
+		Users:       users.NewUsersClient(),
+		Verifier:    oidc.NewVerifier(),
+		Limiter:     ratelimit.NewLimiter(),
+	}
+}
+
+// importLegacyKey keeps EATSAVVY_API_KEY, the shared key the API accepted before keys were minted, working for
+// the clients still deployed with it: it is imported with LEGACY_API_KEY_SCOPES (default read, search, enrich and
+// vapi, what it could do then) and expires LEGACY_API_KEY_TTL (default 720h) after it is first imported.
+func importLegacyKey(keys *apikeys.ApiKeysClient) {
+	scopeList := os.Getenv("LEGACY_API_KEY_SCOPES")
+	if scopeList == "" {
+		scopeList = "read,search,enrich,vapi"
+	}
+	scopes, err := apikeys.ParseScopes(strings.Split(scopeList, ","))
+	if err != nil {
+		slog.Error("[api.importLegacyKey] Invalid LEGACY_API_KEY_SCOPES, not importing the legacy key", "error", err)
+		return
+	}
+	expiresAt := time.Now().Add(config.GetEnvDuration("LEGACY_API_KEY_TTL", 30*24*time.Hour))
+	keys.ImportLegacyKey(os.Getenv("EATSAVVY_API_KEY"), scopes, expiresAt)
+}
+
+// Close closes the dependencies that hold connections
+func (d Dependencies) Close() {
+	for _, dependency := range []any{d.Restaurants, d.Jobs, d.Webhooks, d.Dietary, d.Usage, d.Keys, d.Users, d.Limiter} {
+		if closer, ok := dependency.(interface{ Close() }); ok {
+			closer.Close()
 		}
-		c.Next()
 	}
 }
 
-func StartServer(port string) {
+// Server is the HTTP API. Routes are served under /v1, and the unversioned paths the API started with are
+// kept as deprecated aliases for existing clients.
+type Server struct {
+	Dependencies
+	config Config
+	router *gin.Engine
+}
+
+func NewServer(config Config, dependencies Dependencies) *Server {
+	s := &Server{Dependencies: dependencies, config: config}
 	r := gin.New()
-	r.Use(gin.Recovery())
+	r.Use(requestId, gin.Recovery())
 	r.Use(gin.LoggerWithConfig(gin.LoggerConfig{
 		SkipPaths: []string{"/health"},
 	}))
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"https://eatsavvy.org", "http://localhost:5173"},
+		AllowOrigins:     config.AllowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", requestIdHeader},
+		ExposeHeaders:    []string{requestIdHeader, "Retry-After", "Deprecation", "Link"},
 		AllowCredentials: true,
 	}))
-	restaurantClient := places.NewRestaurantClient()
-	defer restaurantClient.Close()
+	if config.TrustedPlatform == "cloudflare" {
+		r.TrustedPlatform = gin.PlatformCloudflare
+	}
+	if err := r.SetTrustedProxies(config.TrustedProxies); err != nil {
+		slog.Error("[api.NewServer] Invalid TRUSTED_PROXIES, trusting no proxies", "error", err)
+		r.SetTrustedProxies(nil)
+	}
 
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(netHttp.StatusOK, gin.H{"status": "ok"})
 	})
+	s.routes(r.Group("/v1"))
+	s.routes(r.Group("/", deprecated))
+	s.router = r
+	return s
+}
 
-	authorized := r.Group("/")
-	authorized.Use(authMiddleware())
-
-	authorized.GET("/restaurant/:id", func(c *gin.Context) {
-		id := c.Param("id")
-		restaurant, err := restaurantClient.GetRestaurant(id)
-		if err != nil {
-			c.JSON(netHttp.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(netHttp.StatusOK, restaurant)
-	})
-
-	authorized.PATCH("/restaurant/:id", func(c *gin.Context) {
-		id := c.Param("id")
-		var request struct {
-			PhoneNumber string `json:"phoneNumber"`
-		}
-		if err := c.ShouldBindJSON(&request); err != nil {
-			c.JSON(netHttp.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		formattedPhone, err := formatPhoneNumber(request.PhoneNumber)
-		if err != nil {
-			c.JSON(netHttp.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		restaurant, err := restaurantClient.UpdateRestaurantPhoneNumber(id, formattedPhone)
-		if err != nil {
-			c.JSON(netHttp.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(netHttp.StatusOK, restaurant)
-	})
-
-	authorized.GET("/restaurant", func(c *gin.Context) {
-		restaurants, err := restaurantClient.GetAllRestaurants()
-		if err != nil {
-			c.JSON(netHttp.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(netHttp.StatusOK, restaurants)
-	})
-
-	authorized.POST("/search", func(c *gin.Context) {
-		var request struct {
-			Query string `json:"query"`
-		}
-
-		if err := c.ShouldBindJSON(&request); err != nil {
-			c.JSON(netHttp.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		restaurants, err := restaurantClient.SearchRestaurants(request.Query) // Magnin Cafe
-		if err != nil {
-			c.JSON(netHttp.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(netHttp.StatusOK, restaurants)
-	})
+// Handler serves the API's routes
+func (s *Server) Handler() netHttp.Handler {
+	return s.router
+}
 
-	authorized.POST("/enrich", func(c *gin.Context) {
-		var request struct {
-			Ids []string `json:"ids"`
-		}
-		if err := c.ShouldBindJSON(&request); err != nil {
-			c.JSON(netHttp.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		restaurants, err := restaurantClient.BatchEnrichRestaurantDetails(request.Ids)
-		if err != nil {
-			c.JSON(netHttp.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(netHttp.StatusOK, restaurants)
-	})
+// Run starts the event broker and serves the API on the configured port until it fails
+func (s *Server) Run(ctx context.Context) error {
+	go s.Events.Start(ctx)
+	return s.router.Run(":" + s.config.Port)
+}
 
-	authorized.POST("/search-and-enrich", func(c *gin.Context) {
-		var request struct {
-			Query string `json:"query"`
-		}
-		if err := c.ShouldBindJSON(&request); err != nil {
-			c.JSON(netHttp.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		restaurants, err := restaurantClient.SearchRestaurants(request.Query)
-		if err != nil {
-			c.JSON(netHttp.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		if len(restaurants) == 0 {
-			c.JSON(netHttp.StatusNotFound, gin.H{"error": "No restaurants found for query: " + request.Query})
-			return
-		}
-		if len(restaurants) > MAX_ENRICHMENTS {
-			c.JSON(netHttp.StatusBadRequest, gin.H{"error": "Too many restaurants found for query: " + request.Query + ". Please refine your query."})
-			return
-		}
-		ids := []string{}
-		for _, restaurant := range restaurants {
-			ids = append(ids, restaurant.Id)
-		}
-		enrichedRestaurants, err := restaurantClient.BatchEnrichRestaurantDetails(ids)
-		if err != nil {
-			c.JSON(netHttp.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(netHttp.StatusOK, enrichedRestaurants)
+// routes registers every route of the API on group
+func (s *Server) routes(group *gin.RouterGroup) {
+	// The OpenAPI document for this API, for generating clients
+	group.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(netHttp.StatusOK, "application/json", openapiSpec)
 	})
 
-	authorized.POST("/process-eocr", func(c *gin.Context) {
-		var request places.EndOfCallReportMessage
-		if err := c.ShouldBindJSON(&request); err != nil {
-			c.JSON(netHttp.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		err := restaurantClient.UpdateRestaurantNutritionInfo(request)
-		if err != nil {
-			c.JSON(netHttp.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(netHttp.StatusOK, gin.H{"status": "ok"})
-	})
+	authorized := group.Group("/")
+	authorized.Use(ipRateLimit(s.Limiter), authMiddleware(s.Keys, s.Verifier, s.Users), rateLimit(s.Limiter, ratelimit.ClassDefault))
+
+	authorized.GET("/me", requireUser, s.getMe)
+	authorized.GET("/me/restaurants", requireUser, s.getMyRestaurants)
+
+	authorized.GET("/restaurant", requireScope(apikeys.ScopeRead), s.listRestaurants)
+	authorized.GET("/restaurant/events", requireScope(apikeys.ScopeRead), s.streamRestaurantEvents)
+	authorized.GET("/restaurant/:id", requireScope(apikeys.ScopeRead), s.getRestaurant)
+	authorized.PATCH("/restaurant/:id", requireScope(apikeys.ScopeEnrich), s.updateRestaurant)
+	authorized.GET("/restaurant/:id/events", requireScope(apikeys.ScopeRead), s.streamRestaurant)
+	authorized.POST("/search", requireScope(apikeys.ScopeSearch), rateLimit(s.Limiter, ratelimit.ClassSearch), s.searchRestaurants)
+
+	authorized.POST("/enrich", requireScope(apikeys.ScopeEnrich), rateLimit(s.Limiter, ratelimit.ClassEnrich), s.enrichRestaurants)
+	authorized.POST("/search-and-enrich", requireScope(apikeys.ScopeSearch), requireScope(apikeys.ScopeEnrich), rateLimit(s.Limiter, ratelimit.ClassEnrich), s.searchAndEnrich)
+	authorized.GET("/jobs/:id", requireScope(apikeys.ScopeRead), s.getJob)
+	authorized.DELETE("/jobs/:id", requireScope(apikeys.ScopeEnrich), s.cancelJob)
+
+	authorized.GET("/usage", requireScope(apikeys.ScopeRead), s.getUsage)
+
+	authorized.POST("/dietary-profiles", requireScope(apikeys.ScopeEnrich), s.createDietaryProfile)
+	authorized.GET("/dietary-profiles", requireScope(apikeys.ScopeRead), s.listDietaryProfiles)
+	authorized.GET("/dietary-profiles/:id", requireScope(apikeys.ScopeRead), s.getDietaryProfile)
+	authorized.PUT("/dietary-profiles/:id", requireScope(apikeys.ScopeEnrich), s.updateDietaryProfile)
+	authorized.DELETE("/dietary-profiles/:id", requireScope(apikeys.ScopeEnrich), s.deleteDietaryProfile)
+
+	authorized.POST("/process-eocr", requireScope(apikeys.ScopeVapi), s.processEndOfCallReport)
+	authorized.POST("/webhooks", requireScope(apikeys.ScopeWebhook), s.createWebhook)
+	authorized.GET("/webhooks", requireScope(apikeys.ScopeWebhook), s.listWebhooks)
+	authorized.DELETE("/webhooks/:id", requireScope(apikeys.ScopeWebhook), s.deleteWebhook)
+	authorized.GET("/webhooks/:id/deliveries", requireScope(apikeys.ScopeWebhook), s.listWebhookDeliveries)
+
+	authorized.POST("/api-keys", requireScope(apikeys.ScopeAdmin), s.createApiKey)
+	authorized.GET("/api-keys", requireScope(apikeys.ScopeAdmin), s.listApiKeys)
+	authorized.DELETE("/api-keys/:id", requireScope(apikeys.ScopeAdmin), s.revokeApiKey)
+}
 
-	r.Run(":" + port)
+// deprecated marks responses from the unversioned routes, pointing at their /v1 successor, and has them answer in
+// the shapes clients built before versioning expect (see respondError and respondEnriched)
+func deprecated(c *gin.Context) {
+	c.Set(legacyKey, true)
+	c.Header("Deprecation", "true")
+	c.Header("Link", "</v1"+c.Request.URL.Path+`>; rel="successor-version"`)
+	c.Next()
 }
@@ -0,0 +1,659 @@
+package api
+
+import (
+	"eatsavvy/internal/apikeys"
+	"eatsavvy/internal/apperrors"
+	"eatsavvy/internal/dietary"
+	"eatsavvy/internal/enrichment"
+	"eatsavvy/internal/events"
+	"eatsavvy/internal/oidc"
+	"eatsavvy/internal/places"
+	"eatsavvy/internal/ratelimit"
+	"eatsavvy/internal/usage"
+	"eatsavvy/internal/users"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fakes embed the service they stand in for, so calling a method a test didn't expect panics
+
+type fakeRestaurants struct {
+	RestaurantService
+	restaurants map[string]places.Restaurant
+	// errs are returned for the ids in them instead of a restaurant
+	errs map[string]error
+}
+
+func (f *fakeRestaurants) GetRestaurant(placesId string) (places.Restaurant, error) {
+	if err, ok := f.errs[placesId]; ok {
+		return places.Restaurant{}, err
+	}
+	restaurant, ok := f.restaurants[placesId]
+	if !ok {
+		return places.Restaurant{}, places.ErrRestaurantNotFound
+	}
+	return restaurant, nil
+}
+
+// BatchEnrichRestaurantDetails enqueues the restaurants it knows and reports the rest as not found
+func (f *fakeRestaurants) BatchEnrichRestaurantDetails(restaurantIds []string, requester enrichment.Requester, options places.EnrichOptions) (enrichment.Job, []places.EnrichmentResult, error) {
+	results := []places.EnrichmentResult{}
+	for _, id := range restaurantIds {
+		restaurant, ok := f.restaurants[id]
+		if !ok {
+			results = append(results, places.EnrichmentResult{RestaurantId: id, Outcome: places.EnrichmentOutcomeNotFound, Error: "restaurant not found"})
+			continue
+		}
+		restaurant.EnrichmentStatus = places.EnrichmentStatusQueued
+		results = append(results, places.EnrichmentResult{RestaurantId: id, Outcome: places.EnrichmentOutcomeEnqueued, Restaurant: &restaurant})
+	}
+	return enrichment.Job{Id: "job-1", RequestedBy: requester.Name}, results, nil
+}
+
+type unmetered struct {
+	UsageService
+}
+
+func (unmetered) Allow(usage.Account, int) error {
+	return nil
+}
+
+type fakeKeys struct {
+	KeyService
+	keys map[string]apikeys.Key
+}
+
+func (f *fakeKeys) Authenticate(secret string) (apikeys.Key, error) {
+	key, ok := f.keys[secret]
+	if !ok {
+		return apikeys.Key{}, apikeys.ErrInvalidKey
+	}
+	return key, nil
+}
+
+type disabledVerifier struct {
+	TokenVerifier
+}
+
+func (disabledVerifier) Enabled() bool {
+	return false
+}
+
+// fakeVerifier accepts the tokens it has claims for, signing in as the claims' subject (see fakeUsers)
+type fakeVerifier struct {
+	claims map[string]oidc.Claims
+}
+
+func (fakeVerifier) Enabled() bool {
+	return true
+}
+
+func (f fakeVerifier) Verify(token string) (oidc.Claims, error) {
+	claims, ok := f.claims[token]
+	if !ok {
+		return oidc.Claims{}, oidc.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+type fakeUsers struct {
+	UserService
+}
+
+func (fakeUsers) UpsertUser(issuer string, subject string, email string, name string) (users.User, error) {
+	return users.User{Id: subject, Issuer: issuer, Subject: subject}, nil
+}
+
+type unlimited struct{}
+
+func (unlimited) Allow(ratelimit.Class, string) (bool, time.Duration) {
+	return true, 0
+}
+
+func newTestServer(dependencies Dependencies) http.Handler {
+	return newTestServerWithConfig(Config{AllowedOrigins: []string{"http://localhost:5173"}}, dependencies)
+}
+
+func newTestServerWithConfig(config Config, dependencies Dependencies) http.Handler {
+	gin.SetMode(gin.TestMode)
+	if dependencies.Restaurants == nil {
+		dependencies.Restaurants = &fakeRestaurants{restaurants: map[string]places.Restaurant{
+			"known": {Id: "known", Name: "Magnin Cafe"},
+		}}
+	}
+	if dependencies.Keys == nil {
+		dependencies.Keys = &fakeKeys{keys: map[string]apikeys.Key{
+			"esk_reader": {Id: "reader", Name: "reader", Scopes: []apikeys.Scope{apikeys.ScopeRead}},
+		}}
+	}
+	if dependencies.Verifier == nil {
+		dependencies.Verifier = disabledVerifier{}
+	}
+	if dependencies.Limiter == nil {
+		dependencies.Limiter = unlimited{}
+	}
+	dependencies.Events = events.NewBroker()
+	return NewServer(config, dependencies).Handler()
+}
+
+func serve(handler http.Handler, method string, path string, credential string, body string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	if credential != "" {
+		request.Header.Set("Authorization", "Bearer "+credential)
+	}
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func decodeError(t *testing.T, recorder *httptest.ResponseRecorder) errorBody {
+	t.Helper()
+	var body errorBody
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected an error body, but got %q (%v)", recorder.Body.String(), err)
+	}
+	return body
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	handler := newTestServer(Dependencies{})
+	tests := []struct {
+		path        string
+		status      int
+		deprecation string
+		link        string
+	}{
+		{"/health", http.StatusOK, "", ""},
+		{"/v1/restaurant/known", http.StatusOK, "", ""},
+		{"/restaurant/known", http.StatusOK, "true", `</v1/restaurant/known>; rel="successor-version"`},
+		{"/v1/openapi.json", http.StatusOK, "", ""},
+		{"/openapi.json", http.StatusOK, "true", `</v1/openapi.json>; rel="successor-version"`},
+		{"/v2/restaurant/known", http.StatusNotFound, "", ""},
+	}
+	for _, test := range tests {
+		recorder := serve(handler, http.MethodGet, test.path, "esk_reader", "")
+		if recorder.Code != test.status {
+			t.Errorf("%s: expected status %d, but got %d", test.path, test.status, recorder.Code)
+		}
+		if got := recorder.Header().Get("Deprecation"); got != test.deprecation {
+			t.Errorf("%s: expected Deprecation %q, but got %q", test.path, test.deprecation, got)
+		}
+		if got := recorder.Header().Get("Link"); got != test.link {
+			t.Errorf("%s: expected Link %q, but got %q", test.path, test.link, got)
+		}
+	}
+}
+
+// fakeDietary keeps profiles in memory, reaching them the way DietaryClient does: a user reaches their own
+// profiles and a nil owner (API keys) reaches profiles without an owner
+type fakeDietary struct {
+	DietaryService
+	profiles map[string]dietary.Profile
+}
+
+func (f *fakeDietary) reaches(profile dietary.Profile, ownerId *string) bool {
+	if profile.OwnerUserId == nil || ownerId == nil {
+		return profile.OwnerUserId == nil && ownerId == nil
+	}
+	return *profile.OwnerUserId == *ownerId
+}
+
+func (f *fakeDietary) ListProfiles(ownerId *string) ([]dietary.Profile, error) {
+	profiles := []dietary.Profile{}
+	for _, profile := range f.profiles {
+		if f.reaches(profile, ownerId) {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles, nil
+}
+
+func (f *fakeDietary) GetProfile(id string, ownerId *string) (dietary.Profile, error) {
+	profile, ok := f.profiles[id]
+	if !ok || !f.reaches(profile, ownerId) {
+		return dietary.Profile{}, dietary.ErrProfileNotFound
+	}
+	return profile, nil
+}
+
+func (f *fakeDietary) DeleteProfile(id string, ownerId *string) error {
+	if _, err := f.GetProfile(id, ownerId); err != nil {
+		return err
+	}
+	delete(f.profiles, id)
+	return nil
+}
+
+func TestDietaryProfilesOfUsersAreHiddenFromKeys(t *testing.T) {
+	owner := "user-1"
+	profiles := &fakeDietary{profiles: map[string]dietary.Profile{
+		"users":  {Id: "users", OwnerUserId: &owner, Name: "Ada"},
+		"shared": {Id: "shared", Name: "Team"},
+	}}
+	handler := newTestServer(Dependencies{
+		Dietary: profiles,
+		Keys: &fakeKeys{keys: map[string]apikeys.Key{
+			"esk_reader":   {Id: "reader", Scopes: []apikeys.Scope{apikeys.ScopeRead}},
+			"esk_enricher": {Id: "enricher", Scopes: []apikeys.Scope{apikeys.ScopeRead, apikeys.ScopeEnrich}},
+		}},
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		credential string
+		status     int
+	}{
+		{"read a user's profile", http.MethodGet, "/v1/dietary-profiles/users", "esk_reader", http.StatusNotFound},
+		{"read a shared profile", http.MethodGet, "/v1/dietary-profiles/shared", "esk_reader", http.StatusOK},
+		{"delete without enrich", http.MethodDelete, "/v1/dietary-profiles/shared", "esk_reader", http.StatusForbidden},
+		{"delete a user's profile", http.MethodDelete, "/v1/dietary-profiles/users", "esk_enricher", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		recorder := serve(handler, test.method, test.path, test.credential, "")
+		if recorder.Code != test.status {
+			t.Errorf("%s: expected status %d, but got %d: %s", test.name, test.status, recorder.Code, recorder.Body.String())
+		}
+	}
+	if _, ok := profiles.profiles["users"]; !ok {
+		t.Errorf("Expected the user's profile to survive a key's delete")
+	}
+
+	recorder := serve(handler, http.MethodGet, "/v1/dietary-profiles", "esk_reader", "")
+	var listed []dietary.Profile
+	json.Unmarshal(recorder.Body.Bytes(), &listed)
+	if len(listed) != 1 || listed[0].Id != "shared" {
+		t.Errorf("Expected a key to list only the shared profile, but got %+v", listed)
+	}
+}
+
+func TestVapiAndWebhookScopesAreSeparate(t *testing.T) {
+	handler := newTestServer(Dependencies{
+		Keys: &fakeKeys{keys: map[string]apikeys.Key{
+			"esk_vapi":    {Id: "vapi", Scopes: []apikeys.Scope{apikeys.ScopeVapi}},
+			"esk_webhook": {Id: "webhook", Scopes: []apikeys.Scope{apikeys.ScopeWebhook}},
+		}},
+	})
+
+	tests := []struct {
+		method     string
+		path       string
+		credential string
+	}{
+		{http.MethodPost, "/v1/process-eocr", "esk_webhook"},
+		{http.MethodGet, "/v1/webhooks", "esk_vapi"},
+		{http.MethodPost, "/v1/webhooks", "esk_vapi"},
+		{http.MethodGet, "/v1/restaurant", "esk_vapi"},
+	}
+	for _, test := range tests {
+		recorder := serve(handler, test.method, test.path, test.credential, "{}")
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("%s %s with %s: expected status %d, but got %d", test.method, test.path, test.credential, http.StatusForbidden, recorder.Code)
+		}
+	}
+}
+
+func TestLegacyKeyIsAcceptedWithSignInEnabled(t *testing.T) {
+	handler := newTestServer(Dependencies{
+		Keys: &fakeKeys{keys: map[string]apikeys.Key{
+			"shared-secret": {Id: "legacy", Scopes: []apikeys.Scope{apikeys.ScopeRead, apikeys.ScopeVapi}},
+		}},
+		Verifier: fakeVerifier{claims: map[string]oidc.Claims{}},
+	})
+
+	tests := []struct {
+		credential string
+		status     int
+	}{
+		{"shared-secret", http.StatusOK},
+		{"other-secret", http.StatusUnauthorized},
+		{"a.b.c", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		recorder := serve(handler, http.MethodGet, "/v1/restaurant/known", test.credential, "")
+		if recorder.Code != test.status {
+			t.Errorf("%s: expected status %d, but got %d", test.credential, test.status, recorder.Code)
+		}
+	}
+}
+
+// fakeJobs cancels jobs the way EnrichmentClient does: a user or key only finds the jobs it requested
+type fakeJobs struct {
+	JobService
+	jobs map[string]enrichment.Job
+	// keyIds are the keys that requested jobs
+	keyIds map[string]string
+}
+
+func (f *fakeJobs) CancelJob(id string, owner *enrichment.Requester) (enrichment.Job, error) {
+	job, ok := f.jobs[id]
+	if !ok {
+		return enrichment.Job{}, enrichment.ErrJobNotFound
+	}
+	keyId, byKey := f.keyIds[id]
+	ownedByUser := owner != nil && owner.UserId != nil && job.RequestedByUserId != nil && *job.RequestedByUserId == *owner.UserId
+	ownedByKey := owner != nil && owner.KeyId != nil && byKey && keyId == *owner.KeyId
+	if owner != nil && !ownedByUser && !ownedByKey {
+		return enrichment.Job{}, enrichment.ErrJobNotFound
+	}
+	now := time.Now()
+	job.CancelledAt = &now
+	f.jobs[id] = job
+	return job, nil
+}
+
+func TestCallersCancelOnlyTheirOwnJobs(t *testing.T) {
+	ada := "ada"
+	grace := "grace"
+	jobs := &fakeJobs{
+		jobs: map[string]enrichment.Job{
+			"adas-job":       {Id: "adas-job", RequestedByUserId: &ada},
+			"graces-job":     {Id: "graces-job", RequestedByUserId: &grace},
+			"keys-job":       {Id: "keys-job"},
+			"other-keys-job": {Id: "other-keys-job"},
+			"scheduled-job":  {Id: "scheduled-job"},
+		},
+		keyIds: map[string]string{"keys-job": "enricher", "other-keys-job": "other"},
+	}
+	handler := newTestServer(Dependencies{
+		Jobs: jobs,
+		Keys: &fakeKeys{keys: map[string]apikeys.Key{
+			"esk_enricher": {Id: "enricher", Scopes: []apikeys.Scope{apikeys.ScopeEnrich}},
+			"esk_admin":    {Id: "admin", Scopes: []apikeys.Scope{apikeys.ScopeAdmin}},
+		}},
+		Users:    fakeUsers{},
+		Verifier: fakeVerifier{claims: map[string]oidc.Claims{"adas.id.token": {Issuer: "https://issuer", Subject: ada}}},
+	})
+
+	tests := []struct {
+		job        string
+		credential string
+		status     int
+	}{
+		{"adas-job", "adas.id.token", http.StatusOK},
+		{"graces-job", "adas.id.token", http.StatusNotFound},
+		{"keys-job", "adas.id.token", http.StatusNotFound},
+		{"graces-job", "esk_enricher", http.StatusNotFound},
+		{"other-keys-job", "esk_enricher", http.StatusNotFound},
+		{"keys-job", "esk_enricher", http.StatusOK},
+		{"scheduled-job", "esk_admin", http.StatusOK},
+		{"other-keys-job", "esk_admin", http.StatusOK},
+	}
+	for _, test := range tests {
+		recorder := serve(handler, http.MethodDelete, "/v1/jobs/"+test.job, test.credential, "")
+		if recorder.Code != test.status {
+			t.Errorf("cancel %s with %s: expected status %d, but got %d", test.job, test.credential, test.status, recorder.Code)
+		}
+	}
+	if jobs.jobs["graces-job"].CancelledAt != nil {
+		t.Errorf("Expected a key not to cancel another account's job")
+	}
+}
+
+// recordingLimiter allows everything, remembering the client addresses limited per IP
+type recordingLimiter struct {
+	addresses []string
+}
+
+func (r *recordingLimiter) Allow(class ratelimit.Class, subject string) (bool, time.Duration) {
+	if class == ratelimit.ClassIp {
+		r.addresses = append(r.addresses, subject)
+	}
+	return true, 0
+}
+
+func TestClientAddressOnlyFromTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		want    string
+	}{
+		{"no trusted proxies", nil, "192.0.2.10"},
+		{"trusted proxy", []string{"192.0.2.0/24"}, "203.0.113.7"},
+		{"other proxy", []string{"198.51.100.1"}, "192.0.2.10"},
+	}
+	for _, test := range tests {
+		limiter := &recordingLimiter{}
+		handler := newTestServerWithConfig(Config{AllowedOrigins: []string{"http://localhost:5173"}, TrustedProxies: test.proxies}, Dependencies{Limiter: limiter})
+		request := httptest.NewRequest(http.MethodGet, "/v1/restaurant/known", nil)
+		request.RemoteAddr = "192.0.2.10:41234"
+		request.Header.Set("Authorization", "Bearer esk_reader")
+		request.Header.Set("X-Forwarded-For", "203.0.113.7")
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+		if len(limiter.addresses) != 1 || limiter.addresses[0] != test.want {
+			t.Errorf("%s: expected the client address %s, but got %v", test.name, test.want, limiter.addresses)
+		}
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	handler := newTestServer(Dependencies{
+		Restaurants: &fakeRestaurants{errs: map[string]error{
+			"not-found":    places.ErrRestaurantNotFound,
+			"conflict":     apperrors.Conflict("restaurant is being enriched"),
+			"validation":   apperrors.Validation("phoneNumber is required"),
+			"upstream":     apperrors.Upstream("Google Places is unavailable"),
+			"rate-limited": apperrors.RateLimited("slow down", 1500*time.Millisecond),
+			"internal":     errors.New("connection reset by peer"),
+		}},
+	})
+
+	tests := []struct {
+		id         string
+		status     int
+		code       apperrors.Kind
+		message    string
+		retryAfter string
+	}{
+		{"not-found", http.StatusNotFound, apperrors.KindNotFound, "restaurant not found", ""},
+		{"conflict", http.StatusConflict, apperrors.KindConflict, "restaurant is being enriched", ""},
+		{"validation", http.StatusBadRequest, apperrors.KindValidation, "phoneNumber is required", ""},
+		{"upstream", http.StatusBadGateway, apperrors.KindUpstream, "Google Places is unavailable", ""},
+		{"rate-limited", http.StatusTooManyRequests, apperrors.KindRateLimited, "slow down", "2"},
+		{"internal", http.StatusInternalServerError, apperrors.KindInternal, "Internal server error", ""},
+	}
+	for _, test := range tests {
+		recorder := serve(handler, http.MethodGet, "/v1/restaurant/"+test.id, "esk_reader", "")
+		if recorder.Code != test.status {
+			t.Errorf("%s: expected status %d, but got %d", test.id, test.status, recorder.Code)
+		}
+		body := decodeError(t, recorder)
+		if body.Error.Code != test.code || body.Error.Message != test.message {
+			t.Errorf("%s: expected %s %q, but got %s %q", test.id, test.code, test.message, body.Error.Code, body.Error.Message)
+		}
+		if got := recorder.Header().Get("Retry-After"); got != test.retryAfter {
+			t.Errorf("%s: expected Retry-After %q, but got %q", test.id, test.retryAfter, got)
+		}
+	}
+
+	recorder := serve(handler, http.MethodGet, "/v1/restaurant/known", "", "")
+	if body := decodeError(t, recorder); recorder.Code != http.StatusUnauthorized || body.Error.Code != apperrors.KindUnauthorized {
+		t.Errorf("Expected a request without credentials to be unauthorized, but got %d %+v", recorder.Code, body)
+	}
+}
+
+func TestRequestIdIsEchoed(t *testing.T) {
+	handler := newTestServer(Dependencies{})
+	tests := []struct {
+		name   string
+		sent   string
+		echoed bool
+	}{
+		{"caller's id", "req-123", true},
+		{"no id", "", false},
+		{"id too long", strings.Repeat("x", 129), false},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/v1/restaurant/missing", nil)
+		request.Header.Set("Authorization", "Bearer esk_reader")
+		if test.sent != "" {
+			request.Header.Set(requestIdHeader, test.sent)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		id := recorder.Header().Get(requestIdHeader)
+		if test.echoed && id != test.sent {
+			t.Errorf("%s: expected %s %q, but got %q", test.name, requestIdHeader, test.sent, id)
+		}
+		if !test.echoed && (id == "" || id == test.sent) {
+			t.Errorf("%s: expected a generated %s, but got %q", test.name, requestIdHeader, id)
+		}
+		if body := decodeError(t, recorder); body.Error.RequestId != id {
+			t.Errorf("%s: expected the error body to carry request id %q, but got %q", test.name, id, body.Error.RequestId)
+		}
+	}
+}
+
+func TestInvalidRequestMessages(t *testing.T) {
+	handler := newTestServer(Dependencies{
+		Keys: &fakeKeys{keys: map[string]apikeys.Key{
+			"esk_admin": {Id: "admin", Scopes: []apikeys.Scope{apikeys.ScopeAdmin}},
+		}},
+	})
+	tooManyIds := `["` + strings.Repeat(`a","`, MAX_ENRICHMENTS) + `a"]`
+
+	tests := []struct {
+		path    string
+		body    string
+		message string
+	}{
+		{"/v1/enrich", `{}`, "ids is required"},
+		{"/v1/enrich", `{"ids": []}`, "ids must not be empty"},
+		{"/v1/enrich", `{"ids": ` + tooManyIds + `}`, fmt.Sprintf("ids must have at most %d items", MAX_ENRICHMENTS)},
+		{"/v1/enrich", `{"ids": ["a", ""]}`, "ids[1] is required"},
+		{"/v1/enrich", `{"ids": ["a"], "priority": "urgent"}`, "priority must be one of high, normal"},
+		{"/v1/search", `{"query": "` + strings.Repeat("x", 201) + `"}`, "query must be at most 200 characters"},
+		{"/v1/api-keys", `{"scopes": ["read"]}`, "name is required"},
+		{"/v1/api-keys", `{"name": "", "scopes": []}`, "name is required; scopes must not be empty"},
+	}
+	for _, test := range tests {
+		recorder := serve(handler, http.MethodPost, test.path, "esk_admin", test.body)
+		body := decodeError(t, recorder)
+		if recorder.Code != http.StatusBadRequest || body.Error.Code != apperrors.KindValidation || body.Error.Message != test.message {
+			t.Errorf("%s %s: expected 400 %q, but got %d %s %q", test.path, test.body, test.message, recorder.Code, body.Error.Code, body.Error.Message)
+		}
+	}
+
+	recorder := serve(handler, http.MethodPost, "/v1/enrich", "esk_admin", `{"ids": [`)
+	if body := decodeError(t, recorder); recorder.Code != http.StatusBadRequest || body.Error.Code != apperrors.KindValidation {
+		t.Errorf("Expected malformed JSON to be a validation error, but got %d %+v", recorder.Code, body)
+	}
+}
+
+// TestRoutesAreDocumented keeps openapi.json in step with the router: every route, versioned or not, must be
+// documented, and the limits the spec states must be the ones the handlers enforce
+func TestRoutesAreDocumented(t *testing.T) {
+	var spec struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					MaxItems int `json:"maxItems"`
+				} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(openapiSpec, &spec); err != nil {
+		t.Fatalf("Failed to parse openapi.json: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	server := NewServer(Config{AllowedOrigins: []string{"http://localhost:5173"}}, Dependencies{Events: events.NewBroker()})
+	for _, route := range server.router.Routes() {
+		path := strings.TrimPrefix(route.Path, "/v1")
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			if name, ok := strings.CutPrefix(segment, ":"); ok {
+				segments[i] = "{" + name + "}"
+			}
+		}
+		path = strings.Join(segments, "/")
+		if path == "" {
+			path = "/"
+		}
+		if _, ok := spec.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("%s %s is not documented in openapi.json as %s", route.Method, route.Path, path)
+		}
+	}
+
+	if maxItems := spec.Components.Schemas["EnrichRequest"].Properties["ids"].MaxItems; maxItems != MAX_ENRICHMENTS {
+		t.Errorf("Expected EnrichRequest.ids maxItems to be MAX_ENRICHMENTS (%d), but got %d", MAX_ENRICHMENTS, maxItems)
+	}
+	field, _ := reflect.TypeOf(enrichRequest{}).FieldByName("Ids")
+	if tag := field.Tag.Get("binding"); !strings.Contains(tag, fmt.Sprintf(",max=%d,", MAX_ENRICHMENTS)) {
+		t.Errorf("Expected enrichRequest.Ids to be bound with max=%d (MAX_ENRICHMENTS), but got %q", MAX_ENRICHMENTS, tag)
+	}
+}
+
+func TestUnversionedRoutesKeepTheirOldResponses(t *testing.T) {
+	handler := newTestServer(Dependencies{
+		Usage: unmetered{},
+		Keys: &fakeKeys{keys: map[string]apikeys.Key{
+			"esk_enricher": {Id: "enricher", Name: "enricher", Scopes: []apikeys.Scope{apikeys.ScopeRead, apikeys.ScopeEnrich}},
+		}},
+	})
+	body := `{"ids": ["known", "missing"]}`
+
+	recorder := serve(handler, http.MethodPost, "/enrich", "esk_enricher", body)
+	var restaurants []places.Restaurant
+	if err := json.Unmarshal(recorder.Body.Bytes(), &restaurants); err != nil {
+		t.Fatalf("Expected /enrich to answer with an array of restaurants, but got %q", recorder.Body.String())
+	}
+	if recorder.Code != http.StatusOK || len(restaurants) != 1 || restaurants[0].Id != "known" {
+		t.Errorf("Expected 200 with the enqueued restaurant, but got %d %+v", recorder.Code, restaurants)
+	}
+
+	recorder = serve(handler, http.MethodPost, "/v1/enrich", "esk_enricher", body)
+	var response struct {
+		Job     enrichment.Job            `json:"job"`
+		Results []places.EnrichmentResult `json:"results"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	if recorder.Code != http.StatusMultiStatus || response.Job.Id != "job-1" || len(response.Results) != 2 {
+		t.Errorf("Expected 207 with the job and both results on /v1, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	tests := []struct {
+		path       string
+		credential string
+		status     int
+		message    string
+	}{
+		{"/restaurant/missing", "esk_enricher", http.StatusNotFound, "restaurant not found"},
+		{"/enrich", "", http.StatusUnauthorized, "Unauthorized"},
+		{"/enrich", "esk_enricher", http.StatusBadRequest, "ids is required"},
+	}
+	for _, test := range tests {
+		method := http.MethodGet
+		requestBody := ""
+		if test.path == "/enrich" {
+			method, requestBody = http.MethodPost, "{}"
+		}
+		recorder := serve(handler, method, test.path, test.credential, requestBody)
+		var legacy struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &legacy); err != nil || recorder.Code != test.status || legacy.Error != test.message {
+			t.Errorf("%s: expected %d {\"error\": %q}, but got %d %s", test.path, test.status, test.message, recorder.Code, recorder.Body.String())
+		}
+	}
+}
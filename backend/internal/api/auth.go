@@ -0,0 +1,124 @@
+package api
+
+import (
+	"eatsavvy/internal/apikeys"
+	"eatsavvy/internal/apperrors"
+	"eatsavvy/internal/enrichment"
+	"eatsavvy/internal/usage"
+	"eatsavvy/internal/users"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userScopes are what signed in users may do. Webhooks and API key management stay with API keys.
+var userScopes = []apikeys.Scope{apikeys.ScopeRead, apikeys.ScopeSearch, apikeys.ScopeEnrich}
+
+// authMiddleware authenticates the bearer credential and attaches the caller to the request context. API keys
+// (see apikeys.FromContext) are recognized by their prefix, or by not being a JWT in the case of the imported
+// legacy key; JWTs are verified as OIDC tokens and mapped to a user (see users.FromContext), who is created on
+// first sign in.
+func authMiddleware(keys KeyService, verifier TokenVerifier, userService UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credential, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			respondError(c, apperrors.New(apperrors.KindUnauthorized, "Unauthorized"))
+			return
+		}
+		if apikeys.IsKey(credential) || !verifier.Enabled() || !isJwt(credential) {
+			key, err := keys.Authenticate(credential)
+			if err != nil {
+				respondError(c, err)
+				return
+			}
+			c.Request = c.Request.WithContext(apikeys.NewContext(c.Request.Context(), key))
+			c.Next()
+			return
+		}
+
+		// Fails with an upstream error if the issuer couldn't be reached to fetch its signing keys
+		claims, err := verifier.Verify(credential)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		user, err := userService.UpsertUser(claims.Issuer, claims.Subject, claims.Email, claims.Name)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.Request = c.Request.WithContext(users.NewContext(c.Request.Context(), user))
+		c.Next()
+	}
+}
+
+// isJwt reports whether credential has the three dot separated segments of a JWT
+func isJwt(credential string) bool {
+	return strings.Count(credential, ".") == 2
+}
+
+// requireScope rejects requests whose key, or signed in user, isn't granted scope
+func requireScope(scope apikeys.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := users.FromContext(c.Request.Context()); ok {
+			if !slices.Contains(userScopes, scope) {
+				respondError(c, apperrors.New(apperrors.KindForbidden, "Users can't use the "+string(scope)+" scope; use an API key"))
+				return
+			}
+			c.Next()
+			return
+		}
+		key, ok := apikeys.FromContext(c.Request.Context())
+		if !ok || !key.HasScope(scope) {
+			respondError(c, apperrors.New(apperrors.KindForbidden, "API key is missing the "+string(scope)+" scope"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireUser rejects requests that weren't made by a signed in user
+func requireUser(c *gin.Context) {
+	if _, ok := users.FromContext(c.Request.Context()); !ok {
+		respondError(c, apperrors.New(apperrors.KindForbidden, "Sign in to use this endpoint"))
+		return
+	}
+	c.Next()
+}
+
+// requestedBy names the caller that enrichment jobs are attributed to
+func requestedBy(c *gin.Context) enrichment.Requester {
+	if user, ok := users.FromContext(c.Request.Context()); ok {
+		return enrichment.Requester{Name: user.DisplayName(), UserId: &user.Id}
+	}
+	if key, ok := apikeys.FromContext(c.Request.Context()); ok {
+		return enrichment.Requester{Name: key.Name, KeyId: &key.Id}
+	}
+	return enrichment.Requester{Name: enrichment.RequestedByApi}
+}
+
+// ownerId is the signed in user's id, which limits dietary profiles to their own. It is nil for API keys, which
+// only reach profiles created with API keys.
+func ownerId(c *gin.Context) *string {
+	if user, ok := users.FromContext(c.Request.Context()); ok {
+		return &user.Id
+	}
+	return nil
+}
+
+// jobOwner limits the jobs a caller can cancel to the ones it requested, or is nil for admin keys, which can
+// cancel any job
+func jobOwner(c *gin.Context) *enrichment.Requester {
+	if key, ok := apikeys.FromContext(c.Request.Context()); ok && key.HasScope(apikeys.ScopeAdmin) {
+		return nil
+	}
+	requester := requestedBy(c)
+	return &requester
+}
+
+// account is who the request's usage is charged to
+func account(c *gin.Context) usage.Account {
+	requester := requestedBy(c)
+	return usage.Account{KeyId: requester.KeyId, UserId: requester.UserId}
+}
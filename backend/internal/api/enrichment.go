@@ -0,0 +1,100 @@
+package api
+
+import (
+	"eatsavvy/internal/apperrors"
+	"eatsavvy/internal/jobs"
+	"eatsavvy/internal/places"
+	netHttp "net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) enrichRestaurants(c *gin.Context) {
+	var request enrichRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+	at, err := parseAt(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	priority, err := jobs.ParsePriority(request.Priority)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !s.allowUsage(c, 1) {
+		return
+	}
+	options := places.EnrichOptions{Force: request.Force, Priority: priority, NotBefore: request.NotBefore}
+	job, results, err := s.Restaurants.BatchEnrichRestaurantDetails(request.Ids, requestedBy(c), options)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	respondEnriched(c, job, results, at)
+}
+
+func (s *Server) searchAndEnrich(c *gin.Context) {
+	var request searchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+	at, err := parseAt(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !s.allowUsage(c, 0) {
+		return
+	}
+	restaurants, err := s.Restaurants.SearchRestaurants(request.Query, account(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if len(restaurants) == 0 {
+		respondError(c, apperrors.NotFound("No restaurants found for query: "+request.Query))
+		return
+	}
+	if len(restaurants) > MAX_ENRICHMENTS {
+		respondError(c, apperrors.Validation("Too many restaurants found for query: "+request.Query+". Please refine your query."))
+		return
+	}
+	ids := []string{}
+	for _, restaurant := range restaurants {
+		ids = append(ids, restaurant.Id)
+	}
+	if !s.allowUsage(c, 1) {
+		return
+	}
+	job, results, err := s.Restaurants.BatchEnrichRestaurantDetails(ids, requestedBy(c), places.EnrichOptions{})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	respondEnriched(c, job, results, at)
+}
+
+func (s *Server) getJob(c *gin.Context) {
+	job, err := s.Jobs.GetJob(c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, job)
+}
+
+// cancelJob cancels the job's queued restaurants; calls that were already placed still complete. Users and keys
+// can only cancel their own jobs, except for admin keys.
+func (s *Server) cancelJob(c *gin.Context) {
+	job, err := s.Jobs.CancelJob(c.Param("id"), jobOwner(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, job)
+}
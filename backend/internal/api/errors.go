@@ -0,0 +1,77 @@
+package api
+
+import (
+	"crypto/rand"
+	"eatsavvy/internal/apperrors"
+	"encoding/hex"
+	"log/slog"
+	"math"
+	netHttp "net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requestIdHeader = "X-Request-Id"
+
+const requestIdKey = "requestId"
+
+// legacyKey is set on requests to the unversioned routes (see deprecated)
+const legacyKey = "legacy"
+
+// errorBody is the body of every error response
+type errorBody struct {
+	Error struct {
+		Code      apperrors.Kind `json:"code"`
+		Message   string         `json:"message"`
+		RequestId string         `json:"requestId"`
+	} `json:"error"`
+}
+
+var statusByKind = map[apperrors.Kind]int{
+	apperrors.KindNotFound:     netHttp.StatusNotFound,
+	apperrors.KindConflict:     netHttp.StatusConflict,
+	apperrors.KindValidation:   netHttp.StatusBadRequest,
+	apperrors.KindUnauthorized: netHttp.StatusUnauthorized,
+	apperrors.KindForbidden:    netHttp.StatusForbidden,
+	apperrors.KindUpstream:     netHttp.StatusBadGateway,
+	apperrors.KindRateLimited:  netHttp.StatusTooManyRequests,
+	apperrors.KindInternal:     netHttp.StatusInternalServerError,
+}
+
+// requestId tags each request with the caller's X-Request-Id, or a new one, and echoes it in the response so
+// errors can be matched to logs
+func requestId(c *gin.Context) {
+	id := c.GetHeader(requestIdHeader)
+	if id == "" || len(id) > 128 {
+		bytes := make([]byte, 16)
+		rand.Read(bytes)
+		id = hex.EncodeToString(bytes)
+	}
+	c.Set(requestIdKey, id)
+	c.Header(requestIdHeader, id)
+	c.Next()
+}
+
+// respondError aborts the request with the status for err's kind (see apperrors.KindOf). Internal errors are
+// logged and their details kept out of the response. The unversioned routes answer with the body they used
+// before versioning, {"error": "<message>"}.
+func respondError(c *gin.Context, err error) {
+	kind := apperrors.KindOf(err)
+	var body errorBody
+	body.Error.Code = kind
+	body.Error.Message = err.Error()
+	body.Error.RequestId = c.GetString(requestIdKey)
+	if kind == apperrors.KindInternal {
+		slog.Error("[api.respondError] Request failed", "error", err, "path", c.FullPath(), "requestId", body.Error.RequestId)
+		body.Error.Message = "Internal server error"
+	}
+	if retryAfter := apperrors.RetryAfterOf(err); retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(max(int(math.Ceil(retryAfter.Seconds())), 1)))
+	}
+	if c.GetBool(legacyKey) {
+		c.AbortWithStatusJSON(statusByKind[kind], gin.H{"error": body.Error.Message})
+		return
+	}
+	c.AbortWithStatusJSON(statusByKind[kind], body)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"eatsavvy/internal/events"
+	"io"
+	netHttp "net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseKeepAliveInterval keeps idle event streams from being closed by proxies
+const sseKeepAliveInterval = 15 * time.Second
+
+// streamEvents writes the initial events and then every event from the subscription as Server-Sent Events
+// until the client disconnects
+func streamEvents(c *gin.Context, subscription *events.Subscription, initial []events.Event) {
+	defer subscription.Close()
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(netHttp.StatusOK)
+
+	for _, event := range initial {
+		c.SSEvent("enrichment", event)
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return false
+			}
+			c.SSEvent("enrichment", event)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+}
+
+// parseIds splits a comma-separated ids query parameter, ignoring empty entries
+func parseIds(ids string) []string {
+	parsed := []string{}
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			parsed = append(parsed, id)
+		}
+	}
+	return parsed
+}
@@ -0,0 +1,8 @@
+package api
+
+import _ "embed"
+
+// openapiSpec describes every route and model, served at /openapi.json. Update it with the routes.
+//
+//go:embed openapi.json
+var openapiSpec []byte
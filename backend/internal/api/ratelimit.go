@@ -0,0 +1,53 @@
+package api
+
+import (
+	"eatsavvy/internal/apikeys"
+	"eatsavvy/internal/apperrors"
+	"eatsavvy/internal/ratelimit"
+	"eatsavvy/internal/users"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ipRateLimit limits requests per client address. It runs before authentication, so it also slows down
+// guessing keys.
+func ipRateLimit(limiter RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, retryAfter := limiter.Allow(ratelimit.ClassIp, c.ClientIP())
+		if !allowed {
+			rejectRateLimited(c, retryAfter)
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimit limits requests per API key or user for routes of class. It must run after authMiddleware.
+func rateLimit(limiter RateLimiter, class ratelimit.Class) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, retryAfter := limiter.Allow(class, rateLimitSubject(c))
+		if !allowed {
+			rejectRateLimited(c, retryAfter)
+			return
+		}
+		c.Next()
+	}
+}
+
+func rateLimitSubject(c *gin.Context) string {
+	if user, ok := users.FromContext(c.Request.Context()); ok {
+		return "user:" + user.Id
+	}
+	if key, ok := apikeys.FromContext(c.Request.Context()); ok {
+		return "key:" + key.Id
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func rejectRateLimited(c *gin.Context, retryAfter time.Duration) {
+	seconds := max(int(math.Ceil(retryAfter.Seconds())), 1)
+	respondError(c, apperrors.RateLimited("Rate limit exceeded, retry in "+strconv.Itoa(seconds)+"s", retryAfter))
+}
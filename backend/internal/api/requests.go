@@ -0,0 +1,94 @@
+package api
+
+import (
+	"eatsavvy/internal/apperrors"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Request bodies are validated with binding tags when bound; keep them in step with openapi.json (see
+// TestRoutesAreDocumented)
+
+type searchRequest struct {
+	Query string `json:"query" binding:"required,max=200"`
+}
+
+type enrichRequest struct {
+	// Ids are Places ids; at most MAX_ENRICHMENTS, which TestRoutesAreDocumented checks the tag against
+	Ids []string `json:"ids" binding:"required,min=1,max=25,dive,required"`
+	// Force re-enriches fresh restaurants and replaces queued jobs
+	Force bool `json:"force"`
+	// Priority is "high" or "normal" (the default)
+	Priority string `json:"priority" binding:"omitempty,oneof=high normal"`
+	// NotBefore is an RFC 3339 time before which no call is placed
+	NotBefore *time.Time `json:"notBefore"`
+}
+
+type updateRestaurantRequest struct {
+	PhoneNumber string `json:"phoneNumber" binding:"required"`
+}
+
+type createApiKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+func init() {
+	// Name fields in validation errors as they appear in JSON
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// invalidRequest turns an error binding a request body into a validation error, describing the failed binding
+// rules in terms of the JSON fields
+func invalidRequest(err error) error {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return apperrors.Wrap(apperrors.KindValidation, err)
+	}
+	messages := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		messages = append(messages, fieldMessage(fieldError))
+	}
+	return &apperrors.Error{Kind: apperrors.KindValidation, Message: strings.Join(messages, "; "), Err: err}
+}
+
+func fieldMessage(fieldError validator.FieldError) string {
+	field := fieldError.Field()
+	isList := fieldError.Kind() == reflect.Slice
+	switch fieldError.Tag() {
+	case "required":
+		return field + " is required"
+	case "min":
+		if isList && fieldError.Param() == "1" {
+			return field + " must not be empty"
+		}
+		if isList {
+			return fmt.Sprintf("%s must have at least %s items", field, fieldError.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s characters", field, fieldError.Param())
+	case "max":
+		if isList {
+			return fmt.Sprintf("%s must have at most %s items", field, fieldError.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s characters", field, fieldError.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", field, strings.ReplaceAll(fieldError.Param(), " ", ", "))
+	default:
+		return field + " is invalid"
+	}
+}
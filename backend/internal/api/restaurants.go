@@ -0,0 +1,133 @@
+package api
+
+import (
+	"eatsavvy/internal/events"
+	"eatsavvy/internal/users"
+	netHttp "net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getMyRestaurants lists the restaurants the signed in user asked to enrich, most recently requested first
+func (s *Server) getMyRestaurants(c *gin.Context) {
+	at, err := parseAt(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	user, _ := users.FromContext(c.Request.Context())
+	restaurants, err := s.Restaurants.GetRestaurantsRequestedBy(user.Id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, withOpenStatuses(restaurants, at))
+}
+
+// streamRestaurantEvents streams enrichment status and nutrition info changes as Server-Sent Events, optionally
+// limited with ?ids=a,b
+func (s *Server) streamRestaurantEvents(c *gin.Context) {
+	streamEvents(c, s.Events.Subscribe(parseIds(c.Query("ids"))...), nil)
+}
+
+// streamRestaurant streams one restaurant's changes, starting with its current status
+func (s *Server) streamRestaurant(c *gin.Context) {
+	id := c.Param("id")
+	// Subscribe before reading the current status so no change in between is missed
+	subscription := s.Events.Subscribe(id)
+	restaurant, err := s.Restaurants.GetRestaurant(id)
+	if err != nil {
+		subscription.Close()
+		respondError(c, err)
+		return
+	}
+	current := events.Event{
+		RestaurantId:     restaurant.Id,
+		EnrichmentStatus: restaurant.EnrichmentStatus,
+		NutritionInfo:    restaurant.NutritionInfo,
+		UpdatedAt:        restaurant.UpdatedAt,
+	}
+	streamEvents(c, subscription, []events.Event{current})
+}
+
+func (s *Server) getRestaurant(c *gin.Context) {
+	id := c.Param("id")
+	at, err := parseAt(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	restaurant, err := s.Restaurants.GetRestaurant(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, withOpenStatus(restaurant, at))
+}
+
+func (s *Server) updateRestaurant(c *gin.Context) {
+	id := c.Param("id")
+	var request updateRestaurantRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	formattedPhone, err := formatPhoneNumber(request.PhoneNumber)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	restaurant, err := s.Restaurants.UpdateRestaurantPhoneNumber(id, formattedPhone)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, withOpenStatus(restaurant, time.Now()))
+}
+
+func (s *Server) listRestaurants(c *gin.Context) {
+	at, err := parseAt(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	restaurants, err := s.Restaurants.GetAllRestaurants()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	restaurants, ok := s.withCompatibility(c, restaurants)
+	if !ok {
+		return
+	}
+	c.JSON(netHttp.StatusOK, withOpenStatuses(restaurants, at))
+}
+
+func (s *Server) searchRestaurants(c *gin.Context) {
+	var request searchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+	at, err := parseAt(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !s.allowUsage(c, 0) {
+		return
+	}
+	restaurants, err := s.Restaurants.SearchRestaurants(request.Query, account(c)) // Magnin Cafe
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	restaurants, ok := s.withCompatibility(c, restaurants)
+	if !ok {
+		return
+	}
+	c.JSON(netHttp.StatusOK, withOpenStatuses(restaurants, at))
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"eatsavvy/internal/apikeys"
+	"eatsavvy/internal/dietary"
+	"eatsavvy/internal/enrichment"
+	"eatsavvy/internal/oidc"
+	"eatsavvy/internal/places"
+	"eatsavvy/internal/ratelimit"
+	"eatsavvy/internal/usage"
+	"eatsavvy/internal/users"
+	"eatsavvy/internal/webhooks"
+	"time"
+)
+
+// The services below are what the handlers use of each dependency. NewDependencies provides the database
+// backed implementations.
+
+// RestaurantService is implemented by places.RestaurantsClient
+type RestaurantService interface {
+	GetRestaurant(placesId string) (places.Restaurant, error)
+	GetAllRestaurants() ([]places.Restaurant, error)
+	GetRestaurantsRequestedBy(userId string) ([]places.Restaurant, error)
+	SearchRestaurants(textQuery string, account usage.Account) ([]places.Restaurant, error)
+	BatchEnrichRestaurantDetails(restaurantIds []string, requester enrichment.Requester, options places.EnrichOptions) (enrichment.Job, []places.EnrichmentResult, error)
+	UpdateRestaurantPhoneNumber(placesId string, phoneNumber string) (places.Restaurant, error)
+	UpdateRestaurantNutritionInfo(eocr places.EndOfCallReportMessage) error
+}
+
+// JobService is implemented by enrichment.EnrichmentClient
+type JobService interface {
+	GetJob(id string) (enrichment.Job, error)
+	CancelJob(id string, owner *enrichment.Requester) (enrichment.Job, error)
+}
+
+// WebhookService is implemented by webhooks.WebhooksClient
+type WebhookService interface {
+	CreateSubscription(subscription webhooks.Subscription) (webhooks.Subscription, error)
+	ListSubscriptions() ([]webhooks.Subscription, error)
+	DeleteSubscription(id string) error
+	ListDeliveries(subscriptionId string) ([]webhooks.Delivery, error)
+}
+
+// DietaryService is implemented by dietary.DietaryClient
+type DietaryService interface {
+	CreateProfile(profile dietary.Profile, ownerId *string) (dietary.Profile, error)
+	ListProfiles(ownerId *string) ([]dietary.Profile, error)
+	GetProfile(id string, ownerId *string) (dietary.Profile, error)
+	UpdateProfile(id string, profile dietary.Profile, ownerId *string) (dietary.Profile, error)
+	DeleteProfile(id string, ownerId *string) error
+}
+
+// UsageService is implemented by usage.UsageClient
+type UsageService interface {
+	GetUsage(account usage.Account) (usage.Usage, error)
+	Allow(account usage.Account, enrichments int) error
+}
+
+// KeyService is implemented by apikeys.ApiKeysClient
+type KeyService interface {
+	Authenticate(secret string) (apikeys.Key, error)
+	CreateKey(name string, scopes []apikeys.Scope, expiresAt *time.Time) (apikeys.Key, error)
+	ListKeys() ([]apikeys.Key, error)
+	RevokeKey(id string) (apikeys.Key, error)
+}
+
+// UserService is implemented by users.UsersClient
+type UserService interface {
+	UpsertUser(issuer string, subject string, email string, name string) (users.User, error)
+}
+
+// TokenVerifier is implemented by oidc.Verifier
+type TokenVerifier interface {
+	Enabled() bool
+	Verify(token string) (oidc.Claims, error)
+}
+
+// RateLimiter is implemented by ratelimit.Limiter
+type RateLimiter interface {
+	Allow(class ratelimit.Class, subject string) (bool, time.Duration)
+}
@@ -1,8 +1,16 @@
 package api
 
 import (
+	"eatsavvy/internal/apperrors"
+	"eatsavvy/internal/enrichment"
+	"eatsavvy/internal/openhours"
+	"eatsavvy/internal/places"
 	"fmt"
+	netHttp "net/http"
 	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
 func formatPhoneNumber(phone string) (string, error) {
@@ -17,9 +25,100 @@ func formatPhoneNumber(phone string) (string, error) {
 
 	// Validate we have exactly 10 digits
 	if len(digits) != 10 {
-		return "", fmt.Errorf("invalid phone number: expected 10 digits, got %d", len(digits))
+		return "", apperrors.Newf(apperrors.KindValidation, "invalid phone number: expected 10 digits, got %d", len(digits))
 	}
 
 	// Format as (XXX) XXX-XXXX
 	return fmt.Sprintf("(%s) %s-%s", digits[0:3], digits[3:6], digits[6:10]), nil
 }
+
+// parseAt reads the optional ?at= RFC 3339 timestamp that open-hours fields are computed for, defaulting to now
+func parseAt(c *gin.Context) (time.Time, error) {
+	at := c.Query("at")
+	if at == "" {
+		return time.Now(), nil
+	}
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return time.Time{}, apperrors.Newf(apperrors.KindValidation, "invalid at: expected an RFC 3339 timestamp, got %q", at)
+	}
+	return t, nil
+}
+
+// withOpenStatus fills in the computed open-hours fields of a restaurant as of at
+func withOpenStatus(restaurant places.Restaurant, at time.Time) places.Restaurant {
+	if len(restaurant.OpenHours) == 0 && len(restaurant.SpecialDays) == 0 {
+		return restaurant
+	}
+	openHours, location := places.LocalOpenHours(restaurant)
+	openNow := openhours.IsOpenAt(openHours, restaurant.SpecialDays, location, at)
+	restaurant.OpenNow = &openNow
+	if openNow {
+		if nextClose, ok := openhours.NextCloseAt(openHours, restaurant.SpecialDays, location, at); ok {
+			nextClose = nextClose.In(location)
+			restaurant.NextCloseAt = &nextClose
+		}
+	} else if nextOpen, ok := openhours.NextOpenAt(openHours, restaurant.SpecialDays, location, at); ok {
+		nextOpen = nextOpen.In(location)
+		restaurant.NextOpenAt = &nextOpen
+	}
+	restaurant.WeekdayDescriptions = openhours.Describe(openHours)
+	return restaurant
+}
+
+func withOpenStatuses(restaurants []places.Restaurant, at time.Time) []places.Restaurant {
+	for i := range restaurants {
+		restaurants[i] = withOpenStatus(restaurants[i], at)
+	}
+	return restaurants
+}
+
+// respondEnriched answers once restaurants are queued for enrichment with the job to follow (GET /jobs/:id)
+// and what happened to each restaurant. The status is 202 if every restaurant was enqueued or skipped, and
+// 207 if some could not be enriched, so clients know to look at the individual results. The unversioned
+// routes answer 200 with the snapshots of the restaurants that weren't failures, as they did before jobs.
+func respondEnriched(c *gin.Context, job enrichment.Job, results []places.EnrichmentResult, at time.Time) {
+	status := netHttp.StatusAccepted
+	restaurants := []places.Restaurant{}
+	for i, result := range results {
+		if result.Failed() {
+			status = netHttp.StatusMultiStatus
+		}
+		if result.Restaurant != nil {
+			restaurant := withOpenStatus(*result.Restaurant, at)
+			results[i].Restaurant = &restaurant
+			restaurants = append(restaurants, restaurant)
+		}
+	}
+	if c.GetBool(legacyKey) {
+		c.JSON(netHttp.StatusOK, restaurants)
+		return
+	}
+	c.JSON(status, gin.H{"job": job, "results": results})
+}
+
+// withCompatibility ranks restaurants for the dietary profile in ?profileId=, if given (see
+// places.RankByCompatibility). It writes the error response and returns false if the profile can't be loaded.
+func (s *Server) withCompatibility(c *gin.Context, restaurants []places.Restaurant) ([]places.Restaurant, bool) {
+	profileId := c.Query("profileId")
+	if profileId == "" {
+		return restaurants, true
+	}
+	profile, err := s.Dietary.GetProfile(profileId, ownerId(c))
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+	return places.RankByCompatibility(restaurants, profile), true
+}
+
+// allowUsage checks that the caller has quota left to queue enrichments restaurants (0 for requests that only cost
+// Places calls). Enrich requests pass 1, since only the restaurants that turn out to need a call use up quota and
+// each is checked as it is queued. It writes the error response, a 429 when over quota, and returns false if not.
+func (s *Server) allowUsage(c *gin.Context, enrichments int) bool {
+	if err := s.Usage.Allow(account(c), enrichments); err != nil {
+		respondError(c, err)
+		return false
+	}
+	return true
+}
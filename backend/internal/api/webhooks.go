@@ -0,0 +1,68 @@
+package api
+
+import (
+	"eatsavvy/internal/places"
+	"eatsavvy/internal/webhooks"
+	netHttp "net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) processEndOfCallReport(c *gin.Context) {
+	var request places.EndOfCallReportMessage
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+	err := s.Restaurants.UpdateRestaurantNutritionInfo(request)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, gin.H{"status": "ok"})
+}
+
+func (s *Server) createWebhook(c *gin.Context) {
+	var request webhooks.Subscription
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+	if err := webhooks.ValidateSubscription(request); err != nil {
+		respondError(c, err)
+		return
+	}
+	subscription, err := s.Webhooks.CreateSubscription(request)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusCreated, subscription)
+}
+
+func (s *Server) listWebhooks(c *gin.Context) {
+	subscriptions, err := s.Webhooks.ListSubscriptions()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, subscriptions)
+}
+
+func (s *Server) deleteWebhook(c *gin.Context) {
+	err := s.Webhooks.DeleteSubscription(c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(netHttp.StatusNoContent)
+}
+
+func (s *Server) listWebhookDeliveries(c *gin.Context) {
+	deliveries, err := s.Webhooks.ListDeliveries(c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(netHttp.StatusOK, deliveries)
+}
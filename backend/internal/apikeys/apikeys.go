@@ -0,0 +1,119 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"eatsavvy/internal/apperrors"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Scope string
+
+const (
+	// ScopeRead allows reading restaurants, enrichment jobs and event streams
+	ScopeRead Scope = "read"
+	// ScopeSearch allows searching Places
+	ScopeSearch Scope = "search"
+	// ScopeEnrich allows queueing and cancelling enrichment
+	ScopeEnrich Scope = "enrich"
+	// ScopeWebhook allows managing webhook subscriptions
+	ScopeWebhook Scope = "webhook"
+	// ScopeVapi allows delivering Vapi end-of-call reports, and is all the key Vapi holds should grant
+	ScopeVapi Scope = "vapi"
+	// ScopeAdmin allows everything, including managing API keys
+	ScopeAdmin Scope = "admin"
+)
+
+var Scopes = []Scope{ScopeRead, ScopeSearch, ScopeEnrich, ScopeWebhook, ScopeVapi, ScopeAdmin}
+
+// keyPrefix starts every key, so leaked keys are easy to recognize and scan for
+const keyPrefix = "esk_"
+
+// displayPrefixLength is how much of a key is stored in the clear to tell keys apart in listings
+const displayPrefixLength = 12
+
+// legacyPrefixLength is how much of an imported legacy key is stored in the clear; it has no esk_ to spare
+const legacyPrefixLength = 4
+
+var (
+	ErrKeyNotFound = apperrors.NotFound("api key not found")
+	ErrInvalidKey  = apperrors.New(apperrors.KindUnauthorized, "invalid, expired or revoked api key")
+)
+
+// Key is an API key. Only a hash of the key is stored; Secret is set once, when the key is created.
+type Key struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Secret     string     `json:"key,omitempty"`
+	Scopes     []Scope    `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// HasScope reports whether the key grants scope. Admin keys grant every scope.
+func (k Key) HasScope(scope Scope) bool {
+	return slices.Contains(k.Scopes, ScopeAdmin) || slices.Contains(k.Scopes, scope)
+}
+
+// Usable reports whether the key can authenticate at now
+func (k Key) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// IsKey reports whether credential looks like an API key rather than some other bearer token
+func IsKey(credential string) bool {
+	return strings.HasPrefix(credential, keyPrefix)
+}
+
+// ParseScopes validates a list of scope names
+func ParseScopes(names []string) ([]Scope, error) {
+	if len(names) == 0 {
+		return nil, apperrors.Validation("at least one scope is required")
+	}
+	scopes := []Scope{}
+	for _, name := range names {
+		scope := Scope(strings.TrimSpace(name))
+		if !slices.Contains(Scopes, scope) {
+			return nil, apperrors.Newf(apperrors.KindValidation, "unknown scope %q", name)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// Hash returns the stored form of a key. Keys are random 256-bit values, so a fast hash is enough.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateKey() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return keyPrefix + hex.EncodeToString(b), nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the key that authenticated the request
+func NewContext(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the key that authenticated the request, if any
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(Key)
+	return key, ok
+}
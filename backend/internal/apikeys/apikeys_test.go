@@ -0,0 +1,81 @@
+package apikeys
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateKey(t *testing.T) {
+	key, err := generateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if !strings.HasPrefix(key, keyPrefix) || len(key) != len(keyPrefix)+64 {
+		t.Errorf("Expected an %s key with 64 hex characters, but got %q", keyPrefix, key)
+	}
+	other, _ := generateKey()
+	if key == other || Hash(key) == Hash(other) {
+		t.Errorf("Expected distinct keys and hashes")
+	}
+	if hash := Hash(key); len(hash) != 64 || strings.Contains(hash, key[len(keyPrefix):]) {
+		t.Errorf("Expected a SHA-256 hex hash that doesn't contain the key, but got %q", hash)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes([]string{"read", " enrich", "read"})
+	if err != nil || len(scopes) != 2 || scopes[0] != ScopeRead || scopes[1] != ScopeEnrich {
+		t.Errorf("Expected [read enrich], but got %v (%v)", scopes, err)
+	}
+	if _, err = ParseScopes([]string{"read", "delete"}); err == nil {
+		t.Errorf("Expected an unknown scope to be rejected")
+	}
+	if _, err = ParseScopes(nil); err == nil {
+		t.Errorf("Expected an empty scope list to be rejected")
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	reader := Key{Scopes: []Scope{ScopeRead, ScopeSearch}}
+	if !reader.HasScope(ScopeSearch) || reader.HasScope(ScopeEnrich) {
+		t.Errorf("Expected a read/search key to grant search but not enrich")
+	}
+	admin := Key{Scopes: []Scope{ScopeAdmin}}
+	if !admin.HasScope(ScopeWebhook) {
+		t.Errorf("Expected an admin key to grant every scope")
+	}
+}
+
+func TestUsable(t *testing.T) {
+	now := time.Date(2026, 1, 21, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	tests := []struct {
+		name     string
+		key      Key
+		expected bool
+	}{
+		{"no expiry", Key{}, true},
+		{"expires later", Key{ExpiresAt: &future}, true},
+		{"expired", Key{ExpiresAt: &past}, false},
+		{"revoked", Key{RevokedAt: &past, ExpiresAt: &future}, false},
+	}
+	for _, test := range tests {
+		if usable := test.key.Usable(now); usable != test.expected {
+			t.Errorf("%s: expected usable %v, but got %v", test.name, test.expected, usable)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Errorf("Expected no key in an empty context")
+	}
+	ctx := NewContext(context.Background(), Key{Id: "key-1", Name: "ops"})
+	key, ok := FromContext(ctx)
+	if !ok || key.Name != "ops" {
+		t.Errorf("Expected the ops key from the context, but got %+v", key)
+	}
+}
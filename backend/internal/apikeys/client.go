@@ -0,0 +1,170 @@
+package apikeys
+
+import (
+	"eatsavvy/internal/apperrors"
+	"eatsavvy/pkg/db"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// lastUsedResolution is how stale last_used_at may get, so authenticating doesn't write on every request
+const lastUsedResolution = time.Minute
+
+type ApiKeysClient struct {
+	dbClient *db.DatabaseClient
+}
+
+func NewApiKeysClient() *ApiKeysClient {
+	return &ApiKeysClient{
+		dbClient: db.NewDatabaseClient(),
+	}
+}
+
+func (kc *ApiKeysClient) Close() {
+	kc.dbClient.Close()
+}
+
+// CreateKey mints a key for name with the given scopes, optionally expiring at expiresAt. The returned key
+// includes the secret; it is not returned again.
+func (kc *ApiKeysClient) CreateKey(name string, scopes []Scope, expiresAt *time.Time) (Key, error) {
+	if strings.TrimSpace(name) == "" {
+		return Key{}, apperrors.Validation("name is required")
+	}
+	if len(scopes) == 0 {
+		return Key{}, apperrors.Validation("at least one scope is required")
+	}
+	secret, err := generateKey()
+	if err != nil {
+		slog.Error("[apikeys.CreateKey] Failed to generate key", "error", err)
+		return Key{}, err
+	}
+	key := Key{Name: name, Prefix: secret[:displayPrefixLength], Secret: secret, Scopes: scopes, ExpiresAt: expiresAt}
+
+	err = kc.dbClient.Db.QueryRow(kc.dbClient.Ctx,
+		`INSERT INTO public.api_keys (name, prefix, key_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING id::text, created_at`,
+		key.Name, key.Prefix, Hash(secret), scopeNames(scopes), expiresAt,
+	).Scan(&key.Id, &key.CreatedAt)
+	if err != nil {
+		slog.Error("[apikeys.CreateKey] Failed to insert key", "error", err)
+		return Key{}, err
+	}
+	slog.Info("[apikeys.CreateKey] Created api key", "id", key.Id, "name", key.Name, "scopes", scopes)
+	return key, nil
+}
+
+// ImportLegacyKey stores secret, the shared key the API accepted before keys were minted, as a key named
+// "legacy" with the given scopes that stops working at expiresAt. Importing it again changes nothing, so
+// restarts don't extend it. Minted keys are left alone.
+func (kc *ApiKeysClient) ImportLegacyKey(secret string, scopes []Scope, expiresAt time.Time) error {
+	if secret == "" || IsKey(secret) {
+		return nil
+	}
+	tag, err := kc.dbClient.Db.Exec(kc.dbClient.Ctx,
+		`INSERT INTO public.api_keys (name, prefix, key_hash, scopes, expires_at) VALUES ('legacy', $1, $2, $3, $4)
+			ON CONFLICT (key_hash) DO NOTHING`,
+		secret[:min(len(secret), legacyPrefixLength)], Hash(secret), scopeNames(scopes), expiresAt,
+	)
+	if err != nil {
+		slog.Error("[apikeys.ImportLegacyKey] Failed to import legacy key", "error", err)
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		slog.Info("[apikeys.ImportLegacyKey] Imported legacy key", "scopes", scopes, "expiresAt", expiresAt)
+	}
+	return nil
+}
+
+func (kc *ApiKeysClient) ListKeys() ([]Key, error) {
+	rows, err := kc.dbClient.Db.Query(kc.dbClient.Ctx,
+		`SELECT id::text, name, prefix, scopes, expires_at, revoked_at, last_used_at, created_at FROM public.api_keys ORDER BY created_at`,
+	)
+	if err != nil {
+		slog.Error("[apikeys.ListKeys] Failed to list keys", "error", err)
+		return []Key{}, err
+	}
+	defer rows.Close()
+	keys := []Key{}
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			slog.Error("[apikeys.ListKeys] Failed to scan key", "error", err)
+			return []Key{}, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// RevokeKey stops the key from authenticating. Revoked keys are kept so they still show up in listings.
+func (kc *ApiKeysClient) RevokeKey(id string) (Key, error) {
+	key, err := scanKey(kc.dbClient.Db.QueryRow(kc.dbClient.Ctx,
+		`UPDATE public.api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id::text = $1
+			RETURNING id::text, name, prefix, scopes, expires_at, revoked_at, last_used_at, created_at`,
+		id,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Key{}, ErrKeyNotFound
+	}
+	if err != nil {
+		slog.Error("[apikeys.RevokeKey] Failed to revoke key", "error", err, "id", id)
+		return Key{}, err
+	}
+	slog.Info("[apikeys.RevokeKey] Revoked api key", "id", key.Id, "name", key.Name)
+	return key, nil
+}
+
+// Authenticate returns the key for secret, or ErrInvalidKey if it is unknown, expired or revoked. Besides
+// minted keys, this accepts an imported legacy key (see ImportLegacyKey).
+func (kc *ApiKeysClient) Authenticate(secret string) (Key, error) {
+	if secret == "" {
+		return Key{}, ErrInvalidKey
+	}
+	key, err := scanKey(kc.dbClient.Db.QueryRow(kc.dbClient.Ctx,
+		`SELECT id::text, name, prefix, scopes, expires_at, revoked_at, last_used_at, created_at FROM public.api_keys WHERE key_hash = $1`,
+		Hash(secret),
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Key{}, ErrInvalidKey
+	}
+	if err != nil {
+		slog.Error("[apikeys.Authenticate] Failed to look up key", "error", err)
+		return Key{}, err
+	}
+	now := time.Now()
+	if !key.Usable(now) {
+		return Key{}, ErrInvalidKey
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedResolution {
+		_, err = kc.dbClient.Db.Exec(kc.dbClient.Ctx, `UPDATE public.api_keys SET last_used_at = NOW() WHERE id::text = $1`, key.Id)
+		if err != nil {
+			// Not worth failing the request over
+			slog.Error("[apikeys.Authenticate] Failed to record key use", "error", err, "id", key.Id)
+		}
+	}
+	return key, nil
+}
+
+func scanKey(row pgx.Row) (Key, error) {
+	var key Key
+	var scopes []string
+	err := row.Scan(&key.Id, &key.Name, &key.Prefix, &scopes, &key.ExpiresAt, &key.RevokedAt, &key.LastUsedAt, &key.CreatedAt)
+	if err != nil {
+		return Key{}, err
+	}
+	for _, scope := range scopes {
+		key.Scopes = append(key.Scopes, Scope(scope))
+	}
+	return key, nil
+}
+
+func scopeNames(scopes []Scope) []string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return names
+}
@@ -0,0 +1,99 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kind classifies an error by how a client should react to it, which decides its HTTP status
+type Kind string
+
+const (
+	KindNotFound     Kind = "not_found"
+	KindConflict     Kind = "conflict"
+	KindValidation   Kind = "validation"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+	// KindUpstream is a failure of a service we depend on, such as Places or the OIDC provider
+	KindUpstream    Kind = "upstream"
+	KindRateLimited Kind = "rate_limited"
+	// KindInternal is anything unexpected. Its details are logged rather than returned to clients.
+	KindInternal Kind = "internal"
+)
+
+// Error is an error with a Kind. Packages declare their sentinel errors with New so callers can still match
+// them with errors.Is, while the API maps them to a status with KindOf.
+type Error struct {
+	Kind Kind
+	// Message is returned to clients; if empty, Err's message is used
+	Message string
+	// Err is the underlying error, if any
+	Err error
+	// RetryAfter is how long a rate limited client should wait, if known
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" && e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Newf formats the message like fmt.Errorf, wrapping any %w argument
+func Newf(kind Kind, format string, args ...any) *Error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Kind: kind, Message: err.Error(), Err: errors.Unwrap(err)}
+}
+
+// Wrap gives err a kind, keeping its message
+func Wrap(kind Kind, err error) *Error {
+	return &Error{Kind: kind, Err: err}
+}
+
+func NotFound(message string) *Error {
+	return New(KindNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(KindConflict, message)
+}
+
+func Validation(message string) *Error {
+	return New(KindValidation, message)
+}
+
+func Upstream(message string) *Error {
+	return New(KindUpstream, message)
+}
+
+func RateLimited(message string, retryAfter time.Duration) *Error {
+	return &Error{Kind: KindRateLimited, Message: message, RetryAfter: retryAfter}
+}
+
+// KindOf returns the kind of the first Error in err's chain, or KindInternal if there is none
+func KindOf(err error) Kind {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Kind
+	}
+	return KindInternal
+}
+
+// RetryAfterOf returns how long to wait before retrying err, if it says
+func RetryAfterOf(err error) time.Duration {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.RetryAfter
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var errThingNotFound = NotFound("thing not found")
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected Kind
+	}{
+		{"sentinel", errThingNotFound, KindNotFound},
+		{"wrapped sentinel", fmt.Errorf("loading: %w", errThingNotFound), KindNotFound},
+		{"wrapped plain error", Wrap(KindUpstream, errors.New("connection refused")), KindUpstream},
+		{"plain error", errors.New("boom"), KindInternal},
+	}
+	for _, test := range tests {
+		if kind := KindOf(test.err); kind != test.expected {
+			t.Errorf("%s: expected %s, but got %s", test.name, test.expected, kind)
+		}
+	}
+	if !errors.Is(fmt.Errorf("loading: %w", errThingNotFound), errThingNotFound) {
+		t.Errorf("Expected errors.Is to match a wrapped sentinel")
+	}
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("connection refused")
+	tests := []struct {
+		err      *Error
+		expected string
+	}{
+		{NotFound("thing not found"), "thing not found"},
+		{Wrap(KindUpstream, cause), "connection refused"},
+		{Newf(KindUpstream, "places: %w", cause), "places: connection refused"},
+		{Newf(KindRateLimited, "%w: 2 left", errThingNotFound), "thing not found: 2 left"},
+	}
+	for _, test := range tests {
+		if message := test.err.Error(); message != test.expected {
+			t.Errorf("Expected %q, but got %q", test.expected, message)
+		}
+	}
+	if !errors.Is(Newf(KindRateLimited, "%w: 2 left", errThingNotFound), errThingNotFound) {
+		t.Errorf("Expected Newf to wrap its %%w argument")
+	}
+	if RetryAfterOf(fmt.Errorf("x: %w", RateLimited("slow down", time.Second))) != time.Second {
+		t.Errorf("Expected the retry delay of a wrapped rate limit error")
+	}
+}
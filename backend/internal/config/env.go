@@ -1,6 +1,11 @@
 package config
 
-import "os"
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
 
 func GetEnvFile() string {
 	if os.Getenv("ENV") == "production" {
@@ -8,3 +13,45 @@ func GetEnvFile() string {
 	}
 	return ".env"
 }
+
+// GetEnvDuration reads a Go duration string (e.g. "72h") from the environment, returning fallback if unset or invalid.
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Error("[config.GetEnvDuration] Invalid duration, using fallback", "key", key, "value", value, "fallback", fallback)
+		return fallback
+	}
+	return duration
+}
+
+// GetEnvInt reads an integer from the environment, returning fallback if unset or invalid.
+func GetEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Error("[config.GetEnvInt] Invalid integer, using fallback", "key", key, "value", value, "fallback", fallback)
+		return fallback
+	}
+	return number
+}
+
+// GetEnvFloat reads a decimal number from the environment, returning fallback if unset or invalid.
+func GetEnvFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Error("[config.GetEnvFloat] Invalid number, using fallback", "key", key, "value", value, "fallback", fallback)
+		return fallback
+	}
+	return number
+}
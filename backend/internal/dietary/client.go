@@ -0,0 +1,134 @@
+package dietary
+
+import (
+	"eatsavvy/pkg/db"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const profileColumns = `id::text, owner_user_id::text, name, allergies, avoided_oils, diet, must_have_vegetables, created_at, updated_at`
+
+// DietaryClient stores dietary profiles. Methods take the signed in user's id as ownerId, limiting them to the
+// user's own profiles; a nil ownerId (API keys) only reaches profiles without an owner, never a user's.
+type DietaryClient struct {
+	dbClient *db.DatabaseClient
+}
+
+func NewDietaryClient() *DietaryClient {
+	return &DietaryClient{
+		dbClient: db.NewDatabaseClient(),
+	}
+}
+
+func (dc *DietaryClient) Close() {
+	dc.dbClient.Close()
+}
+
+func (dc *DietaryClient) CreateProfile(profile Profile, ownerId *string) (Profile, error) {
+	profile, err := Normalize(profile)
+	if err != nil {
+		return Profile{}, err
+	}
+	created, err := scanProfile(dc.dbClient.Db.QueryRow(dc.dbClient.Ctx,
+		`INSERT INTO public.dietary_profiles (owner_user_id, name, allergies, avoided_oils, diet, must_have_vegetables)
+			VALUES ($1::uuid, $2, $3, $4, $5, $6) RETURNING `+profileColumns,
+		ownerId, profile.Name, profile.Allergies, profile.AvoidedOils, profile.Diet, profile.MustHaveVegetables,
+	))
+	if err != nil {
+		slog.Error("[dietary.CreateProfile] Failed to insert profile", "error", err)
+		return Profile{}, err
+	}
+	slog.Info("[dietary.CreateProfile] Created dietary profile", "id", created.Id, "name", created.Name)
+	return created, nil
+}
+
+func (dc *DietaryClient) ListProfiles(ownerId *string) ([]Profile, error) {
+	rows, err := dc.dbClient.Db.Query(dc.dbClient.Ctx,
+		`SELECT `+profileColumns+` FROM public.dietary_profiles
+			WHERE owner_user_id IS NOT DISTINCT FROM $1::uuid ORDER BY created_at`,
+		ownerId,
+	)
+	if err != nil {
+		slog.Error("[dietary.ListProfiles] Failed to list profiles", "error", err)
+		return []Profile{}, err
+	}
+	defer rows.Close()
+	profiles := []Profile{}
+	for rows.Next() {
+		profile, err := scanProfile(rows)
+		if err != nil {
+			slog.Error("[dietary.ListProfiles] Failed to scan profile", "error", err)
+			return []Profile{}, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// GetProfile returns the profile, or ErrProfileNotFound if it doesn't exist or belongs to someone else
+func (dc *DietaryClient) GetProfile(id string, ownerId *string) (Profile, error) {
+	profile, err := scanProfile(dc.dbClient.Db.QueryRow(dc.dbClient.Ctx,
+		`SELECT `+profileColumns+` FROM public.dietary_profiles
+			WHERE id::text = $1 AND owner_user_id IS NOT DISTINCT FROM $2::uuid`,
+		id, ownerId,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Profile{}, ErrProfileNotFound
+	}
+	if err != nil {
+		slog.Error("[dietary.GetProfile] Failed to get profile", "error", err, "id", id)
+		return Profile{}, err
+	}
+	return profile, nil
+}
+
+// UpdateProfile replaces the profile's name and restrictions
+func (dc *DietaryClient) UpdateProfile(id string, profile Profile, ownerId *string) (Profile, error) {
+	profile, err := Normalize(profile)
+	if err != nil {
+		return Profile{}, err
+	}
+	updated, err := scanProfile(dc.dbClient.Db.QueryRow(dc.dbClient.Ctx,
+		`UPDATE public.dietary_profiles
+			SET name = $3, allergies = $4, avoided_oils = $5, diet = $6, must_have_vegetables = $7, updated_at = NOW()
+			WHERE id::text = $1 AND owner_user_id IS NOT DISTINCT FROM $2::uuid
+			RETURNING `+profileColumns,
+		id, ownerId, profile.Name, profile.Allergies, profile.AvoidedOils, profile.Diet, profile.MustHaveVegetables,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Profile{}, ErrProfileNotFound
+	}
+	if err != nil {
+		slog.Error("[dietary.UpdateProfile] Failed to update profile", "error", err, "id", id)
+		return Profile{}, err
+	}
+	return updated, nil
+}
+
+func (dc *DietaryClient) DeleteProfile(id string, ownerId *string) error {
+	tag, err := dc.dbClient.Db.Exec(dc.dbClient.Ctx,
+		`DELETE FROM public.dietary_profiles WHERE id::text = $1 AND owner_user_id IS NOT DISTINCT FROM $2::uuid`,
+		id, ownerId,
+	)
+	if err != nil {
+		slog.Error("[dietary.DeleteProfile] Failed to delete profile", "error", err, "id", id)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrProfileNotFound
+	}
+	slog.Info("[dietary.DeleteProfile] Deleted dietary profile", "id", id)
+	return nil
+}
+
+func scanProfile(row pgx.Row) (Profile, error) {
+	var profile Profile
+	err := row.Scan(&profile.Id, &profile.OwnerUserId, &profile.Name, &profile.Allergies, &profile.AvoidedOils,
+		&profile.Diet, &profile.MustHaveVegetables, &profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		return Profile{}, err
+	}
+	return profile, nil
+}
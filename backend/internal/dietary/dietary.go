@@ -0,0 +1,82 @@
+package dietary
+
+import (
+	"eatsavvy/internal/apperrors"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Diet string
+
+const (
+	DietVegetarian  Diet = "vegetarian"
+	DietVegan       Diet = "vegan"
+	DietPescatarian Diet = "pescatarian"
+	DietGlutenFree  Diet = "gluten_free"
+	DietDairyFree   Diet = "dairy_free"
+	DietHalal       Diet = "halal"
+	DietKosher      Diet = "kosher"
+)
+
+var Diets = []Diet{DietVegetarian, DietVegan, DietPescatarian, DietGlutenFree, DietDairyFree, DietHalal, DietKosher}
+
+var ErrProfileNotFound = apperrors.NotFound("dietary profile not found")
+
+// Profile is one person's dietary restrictions, used to rank restaurants by how well their nutrition info fits
+type Profile struct {
+	Id string `json:"id"`
+	// OwnerUserId is the signed in user who created the profile; profiles created with an API key have no owner
+	OwnerUserId        *string   `json:"ownerUserId"`
+	Name               string    `json:"name"`
+	Allergies          []string  `json:"allergies"`
+	AvoidedOils        []string  `json:"avoidedOils"`
+	Diet               *Diet     `json:"diet"`
+	MustHaveVegetables []string  `json:"mustHaveVegetables"`
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+// Conflict is something about a restaurant that goes against the profile
+type Conflict struct {
+	// Field is the nutrition info field the conflict was found in: oil, nutFree, accommodations or vegetables
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Compatibility is how well a restaurant fits a profile. Score is from 0 to 100, or nil when the restaurant
+// hasn't been enriched yet and there is nothing to score.
+type Compatibility struct {
+	ProfileId string     `json:"profileId"`
+	Score     *int       `json:"score"`
+	Conflicts []Conflict `json:"conflicts"`
+}
+
+// Normalize trims and lowercases the profile's lists, dropping empty and duplicate entries, and validates it
+func Normalize(profile Profile) (Profile, error) {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if profile.Name == "" {
+		return Profile{}, apperrors.Validation("name is required")
+	}
+	if profile.Diet != nil && *profile.Diet == "" {
+		profile.Diet = nil
+	}
+	if profile.Diet != nil && !slices.Contains(Diets, *profile.Diet) {
+		return Profile{}, apperrors.Newf(apperrors.KindValidation, "unknown diet %q", *profile.Diet)
+	}
+	profile.Allergies = normalizeList(profile.Allergies)
+	profile.AvoidedOils = normalizeList(profile.AvoidedOils)
+	profile.MustHaveVegetables = normalizeList(profile.MustHaveVegetables)
+	return profile, nil
+}
+
+func normalizeList(values []string) []string {
+	normalized := []string{}
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" && !slices.Contains(normalized, value) {
+			normalized = append(normalized, value)
+		}
+	}
+	return normalized
+}
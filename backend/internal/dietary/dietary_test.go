@@ -0,0 +1,36 @@
+package dietary
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	empty := Diet("")
+	profile, err := Normalize(Profile{
+		Name:        "  Ada ",
+		Allergies:   []string{"Peanuts", " peanuts", "", "Shellfish"},
+		AvoidedOils: nil,
+		Diet:        &empty,
+	})
+	if err != nil {
+		t.Fatalf("Expected the profile to be valid, but got %v", err)
+	}
+	if profile.Name != "Ada" || fmt.Sprint(profile.Allergies) != "[peanuts shellfish]" || profile.AvoidedOils == nil || profile.Diet != nil {
+		t.Errorf("Expected a trimmed, deduplicated profile without a diet, but got %+v", profile)
+	}
+
+	unknown := Diet("carnivore")
+	tests := []struct {
+		name    string
+		profile Profile
+	}{
+		{"missing name", Profile{Name: " "}},
+		{"unknown diet", Profile{Name: "Ada", Diet: &unknown}},
+	}
+	for _, test := range tests {
+		if _, err := Normalize(test.profile); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package enrichment
+
+import (
+	"eatsavvy/pkg/db"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type EnrichmentClient struct {
+	dbClient *db.DatabaseClient
+}
+
+func NewEnrichmentClient() *EnrichmentClient {
+	return &EnrichmentClient{
+		dbClient: db.NewDatabaseClient(),
+	}
+}
+
+func (ec *EnrichmentClient) Close() {
+	ec.dbClient.Close()
+}
+
+func (ec *EnrichmentClient) GetJob(id string) (Job, error) {
+	return GetJob(ec.dbClient.Ctx, ec.dbClient.Db, id)
+}
+
+// CancelJob cancels the job's queued restaurants: their queue messages are dropped from the outbox if not yet
+// published, and otherwise skipped by the worker because the restaurant is marked cancelled. Restaurants whose
+// call has already been placed finish normally. Cancelling a cancelled job is a no-op. With an owner, only jobs
+// requested by the owner's user or key are found; without one (admin keys) any job is.
+func (ec *EnrichmentClient) CancelJob(id string, owner *Requester) (Job, error) {
+	anyJob := owner == nil
+	if anyJob {
+		owner = &Requester{}
+	}
+
+	tx, err := ec.dbClient.Db.Begin(ec.dbClient.Ctx)
+	if err != nil {
+		slog.Error("[enrichment.CancelJob] Failed to begin transaction", "error", err)
+		return Job{}, err
+	}
+	defer tx.Rollback(ec.dbClient.Ctx)
+
+	var jobId string
+	var alreadyCancelled bool
+	err = tx.QueryRow(ec.dbClient.Ctx,
+		`SELECT id::text, cancelled_at IS NOT NULL FROM public.enrichment_jobs
+			WHERE id::text = $1 AND ($2 OR requested_by_user_id = $3::uuid OR requested_by_key_id = $4::uuid) FOR UPDATE`,
+		id, anyJob, owner.UserId, owner.KeyId,
+	).Scan(&jobId, &alreadyCancelled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Job{}, ErrJobNotFound
+	}
+	if err != nil {
+		slog.Error("[enrichment.CancelJob] Failed to get job", "error", err, "jobId", id)
+		return Job{}, err
+	}
+	if alreadyCancelled {
+		return GetJob(ec.dbClient.Ctx, tx, jobId)
+	}
+
+	_, err = tx.Exec(ec.dbClient.Ctx,
+		`UPDATE public.enrichment_jobs SET cancelled_at = NOW(), updated_at = NOW() WHERE id = $1::uuid`,
+		jobId,
+	)
+	if err != nil {
+		slog.Error("[enrichment.CancelJob] Failed to cancel job", "error", err, "jobId", jobId)
+		return Job{}, err
+	}
+
+	tag, err := tx.Exec(ec.dbClient.Ctx,
+		`WITH cancelled AS (
+			UPDATE public.enrichment_job_items SET status = $2, reason = 'cancelled by request', updated_at = NOW()
+				WHERE job_id = $1::uuid AND status = $3 RETURNING places_id
+		), restaurants AS (
+			UPDATE public.restaurants r SET enrichment_status = $2
+				FROM cancelled WHERE r.places_id = cancelled.places_id AND r.enrichment_status = $3
+				RETURNING r.enrichment_job_id
+		)
+		DELETE FROM public.outbox o USING restaurants
+			WHERE o.message_id = restaurants.enrichment_job_id AND o.published_at IS NULL`,
+		jobId, StatusCancelled, StatusQueued,
+	)
+	if err != nil {
+		slog.Error("[enrichment.CancelJob] Failed to cancel queued restaurants", "error", err, "jobId", jobId)
+		return Job{}, err
+	}
+
+	job, err := GetJob(ec.dbClient.Ctx, tx, jobId)
+	if err != nil {
+		return Job{}, err
+	}
+	if err = tx.Commit(ec.dbClient.Ctx); err != nil {
+		slog.Error("[enrichment.CancelJob] Failed to commit transaction", "error", err)
+		return Job{}, err
+	}
+	slog.Info("[enrichment.CancelJob] Cancelled enrichment job", "jobId", jobId, "unpublishedMessagesDropped", tag.RowsAffected())
+	return job, nil
+}
@@ -0,0 +1,186 @@
+package enrichment
+
+import (
+	"context"
+	"eatsavvy/internal/apperrors"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Status is the status of an enrichment job or of one restaurant within it
+type Status string
+
+const (
+	StatusQueued     Status = "queued"
+	StatusInProgress Status = "in_progress"
+	StatusCompleted  Status = "completed"
+	StatusFailed     Status = "failed"
+	StatusCancelled  Status = "cancelled"
+	// StatusSkipped is used for restaurants that didn't need enriching, e.g. because they were already queued
+	StatusSkipped Status = "skipped"
+)
+
+const (
+	RequestedByApi       = "api"
+	RequestedByScheduler = "scheduler"
+)
+
+var ErrJobNotFound = apperrors.NotFound("enrichment job not found")
+
+// Requester is who a job is attributed to: an API key or the scheduler by name, or a signed in user. The key
+// or user is charged for the job's usage.
+type Requester struct {
+	Name   string
+	KeyId  *string
+	UserId *string
+}
+
+// Job is a request to enrich a set of restaurants. Its status is derived from its items. RequestedByUserId is
+// set for jobs requested by a signed in user.
+type Job struct {
+	Id                string     `json:"id"`
+	RequestedBy       string     `json:"requestedBy"`
+	RequestedByUserId *string    `json:"requestedByUserId"`
+	Status            Status     `json:"status"`
+	Items             []Item     `json:"items"`
+	CancelledAt       *time.Time `json:"cancelledAt"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+}
+
+type Item struct {
+	RestaurantId string    `json:"restaurantId"`
+	Status       Status    `json:"status"`
+	Reason       *string   `json:"reason"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// DBTX is implemented by both *pgxpool.Pool and pgx.Tx, so items can be recorded inside the caller's transaction
+type DBTX interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// CreateJob stores a new job with no items and returns its id
+func CreateJob(ctx context.Context, db DBTX, requester Requester) (string, error) {
+	var id string
+	err := db.QueryRow(ctx,
+		`INSERT INTO public.enrichment_jobs (requested_by, requested_by_key_id, requested_by_user_id) VALUES ($1, $2::uuid, $3::uuid)
+			RETURNING id::text`,
+		requester.Name, requester.KeyId, requester.UserId,
+	).Scan(&id)
+	if err != nil {
+		slog.Error("[enrichment.CreateJob] Failed to insert job", "error", err)
+		return "", err
+	}
+	return id, nil
+}
+
+// AddItem records the restaurant as part of the job. reason may be empty.
+func AddItem(ctx context.Context, db DBTX, jobId string, placesId string, status Status, reason string) error {
+	var reasonPtr *string
+	if reason != "" {
+		reasonPtr = &reason
+	}
+	_, err := db.Exec(ctx,
+		`INSERT INTO public.enrichment_job_items (job_id, places_id, status, reason) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (job_id, places_id) DO NOTHING`,
+		jobId, placesId, status, reasonPtr,
+	)
+	if err != nil {
+		slog.Error("[enrichment.AddItem] Failed to insert job item", "error", err, "jobId", jobId, "places_id", placesId)
+		return err
+	}
+	return nil
+}
+
+// SetRestaurantStatus moves the restaurant's queued or in progress item, if any, to status. Cancelled
+// items are left alone, so a call that was already placed when its job was cancelled doesn't revive it.
+func SetRestaurantStatus(ctx context.Context, db DBTX, placesId string, status Status) error {
+	_, err := db.Exec(ctx,
+		`UPDATE public.enrichment_job_items SET status = $1, updated_at = NOW()
+			WHERE places_id = $2 AND status IN ($3, $4)`,
+		status, placesId, StatusQueued, StatusInProgress,
+	)
+	if err != nil {
+		slog.Error("[enrichment.SetRestaurantStatus] Failed to update job item", "error", err, "places_id", placesId, "status", status)
+		return err
+	}
+	return nil
+}
+
+// GetJob returns the job with its items, or ErrJobNotFound
+func GetJob(ctx context.Context, db DBTX, id string) (Job, error) {
+	var job Job
+	err := db.QueryRow(ctx,
+		`SELECT id::text, requested_by, requested_by_user_id::text, cancelled_at, created_at, updated_at
+			FROM public.enrichment_jobs WHERE id::text = $1`,
+		id,
+	).Scan(&job.Id, &job.RequestedBy, &job.RequestedByUserId, &job.CancelledAt, &job.CreatedAt, &job.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Job{}, ErrJobNotFound
+	}
+	if err != nil {
+		slog.Error("[enrichment.GetJob] Failed to get job", "error", err, "jobId", id)
+		return Job{}, err
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT places_id, status, reason, created_at, updated_at FROM public.enrichment_job_items
+			WHERE job_id = $1::uuid ORDER BY created_at, places_id`,
+		job.Id,
+	)
+	if err != nil {
+		slog.Error("[enrichment.GetJob] Failed to get job items", "error", err, "jobId", id)
+		return Job{}, err
+	}
+	defer rows.Close()
+	job.Items = []Item{}
+	for rows.Next() {
+		var item Item
+		err = rows.Scan(&item.RestaurantId, &item.Status, &item.Reason, &item.CreatedAt, &item.UpdatedAt)
+		if err != nil {
+			slog.Error("[enrichment.GetJob] Failed to scan job item", "error", err, "jobId", id)
+			return Job{}, err
+		}
+		job.Items = append(job.Items, item)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error("[enrichment.GetJob] Failed to read job items", "error", err, "jobId", id)
+		return Job{}, err
+	}
+	job.Status = jobStatus(job.Items, job.CancelledAt != nil)
+	return job, nil
+}
+
+// jobStatus derives a job's status from its items: queued while nothing has started, in progress while any
+// restaurant is queued or being called, and completed once none are. Cancelled jobs stay cancelled even if
+// a call that was already placed finishes afterwards.
+func jobStatus(items []Item, cancelled bool) Status {
+	if cancelled {
+		return StatusCancelled
+	}
+	queued, active := 0, 0
+	for _, item := range items {
+		switch item.Status {
+		case StatusQueued:
+			queued++
+			active++
+		case StatusInProgress:
+			active++
+		}
+	}
+	if queued > 0 && queued == len(items) {
+		return StatusQueued
+	}
+	if active > 0 {
+		return StatusInProgress
+	}
+	return StatusCompleted
+}
@@ -0,0 +1,33 @@
+package enrichment
+
+import "testing"
+
+func TestJobStatus(t *testing.T) {
+	items := func(statuses ...Status) []Item {
+		result := []Item{}
+		for _, status := range statuses {
+			result = append(result, Item{Status: status})
+		}
+		return result
+	}
+	tests := []struct {
+		name      string
+		items     []Item
+		cancelled bool
+		expected  Status
+	}{
+		{"all queued", items(StatusQueued, StatusQueued), false, StatusQueued},
+		{"some in progress", items(StatusQueued, StatusInProgress), false, StatusInProgress},
+		{"some finished", items(StatusQueued, StatusCompleted, StatusSkipped), false, StatusInProgress},
+		{"all finished", items(StatusCompleted, StatusFailed, StatusSkipped), false, StatusCompleted},
+		{"only skipped", items(StatusSkipped), false, StatusCompleted},
+		{"no items", items(), false, StatusCompleted},
+		{"cancelled with a call in progress", items(StatusCancelled, StatusInProgress), true, StatusCancelled},
+	}
+	for _, test := range tests {
+		status := jobStatus(test.items, test.cancelled)
+		if status != test.expected {
+			t.Errorf("%s: expected %s, but got %s", test.name, test.expected, status)
+		}
+	}
+}
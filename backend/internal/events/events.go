@@ -0,0 +1,153 @@
+package events
+
+import (
+	"context"
+	"eatsavvy/internal/places"
+	"eatsavvy/pkg/backoff"
+	"eatsavvy/pkg/db"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Channel is the Postgres NOTIFY channel the restaurant_events trigger publishes to
+const Channel = "restaurant_events"
+
+// subscriberBuffer is how many events a slow subscriber may fall behind before events are dropped for it
+const subscriberBuffer = 32
+
+// reconnectBackoff is how long the listener waits after consecutive failures to LISTEN
+var reconnectBackoff = backoff.Exponential{Initial: time.Second, Max: 30 * time.Second}
+
+// Event is a change to a restaurant's enrichment status or nutrition info
+type Event struct {
+	RestaurantId             string                  `json:"id"`
+	EnrichmentStatus         places.EnrichmentStatus `json:"enrichmentStatus"`
+	PreviousEnrichmentStatus places.EnrichmentStatus `json:"previousEnrichmentStatus,omitempty"`
+	NutritionInfo            *places.NutritionInfo   `json:"nutritionInfo,omitempty"`
+	UpdatedAt                time.Time               `json:"updatedAt"`
+	// Truncated is set when nutrition info was too large for a notification and has to be fetched separately
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Broker listens for restaurant events on a dedicated Postgres connection and fans them out to subscribers.
+// Every API replica runs its own Broker, so clients get events no matter which replica or worker caused them.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives events for a set of restaurants, or for all restaurants if none were given
+type Subscription struct {
+	Events        <-chan Event
+	events        chan Event
+	restaurantIds map[string]bool
+	broker        *Broker
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: map[*Subscription]struct{}{}}
+}
+
+// Start listens for notifications until ctx is done, reconnecting with backoff when the connection is lost
+func (b *Broker) Start(ctx context.Context) {
+	slog.Info("[events.Broker.Start] Starting restaurant event listener", "channel", Channel)
+	attempt := 0
+	for {
+		err := b.listen(ctx, func() { attempt = 0 })
+		if ctx.Err() != nil {
+			slog.Info("[events.Broker.Start] Stopping restaurant event listener")
+			return
+		}
+		attempt++
+		delay := reconnectBackoff.Delay(attempt)
+		slog.Error("[events.Broker.Start] Lost event listener connection, reconnecting", "error", err, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (b *Broker) listen(ctx context.Context, onListening func()) error {
+	// LISTEN belongs to a session, so the listener keeps a connection of its own rather than using a pool
+	conn, err := db.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+Channel)
+	if err != nil {
+		slog.Error("[events.Broker.listen] Failed to listen for restaurant events", "error", err)
+		return err
+	}
+	onListening()
+	slog.Info("[events.Broker.listen] Listening for restaurant events", "channel", Channel)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var event Event
+		err = json.Unmarshal([]byte(notification.Payload), &event)
+		if err != nil {
+			slog.Error("[events.Broker.listen] Failed to decode restaurant event", "error", err, "payload", notification.Payload)
+			continue
+		}
+		b.publish(event)
+	}
+}
+
+// publish hands an event to every interested subscriber without blocking on slow ones
+func (b *Broker) publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for subscription := range b.subscribers {
+		if !subscription.wants(event.RestaurantId) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			slog.Error("[events.Broker.publish] Subscriber is falling behind, dropping event", "restaurantId", event.RestaurantId)
+		}
+	}
+}
+
+// Subscribe starts receiving events for the given restaurants, or all restaurants if restaurantIds is empty.
+// The subscription must be closed when no longer needed.
+func (b *Broker) Subscribe(restaurantIds ...string) *Subscription {
+	events := make(chan Event, subscriberBuffer)
+	subscription := &Subscription{
+		Events:        events,
+		events:        events,
+		restaurantIds: map[string]bool{},
+		broker:        b,
+	}
+	for _, restaurantId := range restaurantIds {
+		subscription.restaurantIds[restaurantId] = true
+	}
+	b.mu.Lock()
+	b.subscribers[subscription] = struct{}{}
+	b.mu.Unlock()
+	return subscription
+}
+
+func (s *Subscription) wants(restaurantId string) bool {
+	return len(s.restaurantIds) == 0 || s.restaurantIds[restaurantId]
+}
+
+// Close stops the subscription and closes its Events channel
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	if _, ok := s.broker.subscribers[s]; !ok {
+		return
+	}
+	delete(s.broker.subscribers, s)
+	close(s.events)
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPublishFiltersByRestaurant(t *testing.T) {
+	broker := NewBroker()
+	all := broker.Subscribe()
+	defer all.Close()
+	one := broker.Subscribe("a")
+	defer one.Close()
+
+	broker.publish(Event{RestaurantId: "a", EnrichmentStatus: "in_progress"})
+	broker.publish(Event{RestaurantId: "b", EnrichmentStatus: "queued"})
+
+	if got := len(all.Events); got != 2 {
+		t.Errorf("Expected 2 events for the unfiltered subscription, but got %d", got)
+	}
+	if got := len(one.Events); got != 1 {
+		t.Fatalf("Expected 1 event for the filtered subscription, but got %d", got)
+	}
+	if event := <-one.Events; event.RestaurantId != "a" {
+		t.Errorf("Expected event for restaurant a, but got %q", event.RestaurantId)
+	}
+}
+
+func TestPublishDropsEventsForSlowSubscribers(t *testing.T) {
+	broker := NewBroker()
+	subscription := broker.Subscribe()
+	defer subscription.Close()
+
+	for i := 0; i < subscriberBuffer+5; i++ {
+		broker.publish(Event{RestaurantId: "a"})
+	}
+	if got := len(subscription.Events); got != subscriberBuffer {
+		t.Errorf("Expected %d buffered events, but got %d", subscriberBuffer, got)
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	broker := NewBroker()
+	subscription := broker.Subscribe()
+	subscription.Close()
+	subscription.Close()
+
+	broker.publish(Event{RestaurantId: "a"})
+	select {
+	case _, ok := <-subscription.Events:
+		if ok {
+			t.Errorf("Expected no events after Close")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected Events to be closed")
+	}
+}
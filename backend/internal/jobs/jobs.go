@@ -0,0 +1,184 @@
+package jobs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"eatsavvy/internal/apperrors"
+	"eatsavvy/pkg/encoder"
+	"eatsavvy/pkg/queue"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type JobType string
+
+const (
+	JobTypeEnrichRestaurant JobType = "enrich_restaurant"
+)
+
+// Priority is how urgently a job should be processed relative to the rest of the queue
+type Priority string
+
+const (
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+)
+
+// ParsePriority accepts "high", "normal" or an empty string, which means normal
+func ParsePriority(priority string) (Priority, error) {
+	switch Priority(priority) {
+	case "", PriorityNormal:
+		return PriorityNormal, nil
+	case PriorityHigh:
+		return PriorityHigh, nil
+	default:
+		return "", apperrors.Newf(apperrors.KindValidation, "invalid priority %q: expected high or normal", priority)
+	}
+}
+
+// queuePriority maps a job priority to a message priority on the enrichment queue
+func (p Priority) queuePriority() uint8 {
+	if p == PriorityHigh {
+		return 5
+	}
+	return 0
+}
+
+// EnrichmentQueue is the queue the worker consumes enrichment jobs from
+const EnrichmentQueue = "enrich_restaurant_jobs"
+
+// legacyEnrichmentQueue is the non-durable queue without priorities that older builds used; RabbitMQ can't
+// redeclare it as EnrichmentQueue is declared, so its messages are moved over (see queue.Replace)
+const legacyEnrichmentQueue = "enrich_restaurant_details"
+
+func init() {
+	queue.Replace(EnrichmentQueue, legacyEnrichmentQueue)
+}
+
+// SchemaVersion is the version of the Job envelope written by this build.
+// Version 0 is the legacy gob-encoded places.Restaurant that predates the envelope.
+const SchemaVersion = 1
+
+const (
+	HeaderJobType       = "x-job-type"
+	HeaderSchemaVersion = "x-schema-version"
+	HeaderAttempt       = "x-attempt"
+	HeaderTraceId       = "x-trace-id"
+)
+
+// Job is the envelope published to the queue. It only carries the restaurant id plus a name for
+// logging; the worker loads everything else from the database when it processes the job.
+type Job struct {
+	Id             string    `json:"jobId"`
+	Type           JobType   `json:"jobType"`
+	SchemaVersion  int       `json:"schemaVersion"`
+	RestaurantId   string    `json:"restaurantId"`
+	RestaurantName string    `json:"restaurantName,omitempty"`
+	Attempt        int       `json:"attempt"`
+	TraceId        string    `json:"traceId"`
+	CreatedAt      time.Time `json:"createdAt"`
+	// Priority is kept across retries, so a delayed high priority job is still ahead of the queue when it returns
+	Priority Priority `json:"priority,omitempty"`
+}
+
+func NewEnrichmentJob(restaurantId string, restaurantName string) Job {
+	return Job{
+		Id:             newId(),
+		Type:           JobTypeEnrichRestaurant,
+		SchemaVersion:  SchemaVersion,
+		RestaurantId:   restaurantId,
+		RestaurantName: restaurantName,
+		Attempt:        1,
+		TraceId:        newId(),
+		CreatedAt:      time.Now().UTC(),
+	}
+}
+
+// Retry returns a copy of the job for the next attempt, keeping the job and trace ids.
+func (j Job) Retry() Job {
+	next := j
+	next.SchemaVersion = SchemaVersion
+	next.Attempt = j.Attempt + 1
+	return next
+}
+
+func (j Job) Properties() queue.Properties {
+	return queue.Properties{
+		MessageId:     j.Id,
+		Type:          string(j.Type),
+		CorrelationId: j.TraceId,
+		Timestamp:     j.CreatedAt,
+		Priority:      j.Priority.queuePriority(),
+		Headers: map[string]interface{}{
+			HeaderJobType:       string(j.Type),
+			HeaderSchemaVersion: int32(j.SchemaVersion),
+			HeaderAttempt:       int32(j.Attempt),
+			HeaderTraceId:       j.TraceId,
+		},
+	}
+}
+
+// Decode reads a job from a message body. JSON envelopes of any version up to SchemaVersion are
+// accepted, as are legacy gob-encoded restaurants published before the envelope existed.
+func Decode(body []byte, contentType string) (Job, error) {
+	if contentType == encoder.ContentTypeJSON || bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return decodeJSON(body)
+	}
+	return decodeLegacy(body)
+}
+
+func decodeJSON(body []byte) (Job, error) {
+	var job Job
+	err := encoder.FromJSON(body, &job)
+	if err != nil {
+		slog.Error("[jobs.decodeJSON] Failed to decode job", "error", err)
+		return Job{}, err
+	}
+	if job.SchemaVersion > SchemaVersion {
+		return Job{}, fmt.Errorf("unsupported job schema version %d (max %d)", job.SchemaVersion, SchemaVersion)
+	}
+	if job.RestaurantId == "" {
+		return Job{}, errors.New("job is missing restaurant id")
+	}
+	if job.Type == "" {
+		job.Type = JobTypeEnrichRestaurant
+	}
+	if job.Attempt == 0 {
+		job.Attempt = 1
+	}
+	return job, nil
+}
+
+// legacyRestaurant holds the fields we still need from a gob-encoded places.Restaurant.
+// gob matches fields by name, so the remaining fields are ignored.
+type legacyRestaurant struct {
+	Id   string
+	Name string
+}
+
+func decodeLegacy(body []byte) (Job, error) {
+	var restaurant legacyRestaurant
+	err := encoder.FromBytes(body, &restaurant)
+	if err != nil {
+		slog.Error("[jobs.decodeLegacy] Failed to decode legacy message", "error", err)
+		return Job{}, err
+	}
+	if restaurant.Id == "" {
+		return Job{}, errors.New("legacy message is missing restaurant id")
+	}
+	job := NewEnrichmentJob(restaurant.Id, restaurant.Name)
+	job.SchemaVersion = 0
+	return job, nil
+}
+
+func newId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"bytes"
+	"eatsavvy/pkg/encoder"
+	"encoding/gob"
+	"testing"
+	"time"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	job := NewEnrichmentJob("place-1", "Magnin Cafe")
+	body, err := encoder.ToJSON(job)
+	if err != nil {
+		t.Fatalf("Failed to encode job: %v", err)
+	}
+	decoded, err := Decode(body, encoder.ContentTypeJSON)
+	if err != nil {
+		t.Fatalf("Expected job to decode, but got error: %v", err)
+	}
+	if decoded.Id != job.Id || decoded.RestaurantId != "place-1" || decoded.SchemaVersion != SchemaVersion || decoded.Attempt != 1 {
+		t.Errorf("Expected decoded job to match %+v, but got %+v", job, decoded)
+	}
+}
+
+func TestDecodeJSONWithoutContentType(t *testing.T) {
+	decoded, err := Decode([]byte(`{"restaurantId":"place-1","schemaVersion":1}`), "")
+	if err != nil {
+		t.Fatalf("Expected job to decode, but got error: %v", err)
+	}
+	if decoded.Type != JobTypeEnrichRestaurant || decoded.Attempt != 1 {
+		t.Errorf("Expected missing type and attempt to be defaulted, but got %+v", decoded)
+	}
+}
+
+func TestDecodeRejectsNewerSchemaVersion(t *testing.T) {
+	_, err := Decode([]byte(`{"restaurantId":"place-1","schemaVersion":99}`), encoder.ContentTypeJSON)
+	if err == nil {
+		t.Errorf("Expected an error for a newer schema version, but got none")
+	}
+}
+
+func TestDecodeRejectsMissingRestaurantId(t *testing.T) {
+	_, err := Decode([]byte(`{"schemaVersion":1}`), encoder.ContentTypeJSON)
+	if err == nil {
+		t.Errorf("Expected an error for a job without a restaurant id, but got none")
+	}
+}
+
+func TestDecodeLegacyGob(t *testing.T) {
+	// Mirrors the shape of places.Restaurant that was gob-encoded onto the queue before the envelope
+	type restaurant struct {
+		Id          string
+		Name        string
+		PhoneNumber string
+		CreatedAt   time.Time
+	}
+	buffer := bytes.NewBuffer(nil)
+	err := gob.NewEncoder(buffer).Encode(restaurant{Id: "place-1", Name: "Magnin Cafe", PhoneNumber: "(555) 555-5555"})
+	if err != nil {
+		t.Fatalf("Failed to gob-encode restaurant: %v", err)
+	}
+	decoded, err := Decode(buffer.Bytes(), "")
+	if err != nil {
+		t.Fatalf("Expected legacy message to decode, but got error: %v", err)
+	}
+	if decoded.RestaurantId != "place-1" || decoded.RestaurantName != "Magnin Cafe" || decoded.SchemaVersion != 0 {
+		t.Errorf("Expected legacy job for place-1, but got %+v", decoded)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	job := NewEnrichmentJob("place-1", "Magnin Cafe")
+	retry := job.Retry()
+	if retry.Attempt != 2 || retry.Id != job.Id || retry.TraceId != job.TraceId {
+		t.Errorf("Expected retry to keep ids and increment attempt, but got %+v", retry)
+	}
+}
+
+func TestPriority(t *testing.T) {
+	priority, err := ParsePriority("")
+	if err != nil || priority != PriorityNormal {
+		t.Errorf("Expected an empty priority to be normal, but got %q (%v)", priority, err)
+	}
+	if _, err = ParsePriority("urgent"); err == nil {
+		t.Errorf("Expected an unknown priority to be rejected")
+	}
+
+	job := NewEnrichmentJob("place-1", "Magnin Cafe")
+	job.Priority = PriorityHigh
+	if job.Retry().Priority != PriorityHigh {
+		t.Errorf("Expected retry to keep the priority")
+	}
+	if job.Properties().Priority <= NewEnrichmentJob("place-2", "").Properties().Priority {
+		t.Errorf("Expected high priority jobs to have a higher message priority than normal ones")
+	}
+}
@@ -0,0 +1,237 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	devKeyId         = "dev-1"
+	devTokenLifetime = time.Hour
+	devCodeLifetime  = time.Minute
+)
+
+var authorizeForm = template.Must(template.New("authorize").Parse(`<!doctype html>
+<html>
+<head><title>EatSavvy dev sign in</title></head>
+<body style="font-family: sans-serif; max-width: 24rem; margin: 4rem auto">
+<h1>Dev sign in</h1>
+<p>Stand-in identity provider for local development. Any email signs in.</p>
+<form method="post">
+{{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}<p><label>Email<br><input name="email" type="email" required autofocus></label></p>
+<p><label>Name<br><input name="name"></label></p>
+<button type="submit">Sign in</button>
+</form>
+</body>
+</html>`))
+
+// authorizationCode is a pending code from /authorize, redeemed at /token
+type authorizationCode struct {
+	clientId      string
+	redirectUri   string
+	codeChallenge string
+	nonce         string
+	email         string
+	name          string
+	expiresAt     time.Time
+}
+
+// DevIssuer is a minimal OIDC provider for local development and tests. It signs in anyone who enters an
+// email, using the authorization code flow with PKCE, and signs ID tokens with a key generated at startup.
+type DevIssuer struct {
+	issuer string
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authorizationCode
+}
+
+func NewDevIssuer(issuer string) (*DevIssuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &DevIssuer{
+		issuer: strings.TrimSuffix(issuer, "/"),
+		key:    key,
+		codes:  map[string]authorizationCode{},
+	}, nil
+}
+
+func (d *DevIssuer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", d.discovery)
+	mux.HandleFunc("GET /jwks.json", d.jwks)
+	mux.HandleFunc("GET /authorize", d.authorizeForm)
+	mux.HandleFunc("POST /authorize", d.authorize)
+	mux.HandleFunc("OPTIONS /token", d.token)
+	mux.HandleFunc("POST /token", d.token)
+	return mux
+}
+
+// IssueToken signs a token for email directly, skipping the login flow
+func (d *DevIssuer) IssueToken(audience string, email string, name string) (string, error) {
+	now := time.Now()
+	return Sign(d.key, devKeyId, Claims{
+		Issuer:    d.issuer,
+		Subject:   subjectFor(email),
+		Audience:  Audience{audience},
+		ExpiresAt: now.Add(devTokenLifetime).Unix(),
+		IssuedAt:  now.Unix(),
+		Email:     email,
+		Name:      name,
+	})
+}
+
+func (d *DevIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, map[string]any{
+		"issuer":                                d.issuer,
+		"authorization_endpoint":                d.issuer + "/authorize",
+		"token_endpoint":                        d.issuer + "/token",
+		"jwks_uri":                              d.issuer + "/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (d *DevIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, jwks{Keys: []jwk{jwkFromPublicKey(devKeyId, &d.key.PublicKey)}})
+}
+
+func (d *DevIssuer) authorizeForm(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("response_type") != "code" || query.Get("client_id") == "" || query.Get("redirect_uri") == "" {
+		http.Error(w, "response_type=code, client_id and redirect_uri are required", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "an S256 code_challenge is required", http.StatusBadRequest)
+		return
+	}
+	params := map[string]string{}
+	for _, name := range []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge"} {
+		params[name] = query.Get(name)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	authorizeForm.Execute(w, map[string]any{"Params": params})
+}
+
+func (d *DevIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.PostFormValue("email"))
+	redirectUri, err := url.Parse(r.PostFormValue("redirect_uri"))
+	if email == "" || err != nil || !redirectUri.IsAbs() {
+		http.Error(w, "email and an absolute redirect_uri are required", http.StatusBadRequest)
+		return
+	}
+	code, err := randomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	d.mu.Lock()
+	d.codes[code] = authorizationCode{
+		clientId:      r.PostFormValue("client_id"),
+		redirectUri:   redirectUri.String(),
+		codeChallenge: r.PostFormValue("code_challenge"),
+		nonce:         r.PostFormValue("nonce"),
+		email:         email,
+		name:          strings.TrimSpace(r.PostFormValue("name")),
+		expiresAt:     time.Now().Add(devCodeLifetime),
+	}
+	d.mu.Unlock()
+
+	query := redirectUri.Query()
+	query.Set("code", code)
+	if state := r.PostFormValue("state"); state != "" {
+		query.Set("state", state)
+	}
+	redirectUri.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectUri.String(), http.StatusFound)
+}
+
+func (d *DevIssuer) token(w http.ResponseWriter, r *http.Request) {
+	// The frontend redeems codes from the browser
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	d.mu.Lock()
+	code, ok := d.codes[r.PostFormValue("code")]
+	// Codes are single use
+	delete(d.codes, r.PostFormValue("code"))
+	d.mu.Unlock()
+
+	verifierDigest := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	switch {
+	case !ok || time.Now().After(code.expiresAt),
+		code.clientId != r.PostFormValue("client_id"),
+		code.redirectUri != r.PostFormValue("redirect_uri"),
+		code.codeChallenge != encodeSegment(verifierDigest[:]):
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken, err := Sign(d.key, devKeyId, Claims{
+		Issuer:    d.issuer,
+		Subject:   subjectFor(code.email),
+		Audience:  Audience{code.clientId},
+		ExpiresAt: now.Add(devTokenLifetime).Unix(),
+		IssuedAt:  now.Unix(),
+		Email:     code.email,
+		Name:      code.name,
+		Nonce:     code.nonce,
+	})
+	if err != nil {
+		slog.Error("[oidc.token] Failed to sign token", "error", err)
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	slog.Info("[oidc.token] Issued dev token", "email", code.email)
+	writeJson(w, http.StatusOK, map[string]any{
+		"id_token":     idToken,
+		"access_token": idToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(devTokenLifetime.Seconds()),
+	})
+}
+
+// subjectFor derives a stable subject from an email, so signing in again maps to the same user
+func subjectFor(email string) string {
+	digest := sha256.Sum256([]byte(strings.ToLower(email)))
+	return hex.EncodeToString(digest[:16])
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encodeSegment(b), nil
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,147 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateClaims(t *testing.T) {
+	now := time.Date(2026, 1, 22, 13, 0, 0, 0, time.UTC)
+	valid := Claims{
+		Issuer:    "https://issuer.test",
+		Subject:   "user-1",
+		Audience:  Audience{"other", "eatsavvy"},
+		ExpiresAt: now.Add(time.Hour).Unix(),
+	}
+	tests := []struct {
+		name   string
+		modify func(c *Claims)
+		valid  bool
+	}{
+		{"valid", func(c *Claims) {}, true},
+		{"wrong issuer", func(c *Claims) { c.Issuer = "https://evil.test" }, false},
+		{"wrong audience", func(c *Claims) { c.Audience = Audience{"other"} }, false},
+		{"missing subject", func(c *Claims) { c.Subject = "" }, false},
+		{"missing expiry", func(c *Claims) { c.ExpiresAt = 0 }, false},
+		{"expired", func(c *Claims) { c.ExpiresAt = now.Add(-2 * time.Minute).Unix() }, false},
+		{"expired within leeway", func(c *Claims) { c.ExpiresAt = now.Add(-30 * time.Second).Unix() }, true},
+		{"not valid yet", func(c *Claims) { c.NotBefore = now.Add(2 * time.Minute).Unix() }, false},
+	}
+	for _, test := range tests {
+		claims := valid
+		test.modify(&claims)
+		err := validateClaims(claims, "https://issuer.test", "eatsavvy", now, time.Minute)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %v, but got %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestAudience(t *testing.T) {
+	for _, payload := range []string{`{"aud":"eatsavvy"}`, `{"aud":["eatsavvy"]}`} {
+		_, claims, _, _, err := parseToken(encodeSegment([]byte(`{"alg":"RS256"}`)) + "." + encodeSegment([]byte(payload)) + ".c2ln")
+		if err != nil || len(claims.Audience) != 1 || claims.Audience[0] != "eatsavvy" {
+			t.Errorf("Expected audience [eatsavvy] from %s, but got %v (%v)", payload, claims.Audience, err)
+		}
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	server := httptest.NewUnstartedServer(nil)
+	issuer, err := NewDevIssuer("http://" + server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to create issuer: %v", err)
+	}
+	server.Config.Handler = issuer.Handler()
+	server.Start()
+	defer server.Close()
+
+	verifier := newVerifier(server.URL, "eatsavvy")
+	token, _ := issuer.IssueToken("eatsavvy", "ada@example.com", "Ada")
+	claims, err := verifier.Verify(token)
+	if err != nil || claims.Email != "ada@example.com" || claims.Subject != subjectFor("ADA@example.com") {
+		t.Errorf("Expected a valid token for ada@example.com, but got %+v (%v)", claims, err)
+	}
+
+	otherAudience, _ := issuer.IssueToken("someone-else", "ada@example.com", "Ada")
+	if _, err = verifier.Verify(otherAudience); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected a token for another audience to be rejected, but got %v", err)
+	}
+
+	forgingKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	forged, _ := Sign(forgingKey, devKeyId, claims)
+	if _, err = verifier.Verify(forged); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected a token signed by another key to be rejected, but got %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	unsigned := encodeSegment([]byte(`{"alg":"none","kid":"dev-1"}`)) + "." + parts[1] + "."
+	if _, err = verifier.Verify(unsigned); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected an unsigned token to be rejected, but got %v", err)
+	}
+}
+
+func TestDevIssuerLogin(t *testing.T) {
+	server := httptest.NewUnstartedServer(nil)
+	issuer, _ := NewDevIssuer("http://" + server.Listener.Addr().String())
+	server.Config.Handler = issuer.Handler()
+	server.Start()
+	defer server.Close()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	codeVerifier := "a-long-random-code-verifier-for-the-test"
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	resp, err := client.PostForm(server.URL+"/authorize", url.Values{
+		"client_id":      {"eatsavvy"},
+		"redirect_uri":   {"http://localhost:5173/"},
+		"state":          {"xyz"},
+		"nonce":          {"n-0S6_WzA2Mj"},
+		"code_challenge": {encodeSegment(challenge[:])},
+		"email":          {"ada@example.com"},
+	})
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected a redirect from /authorize, but got %v (%v)", resp, err)
+	}
+	redirect, _ := url.Parse(resp.Header.Get("Location"))
+	if redirect.Query().Get("state") != "xyz" || redirect.Query().Get("code") == "" {
+		t.Fatalf("Expected the redirect to carry a code and the state, but got %s", redirect)
+	}
+
+	redeem := func(verifier string) *http.Response {
+		resp, err := client.PostForm(server.URL+"/token", url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {redirect.Query().Get("code")},
+			"client_id":     {"eatsavvy"},
+			"redirect_uri":  {"http://localhost:5173/"},
+			"code_verifier": {verifier},
+		})
+		if err != nil {
+			t.Fatalf("Failed to redeem code: %v", err)
+		}
+		return resp
+	}
+	if resp = redeem(codeVerifier); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the code to be redeemed, but got %d", resp.StatusCode)
+	} else {
+		var body struct {
+			IdToken string `json:"id_token"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		// The frontend refuses tokens that don't carry the nonce it sent to /authorize
+		if _, claims, _, _, err := parseToken(body.IdToken); err != nil || claims.Nonce != "n-0S6_WzA2Mj" {
+			t.Errorf("Expected the token to carry the nonce, but got %+v (%v)", claims, err)
+		}
+	}
+	if resp = redeem(codeVerifier); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a redeemed code to be rejected, but got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,150 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"eatsavvy/internal/apperrors"
+	"eatsavvy/pkg/http"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksRefreshInterval is the least time between JWKS fetches, so tokens with unknown key ids can't
+	// make us hammer the issuer
+	jwksRefreshInterval = time.Minute
+	// clockLeeway allows for clock skew between us and the issuer
+	clockLeeway = time.Minute
+)
+
+// Verifier validates RS256 JWTs issued by an OIDC provider, using the signing keys from its discovery document
+type Verifier struct {
+	issuer     string
+	audience   string
+	httpClient *http.Http
+
+	mu        sync.Mutex
+	jwksUri   string
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewVerifier reads OIDC_ISSUER_URL and OIDC_AUDIENCE (the client id tokens are issued for).
+// Without an issuer, token authentication is disabled.
+func NewVerifier() *Verifier {
+	return newVerifier(os.Getenv("OIDC_ISSUER_URL"), os.Getenv("OIDC_AUDIENCE"))
+}
+
+func newVerifier(issuer string, audience string) *Verifier {
+	return &Verifier{
+		issuer:     strings.TrimSuffix(issuer, "/"),
+		audience:   audience,
+		httpClient: http.NewClientWithTimeout(10 * time.Second),
+		keys:       map[string]*rsa.PublicKey{},
+	}
+}
+
+func (v *Verifier) Enabled() bool {
+	return v.issuer != ""
+}
+
+// Verify checks the token's signature and claims and returns the claims
+func (v *Verifier) Verify(token string) (Claims, error) {
+	if !v.Enabled() {
+		return Claims{}, fmt.Errorf("%w: token authentication is not configured", ErrInvalidToken)
+	}
+	h, claims, signingInput, signature, err := parseToken(token)
+	if err != nil {
+		return Claims{}, err
+	}
+	if h.Algorithm != "RS256" {
+		return Claims{}, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, h.Algorithm)
+	}
+	key, err := v.key(h.KeyId)
+	if err != nil {
+		return Claims{}, err
+	}
+	if err = verifySignature(key, signingInput, signature); err != nil {
+		return Claims{}, err
+	}
+	if err = validateClaims(claims, v.issuer, v.audience, time.Now(), clockLeeway); err != nil {
+		return Claims{}, err
+	}
+	return claims, nil
+}
+
+// key returns the issuer's signing key with keyId, refetching the JWKS when the key is unknown (e.g. after rotation)
+func (v *Verifier) key(keyId string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.keys[keyId]; ok {
+		return key, nil
+	}
+	if time.Since(v.fetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("%w: unknown key id %q", ErrInvalidToken, keyId)
+	}
+	keys, err := v.fetchKeys()
+	if err != nil {
+		return nil, apperrors.Newf(apperrors.KindUpstream, "failed to fetch signing keys: %w", err)
+	}
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	if key, ok := v.keys[keyId]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key id %q", ErrInvalidToken, keyId)
+}
+
+func (v *Verifier) fetchKeys() (map[string]*rsa.PublicKey, error) {
+	if v.jwksUri == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JwksUri string `json:"jwks_uri"`
+		}
+		err := v.getJson(v.issuer+"/.well-known/openid-configuration", &discovery)
+		if err != nil {
+			slog.Error("[oidc.fetchKeys] Failed to fetch discovery document", "error", err, "issuer", v.issuer)
+			return nil, err
+		}
+		if strings.TrimSuffix(discovery.Issuer, "/") != v.issuer || discovery.JwksUri == "" {
+			return nil, fmt.Errorf("discovery document for %s has issuer %q and jwks_uri %q", v.issuer, discovery.Issuer, discovery.JwksUri)
+		}
+		v.jwksUri = discovery.JwksUri
+	}
+
+	var set jwks
+	err := v.getJson(v.jwksUri, &set)
+	if err != nil {
+		slog.Error("[oidc.fetchKeys] Failed to fetch JWKS", "error", err, "jwksUri", v.jwksUri)
+		return nil, err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := publicKeyFromJwk(k)
+		if err != nil {
+			slog.Info("[oidc.fetchKeys] Skipping unusable key", "kid", k.KeyId, "error", err)
+			continue
+		}
+		keys[k.KeyId] = key
+	}
+	slog.Info("[oidc.fetchKeys] Fetched signing keys", "issuer", v.issuer, "count", len(keys))
+	return keys, nil
+}
+
+func (v *Verifier) getJson(url string, target any) error {
+	body, statusCode, err := v.httpClient.Get(url, map[string]string{"Accept": "application/json"})
+	if err != nil {
+		return err
+	}
+	if statusCode != 200 {
+		return fmt.Errorf("GET %s returned %d", url, statusCode)
+	}
+	return json.Unmarshal(body, target)
+}
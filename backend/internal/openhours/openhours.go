@@ -0,0 +1,183 @@
+package openhours
+
+import (
+	"eatsavvy/internal/places"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// timeToMinutes converts a weekday/hour/minute to total minutes since start of week (Sunday 00:00)
+func timeToMinutes(weekday, hour, minute int) int {
+	return weekday*24*60 + hour*60 + minute
+}
+
+// IsOpen reports whether the weekly openHours include the given weekday and time of day
+func IsOpen(openHours []places.TimeRange, currentDay int, currentHour int, currentMinute int) bool {
+	currentMins := timeToMinutes(currentDay, currentHour, currentMinute)
+
+	for _, openHour := range openHours {
+		openMins := timeToMinutes(openHour.Open.Weekday, openHour.Open.Hour, openHour.Open.Minute)
+		closeMins := timeToMinutes(openHour.Close.Weekday, openHour.Close.Hour, openHour.Close.Minute)
+
+		if closeMins > openMins {
+			// Normal case: opens and closes within the same week span
+			if currentMins >= openMins && currentMins < closeMins {
+				return true
+			}
+		} else {
+			// Week wrap: close time is "earlier" in the week than open time
+			// e.g., Sat 20:00 -> Sun 02:00 means open from Sat 20:00 to end of week OR start of week to Sun 02:00
+			if currentMins >= openMins || currentMins < closeMins {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// specialDayAt returns the special day covering t, if any
+func specialDayAt(specialDays []places.SpecialDay, t time.Time) (places.SpecialDay, bool) {
+	for _, specialDay := range specialDays {
+		if !t.Before(specialDay.Start) && t.Before(specialDay.End) {
+			return specialDay, true
+		}
+	}
+	return places.SpecialDay{}, false
+}
+
+// IsOpenAt is IsOpen for an absolute time, with the weekly openHours read as local times in location so DST
+// changes are handled when evaluated. Special days are honoured: on a special day only its own hours count,
+// so a holiday closure keeps the restaurant closed even if the weekly schedule says open.
+func IsOpenAt(openHours []places.TimeRange, specialDays []places.SpecialDay, location *time.Location, t time.Time) bool {
+	if specialDay, ok := specialDayAt(specialDays, t); ok {
+		for _, period := range specialDay.Hours {
+			if !t.Before(period.Open) && t.Before(period.Close) {
+				return true
+			}
+		}
+		return false
+	}
+	local := t.In(location)
+	return IsOpen(openHours, int(local.Weekday()), local.Hour(), local.Minute())
+}
+
+// weeklyOccurrences returns the next two times after the minute containing t that the weekly time point
+// occurs in location. They are built with time.Date in local time, so a DST change in between is accounted for.
+func weeklyOccurrences(point places.TimePoint, location *time.Location, t time.Time) []time.Time {
+	local := t.In(location)
+	startOfMinute := t.Truncate(time.Minute)
+	days := (point.Weekday - int(local.Weekday()) + 7) % 7
+	next := time.Date(local.Year(), local.Month(), local.Day()+days, point.Hour, point.Minute, 0, 0, location)
+	if !next.After(startOfMinute) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+days+7, point.Hour, point.Minute, 0, 0, location)
+	}
+	following := time.Date(next.Year(), next.Month(), next.Day()+7, point.Hour, point.Minute, 0, 0, location)
+	return []time.Time{next, following}
+}
+
+func sortTimes(times []time.Time) {
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+}
+
+// NextOpenAt returns the next time after t that the restaurant opens, skipping weekly openings that fall on
+// special-day closures and including openings that only exist on special days. It returns false if there is
+// no opening in the next two weeks.
+func NextOpenAt(openHours []places.TimeRange, specialDays []places.SpecialDay, location *time.Location, t time.Time) (time.Time, bool) {
+	// Candidate opening times: the next two occurrences of every weekly opening (the first may be cancelled
+	// by a special day) plus every special-day opening still ahead
+	candidates := []time.Time{}
+	for _, openHour := range openHours {
+		candidates = append(candidates, weeklyOccurrences(openHour.Open, location, t)...)
+	}
+	for _, specialDay := range specialDays {
+		for _, period := range specialDay.Hours {
+			if period.Open.After(t) {
+				candidates = append(candidates, period.Open)
+			}
+		}
+	}
+	sortTimes(candidates)
+
+	for _, candidate := range candidates {
+		if IsOpenAt(openHours, specialDays, location, candidate) {
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// NextCloseAt returns when the restaurant, open at t, next closes. Adjoining periods count as one, and a
+// special-day closure ends the period early. It returns false if the restaurant is closed at t or doesn't
+// close in the next two weeks (e.g. it is open 24/7).
+func NextCloseAt(openHours []places.TimeRange, specialDays []places.SpecialDay, location *time.Location, t time.Time) (time.Time, bool) {
+	if !IsOpenAt(openHours, specialDays, location, t) {
+		return time.Time{}, false
+	}
+	candidates := []time.Time{}
+	for _, openHour := range openHours {
+		candidates = append(candidates, weeklyOccurrences(openHour.Close, location, t)...)
+	}
+	for _, specialDay := range specialDays {
+		if specialDay.Start.After(t) {
+			candidates = append(candidates, specialDay.Start)
+		}
+		if specialDay.End.After(t) {
+			candidates = append(candidates, specialDay.End)
+		}
+		for _, period := range specialDay.Hours {
+			if period.Close.After(t) {
+				candidates = append(candidates, period.Close)
+			}
+		}
+	}
+	sortTimes(candidates)
+
+	for _, candidate := range candidates {
+		if !IsOpenAt(openHours, specialDays, location, candidate) {
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Describe formats weekly openHours as one line per day, Monday first, e.g. "Monday: 9:00 AM – 10:00 PM".
+// A range is listed under the day it opens, so overnight hours are not split across days.
+func Describe(openHours []places.TimeRange) []string {
+	alwaysOpen := false
+	byDay := make([][]places.TimeRange, 7)
+	for _, openHour := range openHours {
+		// Places reports 24/7 places as a single period that closes when it opens
+		if openHour.Open == openHour.Close {
+			alwaysOpen = true
+		}
+		byDay[openHour.Open.Weekday] = append(byDay[openHour.Open.Weekday], openHour)
+	}
+
+	descriptions := make([]string, 0, 7)
+	for i := 1; i <= 7; i++ {
+		weekday := i % 7
+		ranges := byDay[weekday]
+		sort.Slice(ranges, func(a, b int) bool {
+			return timeToMinutes(0, ranges[a].Open.Hour, ranges[a].Open.Minute) < timeToMinutes(0, ranges[b].Open.Hour, ranges[b].Open.Minute)
+		})
+		parts := []string{}
+		for _, r := range ranges {
+			parts = append(parts, formatTimePoint(r.Open)+" – "+formatTimePoint(r.Close))
+		}
+		if alwaysOpen {
+			parts = []string{"Open 24 hours"}
+		} else if len(parts) == 0 {
+			parts = append(parts, "Closed")
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s: %s", time.Weekday(weekday), strings.Join(parts, ", ")))
+	}
+	return descriptions
+}
+
+func formatTimePoint(point places.TimePoint) string {
+	return time.Date(2000, 1, 1, point.Hour, point.Minute, 0, 0, time.UTC).Format("3:04 PM")
+}
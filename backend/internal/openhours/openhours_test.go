@@ -0,0 +1,451 @@
+package openhours
+
+import (
+	"eatsavvy/internal/places"
+	"testing"
+	"time"
+)
+
+func TestIsOpen(t *testing.T) {
+	tests := []struct {
+		name          string
+		openHours     []places.TimeRange
+		currentDay    int
+		currentHour   int
+		currentMinute int
+		want          bool
+	}{
+		{
+			name: "[open] during regular hours same day",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
+				},
+			},
+			currentDay:  1,
+			currentHour: 14,
+			want:        true,
+		},
+		{
+			name: "[closed] before opening time",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
+				},
+			},
+			currentDay:  1,
+			currentHour: 8,
+			want:        false,
+		},
+		{
+			name: "[closed] after closing time",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
+				},
+			},
+			currentDay:  1,
+			currentHour: 23,
+			want:        false,
+		},
+		{
+			name: "[open] spans to next day - checking during late night",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 5, Hour: 18, Minute: 0},
+					Close: places.TimePoint{Weekday: 6, Hour: 2, Minute: 0},
+				},
+			},
+			currentDay:  5,
+			currentHour: 23,
+			want:        true,
+		},
+		{
+			name: "[open] from previous day - still open next day",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 5, Hour: 18, Minute: 0},
+					Close: places.TimePoint{Weekday: 6, Hour: 2, Minute: 0},
+				},
+			},
+			currentDay:  6,
+			currentHour: 1,
+			want:        true,
+		},
+		{
+			name: "[closed] on a different day",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
+				},
+			},
+			currentDay:  2,
+			currentHour: 14,
+			want:        false,
+		},
+		{
+			name:        "empty open hours",
+			openHours:   []places.TimeRange{},
+			currentDay:  1,
+			currentHour: 14,
+			want:        false,
+		},
+		{
+			name: "[open] multiple time ranges - open in second range",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 14, Minute: 0},
+				},
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 17, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
+				},
+			},
+			currentDay:  1,
+			currentHour: 19,
+			want:        true,
+		},
+		{
+			name: "[closed] multiple time ranges - closed between ranges",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 14, Minute: 0},
+				},
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 17, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
+				},
+			},
+			currentDay:  1,
+			currentHour: 15,
+			want:        false,
+		},
+		{
+			name: "[open] exactly at opening time - restaurant just opened",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
+				},
+			},
+			currentDay:    1,
+			currentHour:   9,
+			currentMinute: 0,
+			want:          true,
+		},
+		{
+			name: "[closed] exactly at closing hour - should be closed",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
+				},
+			},
+			currentDay:  1,
+			currentHour: 22,
+			want:        false,
+		},
+		{
+			name: "[open] opens at midnight (hour 0)",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 0, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 6, Minute: 0},
+				},
+			},
+			currentDay:  1,
+			currentHour: 3,
+			want:        true,
+		},
+		{
+			name: "[open] late night bar - checking at 1 AM same day it opened",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 5, Hour: 18, Minute: 0},
+					Close: places.TimePoint{Weekday: 6, Hour: 2, Minute: 0},
+				},
+			},
+			currentDay:  5,
+			currentHour: 23,
+			want:        true,
+		},
+		{
+			name: "[open] late night bar - checking next day before close",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 5, Hour: 18, Minute: 0},
+					Close: places.TimePoint{Weekday: 6, Hour: 2, Minute: 0},
+				},
+			},
+			currentDay:  6,
+			currentHour: 1,
+			want:        true,
+		},
+		{
+			name: "[closed] late night bar - checking next day after close",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 5, Hour: 18, Minute: 0},
+					Close: places.TimePoint{Weekday: 6, Hour: 2, Minute: 0},
+				},
+			},
+			currentDay:  6,
+			currentHour: 3,
+			want:        false,
+		},
+		{
+			name: "[closed] week wrap - Saturday night bar, checking Sunday after close",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 6, Hour: 20, Minute: 0},
+					Close: places.TimePoint{Weekday: 0, Hour: 2, Minute: 0},
+				},
+			},
+			currentDay:  0,
+			currentHour: 3,
+			want:        false,
+		},
+		{
+			name: "[open] week wrap - Saturday night bar, checking Sunday before close",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 6, Hour: 20, Minute: 0},
+					Close: places.TimePoint{Weekday: 0, Hour: 2, Minute: 0},
+				},
+			},
+			currentDay:  0,
+			currentHour: 1,
+			want:        true,
+		},
+		// BUG TEST: Week wrap on the opening day itself
+		{
+			name: "[open] week wrap - Saturday night bar, checking SATURDAY before midnight",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 6, Hour: 20, Minute: 0},
+					Close: places.TimePoint{Weekday: 0, Hour: 2, Minute: 0},
+				},
+			},
+			currentDay:  6,
+			currentHour: 23,
+			want:        true,
+		},
+		// BUG TEST: Minute precision - opened 30 min ago
+		{
+			name: "[open] minute precision - opened 30 min ago same hour",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
+				},
+			},
+			currentDay:    1,
+			currentHour:   9,
+			currentMinute: 30,
+			want:          true,
+		},
+		// BUG TEST: Minute precision - closes in 30 min
+		{
+			name: "[open] minute precision - closes in 30 min same hour",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 30},
+				},
+			},
+			currentDay:    1,
+			currentHour:   22,
+			currentMinute: 15,
+			want:          true,
+		},
+		// BUG TEST: Minute precision - closed 15 min ago
+		{
+			name: "[closed] minute precision - closed 15 min ago same hour",
+			openHours: []places.TimeRange{
+				{
+					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
+					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
+				},
+			},
+			currentDay:    1,
+			currentHour:   22,
+			currentMinute: 15,
+			want:          false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IsOpen(tt.openHours, tt.currentDay, tt.currentHour, tt.currentMinute)
+			if got != tt.want {
+				t.Errorf("IsOpen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsOpenAtWithSpecialDays(t *testing.T) {
+	// Open every Monday 09:00-22:00 UTC
+	openHours := []places.TimeRange{
+		{
+			Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
+			Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
+		},
+	}
+	// Monday 2026-12-28
+	closedDay := places.SpecialDay{
+		Date:  "2026-12-28",
+		Start: time.Date(2026, 12, 28, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2026, 12, 29, 0, 0, 0, 0, time.UTC),
+		Hours: []places.DatedPeriod{},
+	}
+	shortDay := places.SpecialDay{
+		Date:  "2026-12-28",
+		Start: time.Date(2026, 12, 28, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2026, 12, 29, 0, 0, 0, 0, time.UTC),
+		Hours: []places.DatedPeriod{
+			{Open: time.Date(2026, 12, 28, 12, 0, 0, 0, time.UTC), Close: time.Date(2026, 12, 28, 15, 0, 0, 0, time.UTC)},
+		},
+	}
+	tests := []struct {
+		name        string
+		specialDays []places.SpecialDay
+		at          time.Time
+		want        bool
+	}{
+		{name: "[open] regular hours without special days", at: time.Date(2026, 12, 28, 10, 0, 0, 0, time.UTC), want: true},
+		{name: "[closed] holiday closure", specialDays: []places.SpecialDay{closedDay}, at: time.Date(2026, 12, 28, 10, 0, 0, 0, time.UTC), want: false},
+		{name: "[closed] special hours not open yet", specialDays: []places.SpecialDay{shortDay}, at: time.Date(2026, 12, 28, 10, 0, 0, 0, time.UTC), want: false},
+		{name: "[open] during special hours", specialDays: []places.SpecialDay{shortDay}, at: time.Date(2026, 12, 28, 13, 0, 0, 0, time.UTC), want: true},
+		{name: "[open] following week is unaffected", specialDays: []places.SpecialDay{closedDay}, at: time.Date(2027, 1, 4, 10, 0, 0, 0, time.UTC), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IsOpenAt(openHours, tt.specialDays, time.UTC, tt.at)
+			if got != tt.want {
+				t.Errorf("IsOpenAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenHoursAcrossDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+	// Open every day 09:00-17:00 New York time; DST starts 2026-03-08
+	openHours := []places.TimeRange{}
+	for day := 0; day < 7; day++ {
+		openHours = append(openHours, places.TimeRange{
+			Open:  places.TimePoint{Weekday: day, Hour: 9, Minute: 0},
+			Close: places.TimePoint{Weekday: day, Hour: 17, Minute: 0},
+		})
+	}
+
+	openTests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "[closed] 13:30 UTC is 08:30 EST", at: time.Date(2026, 3, 6, 13, 30, 0, 0, time.UTC), want: false},
+		{name: "[open] 13:30 UTC is 09:30 EDT", at: time.Date(2026, 3, 9, 13, 30, 0, 0, time.UTC), want: true},
+		{name: "[open] 21:30 UTC is 16:30 EST", at: time.Date(2026, 3, 6, 21, 30, 0, 0, time.UTC), want: true},
+		{name: "[closed] 21:30 UTC is 17:30 EDT", at: time.Date(2026, 3, 9, 21, 30, 0, 0, time.UTC), want: false},
+	}
+	for _, tt := range openTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IsOpenAt(openHours, nil, newYork, tt.at)
+			if got != tt.want {
+				t.Errorf("IsOpenAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Saturday 22:00 EST; Sunday opens at 09:00 EDT after the clocks go forward, which is only 10 hours later
+	now := time.Date(2026, 3, 8, 3, 0, 0, 0, time.UTC)
+	got, ok := NextOpenAt(openHours, nil, newYork, now)
+	if want := time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC); !ok || !got.Equal(want) {
+		t.Errorf("NextOpenAt() = %v, want %v", got, want)
+	}
+}
+
+func TestNextCloseAt(t *testing.T) {
+	// Open every day 09:00-14:00 and 17:00-22:00 UTC, and Friday night through to Saturday 02:00
+	openHours := []places.TimeRange{}
+	for day := 0; day < 7; day++ {
+		openHours = append(openHours,
+			places.TimeRange{Open: places.TimePoint{Weekday: day, Hour: 9}, Close: places.TimePoint{Weekday: day, Hour: 14}},
+			places.TimeRange{Open: places.TimePoint{Weekday: day, Hour: 17}, Close: places.TimePoint{Weekday: day, Hour: 22}},
+		)
+	}
+	openHours = append(openHours, places.TimeRange{Open: places.TimePoint{Weekday: 5, Hour: 22}, Close: places.TimePoint{Weekday: 6, Hour: 2}})
+	// Monday 2026-03-02 closes early at 12:00
+	earlyClose := places.SpecialDay{
+		Date:  "2026-03-02",
+		Start: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+		Hours: []places.DatedPeriod{
+			{Open: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), Close: time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)},
+		},
+	}
+	tests := []struct {
+		name        string
+		specialDays []places.SpecialDay
+		at          time.Time
+		want        time.Time
+		wantOk      bool
+	}{
+		{name: "closes at end of lunch", at: time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC), want: time.Date(2026, 3, 3, 14, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "closed has no close time", at: time.Date(2026, 3, 3, 15, 0, 0, 0, time.UTC), wantOk: false},
+		{name: "adjoining periods close at the end of the last", at: time.Date(2026, 3, 6, 20, 0, 0, 0, time.UTC), want: time.Date(2026, 3, 7, 2, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "special hours close early", specialDays: []places.SpecialDay{earlyClose}, at: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC), want: time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC), wantOk: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NextCloseAt(openHours, tt.specialDays, time.UTC, tt.at)
+			if ok != tt.wantOk || !got.Equal(tt.want) {
+				t.Errorf("NextCloseAt() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	openHours := []places.TimeRange{
+		{Open: places.TimePoint{Weekday: 1, Hour: 17, Minute: 0}, Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0}},
+		{Open: places.TimePoint{Weekday: 1, Hour: 11, Minute: 30}, Close: places.TimePoint{Weekday: 1, Hour: 14, Minute: 0}},
+		{Open: places.TimePoint{Weekday: 6, Hour: 20, Minute: 0}, Close: places.TimePoint{Weekday: 0, Hour: 2, Minute: 0}},
+	}
+	got := Describe(openHours)
+	want := []string{
+		"Monday: 11:30 AM – 2:00 PM, 5:00 PM – 10:00 PM",
+		"Tuesday: Closed",
+		"Wednesday: Closed",
+		"Thursday: Closed",
+		"Friday: Closed",
+		"Saturday: 8:00 PM – 2:00 AM",
+		"Sunday: Closed",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Describe() returned %d lines, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Describe()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	alwaysOpen := Describe([]places.TimeRange{{}})
+	if alwaysOpen[0] != "Monday: Open 24 hours" || alwaysOpen[6] != "Sunday: Open 24 hours" {
+		t.Errorf("Describe() for 24/7 = %v, want every day open 24 hours", alwaysOpen)
+	}
+}
@@ -0,0 +1,36 @@
+package outbox
+
+import (
+	"context"
+	"eatsavvy/internal/jobs"
+	"eatsavvy/pkg/encoder"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Enqueue writes a job to the outbox as part of tx. The job is only published once tx commits and the
+// relay picks it up, so a crash can never leave a restaurant marked queued without a job.
+func Enqueue(ctx context.Context, tx pgx.Tx, queueName string, job jobs.Job) error {
+	return EnqueueAt(ctx, tx, queueName, job, nil)
+}
+
+// EnqueueAt is Enqueue for a job that the relay holds back until availableAt. Until then the job can
+// still be withdrawn by deleting its outbox row. A nil availableAt publishes it right away.
+func EnqueueAt(ctx context.Context, tx pgx.Tx, queueName string, job jobs.Job, availableAt *time.Time) error {
+	payload, err := encoder.ToJSON(job)
+	if err != nil {
+		slog.Error("[outbox.Enqueue] Failed to encode job", "error", err)
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO public.outbox (queue_name, message_id, payload, available_at) VALUES ($1, $2, $3, COALESCE($4, NOW()))`,
+		queueName, job.Id, payload, availableAt,
+	)
+	if err != nil {
+		slog.Error("[outbox.Enqueue] Failed to insert outbox row", "error", err)
+		return err
+	}
+	return nil
+}
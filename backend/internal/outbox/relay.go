@@ -0,0 +1,187 @@
+package outbox
+
+import (
+	"cmp"
+	"context"
+	"eatsavvy/internal/config"
+	"eatsavvy/internal/jobs"
+	"eatsavvy/pkg/backoff"
+	"eatsavvy/pkg/db"
+	"eatsavvy/pkg/encoder"
+	"eatsavvy/pkg/queue"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+const (
+	batchSize = 50
+	// publishLease is how long claimed rows are hidden from other relays. It outlasts a whole batch of publishes
+	// timing out one after another (the publisher waits up to 5s for each confirm) plus the writes in between; if a
+	// relay dies mid-batch, its rows are published again once the lease runs out.
+	publishLease = batchSize*5*time.Second + time.Minute
+	// cleanupInterval is how often published rows older than the retention are deleted
+	cleanupInterval = time.Hour
+)
+
+// retryBackoff is how long a row waits after failed publishes
+var retryBackoff = backoff.Exponential{Initial: time.Second, Max: 5 * time.Minute}
+
+// Relay publishes pending outbox rows to the queue. Rows are marked published only after the broker
+// accepts them, so delivery is at-least-once: a crash between publishing and marking republishes the row.
+// Published rows are kept for OUTBOX_RETENTION (default 168h) for debugging and then deleted.
+type Relay struct {
+	dbClient     *db.DatabaseClient
+	publishers   map[string]queue.Queue
+	pollInterval time.Duration
+	retention    time.Duration
+	lastCleanup  time.Time
+}
+
+func NewRelay() *Relay {
+	return &Relay{
+		dbClient:     db.NewDatabaseClient(),
+		publishers:   map[string]queue.Queue{},
+		pollInterval: config.GetEnvDuration("OUTBOX_POLL_INTERVAL", 2*time.Second),
+		retention:    config.GetEnvDuration("OUTBOX_RETENTION", 7*24*time.Hour),
+	}
+}
+
+func (r *Relay) Close() {
+	for _, publisher := range r.publishers {
+		publisher.Close()
+	}
+	r.dbClient.Close()
+}
+
+func (r *Relay) Start(ctx context.Context) {
+	slog.Info("[outbox.Relay.Start] Starting outbox relay", "pollInterval", r.pollInterval)
+	defer r.Close()
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+	for {
+		published, err := r.relayBatch(ctx)
+		if err != nil {
+			slog.Error("[outbox.Relay.Start] Failed to relay outbox batch", "error", err)
+		}
+		if published == batchSize && ctx.Err() == nil {
+			// There may be more pending rows, don't wait for the next tick
+			continue
+		}
+		if time.Since(r.lastCleanup) >= cleanupInterval {
+			r.deletePublished(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			slog.Info("[outbox.Relay.Start] Stopping outbox relay")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type outboxRow struct {
+	id        int64
+	queueName string
+	messageId string
+	payload   []byte
+	attempts  int
+}
+
+// relayBatch claims due rows by pushing their availability out by the publish lease (SKIP LOCKED, so several
+// relays, one per worker replica, don't claim the same row), then publishes them outside of any transaction so
+// no row locks are held while waiting for broker confirms
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	rows, err := r.dbClient.Db.Query(ctx,
+		`UPDATE public.outbox SET available_at = NOW() + make_interval(secs => $1)
+			WHERE id IN (
+				SELECT id FROM public.outbox WHERE published_at IS NULL AND available_at <= NOW()
+				ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, queue_name, message_id, payload, attempts`,
+		publishLease.Seconds(), batchSize,
+	)
+	if err != nil {
+		slog.Error("[outbox.Relay.relayBatch] Failed to claim pending outbox rows", "error", err)
+		return 0, err
+	}
+	claimed := []outboxRow{}
+	for rows.Next() {
+		var row outboxRow
+		err = rows.Scan(&row.id, &row.queueName, &row.messageId, &row.payload, &row.attempts)
+		if err != nil {
+			rows.Close()
+			slog.Error("[outbox.Relay.relayBatch] Failed to scan outbox row", "error", err)
+			return 0, err
+		}
+		claimed = append(claimed, row)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		slog.Error("[outbox.Relay.relayBatch] Failed to claim pending outbox rows", "error", err)
+		return 0, err
+	}
+	// Publish in id order, as rows were enqueued
+	slices.SortFunc(claimed, func(a, b outboxRow) int { return cmp.Compare(a.id, b.id) })
+
+	published := 0
+	for _, row := range claimed {
+		err = r.publish(row)
+		if err != nil {
+			slog.Error("[outbox.Relay.relayBatch] Failed to publish outbox row", "error", err, "id", row.id, "messageId", row.messageId)
+			_, err = r.dbClient.Db.Exec(ctx,
+				`UPDATE public.outbox SET attempts = attempts + 1, last_error = $1, available_at = NOW() + make_interval(secs => $2) WHERE id = $3`,
+				err.Error(), retryBackoff.Delay(row.attempts+1).Seconds(), row.id,
+			)
+			if err != nil {
+				slog.Error("[outbox.Relay.relayBatch] Failed to record publish failure", "error", err, "id", row.id)
+				return len(claimed), err
+			}
+			continue
+		}
+		_, err = r.dbClient.Db.Exec(ctx,
+			`UPDATE public.outbox SET attempts = attempts + 1, published_at = NOW(), last_error = NULL WHERE id = $1`,
+			row.id,
+		)
+		if err != nil {
+			slog.Error("[outbox.Relay.relayBatch] Failed to mark outbox row published", "error", err, "id", row.id)
+			return len(claimed), err
+		}
+		published++
+	}
+
+	if published > 0 {
+		slog.Info("[outbox.Relay.relayBatch] Published outbox rows", "count", published)
+	}
+	return len(claimed), nil
+}
+
+// deletePublished deletes rows published longer ago than the retention
+func (r *Relay) deletePublished(ctx context.Context) {
+	r.lastCleanup = time.Now()
+	tag, err := r.dbClient.Db.Exec(ctx,
+		`DELETE FROM public.outbox WHERE published_at < NOW() - make_interval(secs => $1)`,
+		r.retention.Seconds(),
+	)
+	if err != nil {
+		slog.Error("[outbox.Relay.deletePublished] Failed to delete published outbox rows", "error", err)
+		return
+	}
+	if tag.RowsAffected() > 0 {
+		slog.Info("[outbox.Relay.deletePublished] Deleted published outbox rows", "count", tag.RowsAffected())
+	}
+}
+
+func (r *Relay) publish(row outboxRow) error {
+	job, err := jobs.Decode(row.payload, encoder.ContentTypeJSON)
+	if err != nil {
+		return err
+	}
+	publisher, ok := r.publishers[row.queueName]
+	if !ok {
+		publisher = queue.New(row.queueName)
+		r.publishers[row.queueName] = publisher
+	}
+	return publisher.PublishMessage(job)
+}
@@ -0,0 +1,115 @@
+package places
+
+import (
+	"eatsavvy/internal/dietary"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Score penalties for each kind of conflict. Allergies and diets are hard requirements, so a single miss
+// outweighs any number of missing vegetables.
+const (
+	allergyPenalty    = 40
+	avoidedOilPenalty = 30
+	dietPenalty       = 30
+	vegetablePenalty  = 10
+)
+
+// nutAllergies are the allergies answered by whether a restaurant is nut free. Allergies are matched whole, so
+// "coconut" and "nutmeg" aren't mistaken for nuts.
+var nutAllergies = []string{
+	"nut", "nuts", "tree nut", "tree nuts", "peanut", "peanuts", "almond", "almonds", "brazil nut", "brazil nuts",
+	"cashew", "cashews", "hazelnut", "hazelnuts", "macadamia", "pecan", "pecans", "pistachio", "pistachios",
+	"walnut", "walnuts",
+}
+
+// dietKeywords are what a restaurant's accommodations must mention to suit a diet
+var dietKeywords = map[dietary.Diet][]string{
+	dietary.DietVegetarian:  {"vegetarian", "vegan"},
+	dietary.DietVegan:       {"vegan"},
+	dietary.DietPescatarian: {"pescatarian", "vegetarian", "vegan"},
+	dietary.DietGlutenFree:  {"gluten free", "gluten-free", "celiac"},
+	dietary.DietDairyFree:   {"dairy free", "dairy-free", "lactose", "vegan"},
+	dietary.DietHalal:       {"halal"},
+	dietary.DietKosher:      {"kosher"},
+}
+
+// ScoreCompatibility rates how well a restaurant's nutrition info fits the profile, starting from 100 and
+// subtracting a penalty for each conflict. Nutrition info comes from free-form answers on the call, so matching
+// is by keyword.
+func ScoreCompatibility(profile dietary.Profile, info *NutritionInfo) dietary.Compatibility {
+	compatibility := dietary.Compatibility{ProfileId: profile.Id, Conflicts: []dietary.Conflict{}}
+	if info == nil {
+		return compatibility
+	}
+	oils := strings.ToLower(info.CookingOils)
+	accommodations := strings.ToLower(info.DietaryAccommodations)
+	vegetables := strings.ToLower(info.Vegetables)
+	score := 100
+	conflict := func(penalty int, field string, format string, args ...any) {
+		score -= penalty
+		compatibility.Conflicts = append(compatibility.Conflicts, dietary.Conflict{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, allergy := range profile.Allergies {
+		if slices.Contains(nutAllergies, allergy) {
+			if !info.NutFree {
+				conflict(allergyPenalty, "nutFree", "Not nut free (allergy: %s)", allergy)
+			}
+			continue
+		}
+		if !mentionsFree(accommodations, allergy) {
+			conflict(allergyPenalty, "accommodations", "No %s-free accommodation", allergy)
+		}
+	}
+	for _, oil := range profile.AvoidedOils {
+		if strings.Contains(oils, oil) {
+			conflict(avoidedOilPenalty, "oil", "Cooks with %s", oil)
+		}
+	}
+	if profile.Diet != nil {
+		keywords := dietKeywords[*profile.Diet]
+		if !slices.ContainsFunc(keywords, func(keyword string) bool { return strings.Contains(accommodations, keyword) }) {
+			conflict(dietPenalty, "accommodations", "No %s accommodation", strings.ReplaceAll(string(*profile.Diet), "_", " "))
+		}
+	}
+	for _, vegetable := range profile.MustHaveVegetables {
+		if !strings.Contains(vegetables, vegetable) {
+			conflict(vegetablePenalty, "vegetables", "Doesn't use %s", vegetable)
+		}
+	}
+
+	score = max(score, 0)
+	compatibility.Score = &score
+	return compatibility
+}
+
+// mentionsFree reports whether accommodations offer food free of allergen, e.g. "gluten free" or "dairy-free"
+func mentionsFree(accommodations string, allergen string) bool {
+	return strings.Contains(accommodations, allergen+" free") ||
+		strings.Contains(accommodations, allergen+"-free") ||
+		strings.Contains(accommodations, "no "+allergen)
+}
+
+// RankByCompatibility scores each restaurant against the profile and orders them best fit first. Restaurants
+// that haven't been enriched can't be scored and go last; ties keep their original order.
+func RankByCompatibility(restaurants []Restaurant, profile dietary.Profile) []Restaurant {
+	for i := range restaurants {
+		compatibility := ScoreCompatibility(profile, restaurants[i].NutritionInfo)
+		restaurants[i].Compatibility = &compatibility
+	}
+	slices.SortStableFunc(restaurants, func(a, b Restaurant) int {
+		aScore, bScore := a.Compatibility.Score, b.Compatibility.Score
+		switch {
+		case aScore == nil && bScore == nil:
+			return 0
+		case aScore == nil:
+			return 1
+		case bScore == nil:
+			return -1
+		}
+		return *bScore - *aScore
+	})
+	return restaurants
+}
@@ -0,0 +1,95 @@
+package places
+
+import (
+	"eatsavvy/internal/dietary"
+	"fmt"
+	"testing"
+)
+
+func TestScoreCompatibility(t *testing.T) {
+	vegan := dietary.DietVegan
+	profile := dietary.Profile{
+		Id:                 "profile-1",
+		Allergies:          []string{"peanuts", "gluten"},
+		AvoidedOils:        []string{"seed oil", "palm"},
+		Diet:               &vegan,
+		MustHaveVegetables: []string{"kale"},
+	}
+	tests := []struct {
+		name      string
+		info      *NutritionInfo
+		score     *int
+		conflicts []string
+	}{
+		{"not enriched", nil, nil, []string{}},
+		{"perfect fit", &NutritionInfo{CookingOils: "Olive oil", NutFree: true, DietaryAccommodations: "Vegan menu, gluten-free bread", Vegetables: "Kale, spinach"}, intPtr(100), []string{}},
+		{"everything wrong", &NutritionInfo{CookingOils: "Palm and seed oil", NutFree: false, DietaryAccommodations: "None", Vegetables: "Carrots"}, intPtr(0),
+			[]string{"nutFree", "accommodations", "oil", "oil", "accommodations", "vegetables"}},
+		{"missing vegetable", &NutritionInfo{CookingOils: "olive oil", NutFree: true, DietaryAccommodations: "vegan, gluten free", Vegetables: "spinach"}, intPtr(90), []string{"vegetables"}},
+	}
+	for _, test := range tests {
+		compatibility := ScoreCompatibility(profile, test.info)
+		if (compatibility.Score == nil) != (test.score == nil) || (test.score != nil && *compatibility.Score != *test.score) {
+			t.Errorf("%s: expected score %v, but got %v", test.name, formatScore(test.score), formatScore(compatibility.Score))
+		}
+		fields := []string{}
+		for _, conflict := range compatibility.Conflicts {
+			fields = append(fields, conflict.Field)
+		}
+		if fmt.Sprint(fields) != fmt.Sprint(test.conflicts) {
+			t.Errorf("%s: expected conflicts in %v, but got %v", test.name, test.conflicts, compatibility.Conflicts)
+		}
+	}
+}
+
+func TestRankByCompatibility(t *testing.T) {
+	profile := dietary.Profile{AvoidedOils: []string{"canola"}, MustHaveVegetables: []string{"kale"}}
+	restaurants := []Restaurant{
+		{Id: "unknown"},
+		{Id: "canola", NutritionInfo: &NutritionInfo{CookingOils: "canola", Vegetables: "kale"}},
+		{Id: "best", NutritionInfo: &NutritionInfo{CookingOils: "olive", Vegetables: "kale"}},
+		{Id: "no-kale", NutritionInfo: &NutritionInfo{CookingOils: "olive"}},
+	}
+	ranked := RankByCompatibility(restaurants, profile)
+	ids := []string{}
+	for _, restaurant := range ranked {
+		ids = append(ids, restaurant.Id)
+	}
+	if fmt.Sprint(ids) != "[best no-kale canola unknown]" {
+		t.Errorf("Expected [best no-kale canola unknown], but got %v", ids)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func formatScore(score *int) string {
+	if score == nil {
+		return "nil"
+	}
+	return fmt.Sprint(*score)
+}
+
+func TestScoreCompatibilityNutAllergies(t *testing.T) {
+	info := &NutritionInfo{NutFree: false, DietaryAccommodations: "coconut-free and nutmeg-free desserts"}
+	tests := []struct {
+		allergy   string
+		conflicts []string
+	}{
+		{"tree nuts", []string{"nutFree"}},
+		{"cashew", []string{"nutFree"}},
+		{"coconut", []string{}},
+		{"nutmeg", []string{}},
+	}
+	for _, test := range tests {
+		compatibility := ScoreCompatibility(dietary.Profile{Allergies: []string{test.allergy}}, info)
+		fields := []string{}
+		for _, conflict := range compatibility.Conflicts {
+			fields = append(fields, conflict.Field)
+		}
+		if fmt.Sprint(fields) != fmt.Sprint(test.conflicts) {
+			t.Errorf("%s: expected conflicts in %v, but got %v", test.allergy, test.conflicts, compatibility.Conflicts)
+		}
+	}
+}
@@ -1,17 +1,30 @@
 package places
 
 import (
+	"eatsavvy/internal/apperrors"
 	"eatsavvy/pkg/http"
 	"encoding/json"
-	"errors"
 	"log/slog"
+	netHttp "net/http"
 	"os"
 )
 
+// ErrPlaceNotFound is returned when Places has no place with the requested id
+var ErrPlaceNotFound = apperrors.NotFound("place not found")
+
+// errPlacesUnavailable is returned to clients when Places fails; what Google said is only logged
+const errPlacesUnavailable = "Google Places is unavailable, try again later"
+
 type PlacesClient struct {
 	httpClient *http.Http
 }
 
+func NewPlacesClient() *PlacesClient {
+	return &PlacesClient{
+		httpClient: http.NewClient(),
+	}
+}
+
 func (pc *PlacesClient) GetPlaces(textQuery string, fields []string) (Places, error) {
 	slog.Info("[places.GetPlaces] Getting places for text query", "textQuery", textQuery)
 	reqBody := map[string]string{
@@ -27,11 +40,11 @@ func (pc *PlacesClient) GetPlaces(textQuery string, fields []string) (Places, er
 	respBody, statusCode, err := pc.httpClient.Post("https://places.googleapis.com/v1/places:searchText", reqBody, headers)
 	if err != nil {
 		slog.Error("[places.GetPlaces] Failed to send HTTP request", "error", err)
-		return Places{}, err
+		return Places{}, &apperrors.Error{Kind: apperrors.KindUpstream, Message: errPlacesUnavailable, Err: err}
 	}
 	if statusCode >= 400 {
 		slog.Error("[places.GetPlaces] Failed to get places", "statusCode", statusCode, "responseBody", string(respBody))
-		return Places{}, errors.New("failed to get places: " + string(respBody))
+		return Places{}, apperrors.Upstream(errPlacesUnavailable)
 	}
 
 	var places Places
@@ -56,11 +69,15 @@ func (pc *PlacesClient) GetPlaceDetails(placeId string, fields []string) (Place,
 	respBody, statusCode, err := pc.httpClient.Get("https://places.googleapis.com/v1/places/"+placeId, headers)
 	if err != nil {
 		slog.Error("[places.GetPlaceDetails] Failed to send HTTP request", "error", err)
-		return Place{}, err
+		return Place{}, &apperrors.Error{Kind: apperrors.KindUpstream, Message: errPlacesUnavailable, Err: err}
+	}
+	if statusCode == netHttp.StatusNotFound {
+		slog.Info("[places.GetPlaceDetails] Place not found", "placeId", placeId)
+		return Place{}, ErrPlaceNotFound
 	}
 	if statusCode >= 400 {
 		slog.Error("[places.GetPlaceDetails] Failed to get place details", "statusCode", statusCode, "responseBody", string(respBody))
-		return Place{}, errors.New("failed to get place details: " + string(respBody))
+		return Place{}, apperrors.Upstream(errPlacesUnavailable)
 	}
 
 	var place Place
@@ -72,3 +89,14 @@ func (pc *PlacesClient) GetPlaceDetails(placeId string, fields []string) (Place,
 
 	return place, nil
 }
+
+// GetOpenHours fetches the current opening hours for a place in its local time zone (see hoursFromPlace),
+// along with any special days (holidays, exceptional closures) in the coming week
+func (pc *PlacesClient) GetOpenHours(placeId string) (Hours, error) {
+	place, err := pc.GetPlaceDetails(placeId, []string{"id", "currentOpeningHours", "regularOpeningHours", "utcOffsetMinutes", "timeZone"})
+	if err != nil {
+		slog.Error("[places.GetOpenHours] Failed to get place details", "error", err)
+		return Hours{}, err
+	}
+	return hoursFromPlace(place), nil
+}
@@ -1,54 +1,69 @@
 package places
 
 import (
-	"database/sql"
+	"eatsavvy/internal/apperrors"
+	"eatsavvy/internal/config"
+	"eatsavvy/internal/enrichment"
+	"eatsavvy/internal/jobs"
+	"eatsavvy/internal/outbox"
+	"eatsavvy/internal/usage"
+	"eatsavvy/internal/webhooks"
 	"eatsavvy/pkg/db"
 	"eatsavvy/pkg/http"
-	"eatsavvy/pkg/queue"
 	"errors"
 
 	"encoding/json"
+	"sync"
 	"time"
 
 	"log/slog"
+
+	"github.com/jackc/pgx/v5"
 )
 
+// ErrRestaurantNotFound is returned when no restaurant has the requested id
+var ErrRestaurantNotFound = apperrors.NotFound("restaurant not found")
+
 type RestaurantsClient struct {
 	PlacesClient
-	dbClient  *db.DatabaseClient
-	publisher *queue.Publisher
+	dbClient *db.DatabaseClient
+	// enrichConcurrency is how many restaurants of a batch are enriched at once
+	enrichConcurrency int
+	costs             usage.Costs
+	limits            usage.Limits
 }
 
 func NewRestaurantClient() *RestaurantsClient {
 	httpClient := http.NewClient()
 	dbClient := db.NewDatabaseClient()
-	publisher := queue.NewPublisher("enrich_restaurant_details")
 	return &RestaurantsClient{
 		PlacesClient: PlacesClient{
 			httpClient: httpClient,
 		},
-		dbClient:  dbClient,
-		publisher: publisher,
+		dbClient:          dbClient,
+		enrichConcurrency: max(config.GetEnvInt("ENRICH_CONCURRENCY", 4), 1),
+		costs:             usage.CostsFromEnv(),
+		limits:            usage.LimitsFromEnv(),
 	}
 }
 
 func (rc *RestaurantsClient) Close() {
 	rc.dbClient.Close()
-	rc.publisher.Close()
 }
 
 func (rc *RestaurantsClient) GetRestaurant(placesId string) (Restaurant, error) {
 	var restaurant Restaurant
 	err := rc.dbClient.Db.QueryRow(rc.dbClient.Ctx,
-		`SELECT places_id, name, address, phone_number, open_hours, nutrition_info, created_at, updated_at, enrichment_status, rating 
+		`SELECT places_id, name, address, phone_number, open_hours, nutrition_info, created_at, updated_at, enrichment_status, rating, time_zone, utc_offset_minutes, special_days 
 			FROM public.restaurants WHERE places_id = $1`,
 		placesId,
 	).Scan(&restaurant.Id, &restaurant.Name, &restaurant.Address, &restaurant.PhoneNumber, &restaurant.OpenHours,
-		&restaurant.NutritionInfo, &restaurant.CreatedAt, &restaurant.UpdatedAt, &restaurant.EnrichmentStatus, &restaurant.Rating)
+		&restaurant.NutritionInfo, &restaurant.CreatedAt, &restaurant.UpdatedAt, &restaurant.EnrichmentStatus, &restaurant.Rating,
+		&restaurant.TimeZone, &restaurant.UtcOffsetMinutes, &restaurant.SpecialDays)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Restaurant{}, ErrRestaurantNotFound
+	}
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return Restaurant{}, err
-		}
 		slog.Error("[restaurants.GetRestaurant] Failed to get restaurant", "error", err)
 		return Restaurant{}, err
 	}
@@ -58,7 +73,7 @@ func (rc *RestaurantsClient) GetRestaurant(placesId string) (Restaurant, error)
 func (rc *RestaurantsClient) GetAllRestaurants() ([]Restaurant, error) {
 	var restaurants []Restaurant
 	rows, err := rc.dbClient.Db.Query(rc.dbClient.Ctx,
-		`SELECT places_id, name, address, phone_number, open_hours, nutrition_info, created_at, updated_at, enrichment_status, rating 
+		`SELECT places_id, name, address, phone_number, open_hours, nutrition_info, created_at, updated_at, enrichment_status, rating, time_zone, utc_offset_minutes, special_days 
 			FROM public.restaurants`,
 	)
 	if err != nil {
@@ -71,7 +86,7 @@ func (rc *RestaurantsClient) GetAllRestaurants() ([]Restaurant, error) {
 		var restaurant Restaurant
 		err = rows.Scan(&restaurant.Id, &restaurant.Name, &restaurant.Address, &restaurant.PhoneNumber,
 			&restaurant.OpenHours, &restaurant.NutritionInfo, &restaurant.CreatedAt, &restaurant.UpdatedAt,
-			&restaurant.EnrichmentStatus, &restaurant.Rating)
+			&restaurant.EnrichmentStatus, &restaurant.Rating, &restaurant.TimeZone, &restaurant.UtcOffsetMinutes, &restaurant.SpecialDays)
 		if err != nil {
 			slog.Error("[restaurants.GetAllRestaurants] Failed to get all restaurants", "error", err)
 			return []Restaurant{}, err
@@ -81,15 +96,58 @@ func (rc *RestaurantsClient) GetAllRestaurants() ([]Restaurant, error) {
 	return restaurants, nil
 }
 
-func (rc *RestaurantsClient) SearchRestaurants(textQuery string) ([]Restaurant, error) {
+// GetRestaurantsRequestedBy returns the restaurants in the user's enrichment jobs, most recently requested first
+func (rc *RestaurantsClient) GetRestaurantsRequestedBy(userId string) ([]Restaurant, error) {
+	if !db.IsUUID(userId) {
+		return []Restaurant{}, apperrors.Validation("invalid user id")
+	}
+	rows, err := rc.dbClient.Db.Query(rc.dbClient.Ctx,
+		`SELECT r.places_id, r.name, r.address, r.phone_number, r.open_hours, r.nutrition_info, r.created_at, r.updated_at,
+				r.enrichment_status, r.rating, r.time_zone, r.utc_offset_minutes, r.special_days
+			FROM public.restaurants r
+			JOIN (
+				SELECT i.places_id, MAX(j.created_at) AS requested_at
+				FROM public.enrichment_jobs j
+				JOIN public.enrichment_job_items i ON i.job_id = j.id
+				WHERE j.requested_by_user_id = $1::uuid
+				GROUP BY i.places_id
+			) requested ON requested.places_id = r.places_id
+			ORDER BY requested.requested_at DESC`,
+		userId,
+	)
+	if err != nil {
+		slog.Error("[restaurants.GetRestaurantsRequestedBy] Failed to get requested restaurants", "error", err, "userId", userId)
+		return []Restaurant{}, err
+	}
+
+	defer rows.Close()
+	restaurants := []Restaurant{}
+	for rows.Next() {
+		var restaurant Restaurant
+		err = rows.Scan(&restaurant.Id, &restaurant.Name, &restaurant.Address, &restaurant.PhoneNumber,
+			&restaurant.OpenHours, &restaurant.NutritionInfo, &restaurant.CreatedAt, &restaurant.UpdatedAt,
+			&restaurant.EnrichmentStatus, &restaurant.Rating, &restaurant.TimeZone, &restaurant.UtcOffsetMinutes, &restaurant.SpecialDays)
+		if err != nil {
+			slog.Error("[restaurants.GetRestaurantsRequestedBy] Failed to scan restaurant", "error", err, "userId", userId)
+			return []Restaurant{}, err
+		}
+		restaurants = append(restaurants, restaurant)
+	}
+	return restaurants, nil
+}
+
+// SearchRestaurants searches Places, charging the request to account
+func (rc *RestaurantsClient) SearchRestaurants(textQuery string, account usage.Account) ([]Restaurant, error) {
 	fields := []string{
 		"id",
 		"displayName",
 		"primaryType",
 		"currentOpeningHours",
+		"regularOpeningHours",
 		"nationalPhoneNumber",
 		"formattedAddress",
 		"utcOffsetMinutes",
+		"timeZone",
 		"rating",
 	}
 	places, err := rc.GetPlaces(textQuery, fields)
@@ -97,20 +155,26 @@ func (rc *RestaurantsClient) SearchRestaurants(textQuery string) ([]Restaurant,
 		slog.Error("[restaurants.SearchRestaurants] Failed to get restaurants", "error", err)
 		return nil, err
 	}
+	// Failing to record usage shouldn't fail a search that was already paid for
+	usage.Record(rc.dbClient.Ctx, rc.dbClient.Db, account, usage.KindPlacesSearch, "", textQuery, rc.costs.PlacesSearchUsd)
 	filteredPlaces := filterRestaurants(places.Places)
+	rc.recordSearchHits(filteredPlaces)
 	restaurants := []Restaurant{}
 	for _, place := range filteredPlaces {
 		restaurant, err := rc.GetRestaurant(place.Id)
-		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		if err != nil && !errors.Is(err, ErrRestaurantNotFound) {
 			slog.Error("[restaurants.SearchRestaurants] Failed to get restaurant", "error", err)
 			return []Restaurant{}, err
 		}
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, ErrRestaurantNotFound) {
+			hours := hoursFromPlace(place)
 			restaurant = Restaurant{
 				Id:          place.Id,
 				Name:        place.DisplayName.Text,
 				Address:     place.Address,
-				OpenHours:   periodsToTimeRanges(place.CurrentOpeningHours.Periods, place.UtcOffsetMinutes),
+				OpenHours:   hours.OpenHours,
+				TimeZone:    timeZonePtr(hours.TimeZone),
+				SpecialDays: hours.SpecialDays,
 				PhoneNumber: place.NationalPhoneNumber,
 				Rating:      &place.Rating,
 			}
@@ -120,68 +184,131 @@ func (rc *RestaurantsClient) SearchRestaurants(textQuery string) ([]Restaurant,
 	return restaurants, nil
 }
 
-func (rc *RestaurantsClient) enrichRestaurantDetails(restaurantId string) (Restaurant, error) {
-	var restaurant Restaurant
-	var openHours []byte
-	var nutritionInfo []byte
-	err := rc.dbClient.Db.QueryRow(rc.dbClient.Ctx,
-		`SELECT places_id, name, address, phone_number, open_hours, nutrition_info, created_at, updated_at, enrichment_status, rating
-		 FROM public.restaurants WHERE places_id = $1`,
-		restaurantId,
-	).Scan(&restaurant.Id, &restaurant.Name, &restaurant.Address, &restaurant.PhoneNumber, &openHours, &nutritionInfo,
-		&restaurant.CreatedAt, &restaurant.UpdatedAt, &restaurant.EnrichmentStatus, &restaurant.Rating)
+// recordSearchHits counts how often known restaurants show up in searches, which the refresh scheduler
+// uses to prioritize re-enrichment. Failures are logged and don't affect the search.
+func (rc *RestaurantsClient) recordSearchHits(places []Place) {
+	placesIds := make([]string, len(places))
+	for i, place := range places {
+		placesIds[i] = place.Id
+	}
+	_, err := rc.dbClient.Db.Exec(rc.dbClient.Ctx,
+		`UPDATE public.restaurants SET search_count = search_count + 1, last_searched_at = NOW() WHERE places_id = ANY($1)`,
+		placesIds,
+	)
+	if err != nil {
+		slog.Error("[restaurants.recordSearchHits] Failed to record search hits", "error", err)
+	}
+}
 
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		slog.Error("[restaurants.EnrichRestaurantDetails] Failed to get restaurant details", "error", err)
-		return Restaurant{}, err
+// enrichRestaurantDetails queues the restaurant for enrichment as part of the enrichment job jobId, or records
+// it in the job as skipped if it is fresh or already being enriched (see shouldSkipEnrichment)
+func (rc *RestaurantsClient) enrichRestaurantDetails(jobId string, restaurantId string, options EnrichOptions) (EnrichmentResult, error) {
+	restaurant, skipped, err := rc.skipIfUpToDate(jobId, restaurantId, options)
+	if err != nil {
+		return EnrichmentResult{}, err
 	}
-	if err == nil {
-		if len(openHours) > 0 {
-			json.Unmarshal(openHours, &restaurant.OpenHours)
-		}
-		// If enrichment is completed and updated within the last 30 days, or is in progress or queued, no need to enrich again
-		if (restaurant.EnrichmentStatus == EnrichmentStatusCompleted &&
-			restaurant.UpdatedAt.After(time.Now().Add(-1*time.Hour*24*30))) ||
-			restaurant.EnrichmentStatus == EnrichmentStatusInProgress ||
-			restaurant.EnrichmentStatus == EnrichmentStatusQueued {
-			return restaurant, nil
-		}
+	if skipped != nil {
+		return *skipped, nil
 	}
 
-	// If no row found, fetch from API
+	// If no row found, fetch from API. Lookups run outside the lock so a batch's Places requests overlap.
 	fields := []string{
 		"id",
 		"displayName",
 		"currentOpeningHours",
+		"regularOpeningHours",
 		"nationalPhoneNumber",
 		"formattedAddress",
 		"utcOffsetMinutes",
+		"timeZone",
 		"rating",
 	}
 	place, err := rc.GetPlaceDetails(restaurantId, fields)
+	if err == nil || errors.Is(err, ErrPlaceNotFound) {
+		usage.RecordForJob(rc.dbClient.Ctx, rc.dbClient.Db, jobId, usage.KindPlacesDetails, restaurantId, rc.costs.PlacesDetailsUsd)
+	}
 	if err != nil {
 		slog.Error("[restaurants.EnrichRestaurantDetails] Failed to get place details", "error", err)
-		return Restaurant{}, err
+		return EnrichmentResult{}, err
+	}
+	return rc.queueRestaurant(jobId, restaurant, place, options)
+}
+
+// skipIfUpToDate records the restaurant as skipped and returns its result if it doesn't need enriching.
+// Otherwise it returns the stored restaurant, if any.
+func (rc *RestaurantsClient) skipIfUpToDate(jobId string, restaurantId string, options EnrichOptions) (Restaurant, *EnrichmentResult, error) {
+	var restaurant Restaurant
+	var openHours []byte
+	var nutritionInfo []byte
+	err := rc.dbClient.Db.QueryRow(rc.dbClient.Ctx,
+		`SELECT places_id, name, address, phone_number, open_hours, nutrition_info, created_at, updated_at, enrichment_status, rating, time_zone, utc_offset_minutes, special_days
+		 FROM public.restaurants WHERE places_id = $1`,
+		restaurantId,
+	).Scan(&restaurant.Id, &restaurant.Name, &restaurant.Address, &restaurant.PhoneNumber, &openHours, &nutritionInfo,
+		&restaurant.CreatedAt, &restaurant.UpdatedAt, &restaurant.EnrichmentStatus, &restaurant.Rating,
+		&restaurant.TimeZone, &restaurant.UtcOffsetMinutes, &restaurant.SpecialDays)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Restaurant{}, nil, nil
 	}
+	if err != nil {
+		slog.Error("[restaurants.EnrichRestaurantDetails] Failed to get restaurant details", "error", err)
+		return Restaurant{}, nil, err
+	}
+	if len(openHours) > 0 {
+		json.Unmarshal(openHours, &restaurant.OpenHours)
+	}
+	if skip, reason := shouldSkipEnrichment(restaurant.EnrichmentStatus, restaurant.UpdatedAt, options.Force, time.Now()); skip {
+		err = enrichment.AddItem(rc.dbClient.Ctx, rc.dbClient.Db, jobId, restaurant.Id, enrichment.StatusSkipped, reason)
+		if err != nil {
+			return Restaurant{}, nil, err
+		}
+		result := skippedResult(restaurant, reason)
+		return restaurant, &result, nil
+	}
+	return restaurant, nil, nil
+}
 
+// queueRestaurant upserts the restaurant from its place details and enqueues its job, unless it was queued
+// while the details were being fetched
+func (rc *RestaurantsClient) queueRestaurant(jobId string, restaurant Restaurant, place Place, options EnrichOptions) (EnrichmentResult, error) {
 	// Start transaction to check enrichment_status and upsert atomically
 	tx, err := rc.dbClient.Db.Begin(rc.dbClient.Ctx)
 	if err != nil {
 		slog.Error("[restaurants.EnrichRestaurantDetails] Failed to begin transaction", "error", err)
-		return Restaurant{}, err
+		return EnrichmentResult{}, err
 	}
 	defer tx.Rollback(rc.dbClient.Ctx)
 
-	// Check if enrichment_status is already "queued" or "in_progress"
+	// Check the status again now that the row is locked, it may have been queued in the meantime
 	var existingStatus string
+	var existingUpdatedAt time.Time
+	var existingJobId *string
 	err = tx.QueryRow(rc.dbClient.Ctx,
-		`SELECT enrichment_status FROM public.restaurants WHERE places_id = $1 FOR UPDATE`,
+		`SELECT enrichment_status, updated_at, enrichment_job_id FROM public.restaurants WHERE places_id = $1 FOR UPDATE`,
 		place.Id,
-	).Scan(&existingStatus)
+	).Scan(&existingStatus, &existingUpdatedAt, &existingJobId)
 
-	if err == nil && (existingStatus == string(EnrichmentStatusQueued) || existingStatus == string(EnrichmentStatusInProgress)) {
-		slog.Info("[restaurants.EnrichRestaurantDetails] Skipping insert, enrichment already in progress or queued", "places_id", place.Id, "status", existingStatus)
-		return restaurant, nil
+	if err == nil {
+		if skip, reason := shouldSkipEnrichment(EnrichmentStatus(existingStatus), existingUpdatedAt, options.Force, time.Now()); skip {
+			slog.Info("[restaurants.EnrichRestaurantDetails] Skipping insert", "places_id", place.Id, "status", existingStatus, "reason", reason)
+			err = enrichment.AddItem(rc.dbClient.Ctx, tx, jobId, place.Id, enrichment.StatusSkipped, reason)
+			if err != nil {
+				return EnrichmentResult{}, err
+			}
+			if err = tx.Commit(rc.dbClient.Ctx); err != nil {
+				slog.Error("[restaurants.EnrichRestaurantDetails] Failed to commit transaction", "error", err)
+				return EnrichmentResult{}, err
+			}
+			return skippedResult(restaurant, reason), nil
+		}
+		if existingStatus == string(EnrichmentStatusQueued) {
+			// Forced over a queued job: withdraw it if it hasn't been published, otherwise the worker skips it as stale
+			err = rc.supersedeQueuedJob(tx, place.Id, existingJobId)
+			if err != nil {
+				return EnrichmentResult{}, err
+			}
+		}
 	}
 
 	restaurant.Id = place.Id
@@ -190,67 +317,167 @@ func (rc *RestaurantsClient) enrichRestaurantDetails(restaurantId string) (Resta
 	if place.NationalPhoneNumber != "" {
 		restaurant.PhoneNumber = place.NationalPhoneNumber
 	}
-	restaurant.OpenHours = periodsToTimeRanges(place.CurrentOpeningHours.Periods, place.UtcOffsetMinutes)
+	hours := hoursFromPlace(place)
+	restaurant.OpenHours = hours.OpenHours
+	restaurant.SpecialDays = hours.SpecialDays
+	restaurant.UtcOffsetMinutes = &hours.UtcOffsetMinutes
+	restaurant.TimeZone = timeZonePtr(hours.TimeZone)
 	restaurant.Rating = &place.Rating
 	restaurant.EnrichmentStatus = EnrichmentStatusQueued
+	job := jobs.NewEnrichmentJob(restaurant.Id, restaurant.Name)
+	job.Priority = options.Priority
 
-	// Proceed with upsert and set enrichment_status to "queued"
+	// Proceed with upsert and set enrichment_status to "queued", recording which job is responsible for the restaurant
+	// and when it is due, so recovery doesn't mistake a job held back until notBefore for a lost one
 	_, err = tx.Exec(rc.dbClient.Ctx,
-		`INSERT INTO public.restaurants (places_id, name, address, phone_number, open_hours, rating, enrichment_status) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7) 
+		`INSERT INTO public.restaurants (places_id, name, address, phone_number, open_hours, rating, enrichment_status, open_hours_updated_at, enrichment_job_id, enrichment_job_due_at, special_days, utc_offset_minutes, time_zone) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), $8, COALESCE($12, NOW()), $9, $10, $11) 
 		ON CONFLICT (places_id) DO UPDATE SET 
 			name = EXCLUDED.name, 
 			address = EXCLUDED.address, 
 			phone_number = COALESCE(restaurants.phone_number, EXCLUDED.phone_number), 
 			open_hours = EXCLUDED.open_hours,
+			open_hours_updated_at = EXCLUDED.open_hours_updated_at,
+			special_days = EXCLUDED.special_days,
+			utc_offset_minutes = EXCLUDED.utc_offset_minutes,
+			time_zone = EXCLUDED.time_zone,
 			rating = EXCLUDED.rating,
 			enrichment_status = EXCLUDED.enrichment_status,
+			enrichment_job_id = EXCLUDED.enrichment_job_id,
+			enrichment_job_due_at = EXCLUDED.enrichment_job_due_at,
 			updated_at = NOW()
 		`,
 		restaurant.Id, restaurant.Name, restaurant.Address, restaurant.PhoneNumber,
-		restaurant.OpenHours, restaurant.Rating, restaurant.EnrichmentStatus,
+		restaurant.OpenHours, restaurant.Rating, restaurant.EnrichmentStatus, job.Id, restaurant.SpecialDays,
+		restaurant.UtcOffsetMinutes, restaurant.TimeZone, options.NotBefore,
 	)
 	if err != nil {
 		slog.Error("[restaurants.EnrichRestaurantDetails] Failed to insert restaurant details", "error", err)
-		return Restaurant{}, err
+		return EnrichmentResult{}, err
+	}
+
+	// The job is written to the outbox in the same transaction as the queued status and published by the relay
+	err = outbox.EnqueueAt(rc.dbClient.Ctx, tx, jobs.EnrichmentQueue, job, options.NotBefore)
+	if err != nil {
+		slog.Error("[restaurants.EnrichRestaurantDetails] Failed to enqueue enrichment job", "error", err)
+		return EnrichmentResult{}, err
+	}
+	err = enrichment.AddItem(rc.dbClient.Ctx, tx, jobId, restaurant.Id, enrichment.StatusQueued, "")
+	if err != nil {
+		return EnrichmentResult{}, err
+	}
+	// Takes one of the requester's enrichments, or fails the restaurant if none are left; the call's cost is
+	// recorded when it ends
+	err = usage.ReserveEnrichment(rc.dbClient.Ctx, tx, jobId, restaurant.Id, rc.limits)
+	if err != nil {
+		return EnrichmentResult{}, err
 	}
 
 	if err = tx.Commit(rc.dbClient.Ctx); err != nil {
 		slog.Error("[restaurants.EnrichRestaurantDetails] Failed to commit transaction", "error", err)
-		return Restaurant{}, err
+		return EnrichmentResult{}, err
 	}
 
-	slog.Info("[restaurants.EnrichRestaurantDetails] Upserted restaurant", "places_id", restaurant.Id)
+	slog.Info("[restaurants.EnrichRestaurantDetails] Enqueued enrichment job for restaurant", "places_id", place.Id,
+		"priority", job.Priority, "notBefore", options.NotBefore)
 
-	err = rc.publisher.PublishMessage(restaurant)
-	if err != nil {
-		slog.Error("[restaurants.EnrichRestaurantDetails] Failed to publish message", "error", err)
-		_, err = rc.dbClient.Db.Exec(rc.dbClient.Ctx,
-			`UPDATE public.restaurants SET enrichment_status = $1 WHERE places_id = $2`,
-			EnrichmentStatusFailed, restaurant.Id,
+	return EnrichmentResult{RestaurantId: restaurant.Id, Outcome: EnrichmentOutcomeEnqueued, Restaurant: &restaurant}, nil
+}
+
+// supersedeQueuedJob withdraws the restaurant's queued job from the outbox if it hasn't been published yet
+// and marks its enrichment job item cancelled
+func (rc *RestaurantsClient) supersedeQueuedJob(tx pgx.Tx, placesId string, queuedJobId *string) error {
+	if queuedJobId != nil {
+		_, err := tx.Exec(rc.dbClient.Ctx,
+			`DELETE FROM public.outbox WHERE message_id = $1 AND published_at IS NULL`,
+			*queuedJobId,
 		)
 		if err != nil {
-			slog.Error("[restaurants.EnrichRestaurantDetails] Failed to update enrichment status", "error", err)
-			return Restaurant{}, err
+			slog.Error("[restaurants.supersedeQueuedJob] Failed to withdraw queued job", "error", err, "places_id", placesId)
+			return err
 		}
-		return Restaurant{}, err
+	}
+	return enrichment.SetRestaurantStatus(rc.dbClient.Ctx, tx, placesId, enrichment.StatusCancelled)
+}
+
+// BatchEnrichRestaurantDetails creates an enrichment job for the restaurants and queues the ones that need
+// enriching. It returns the job along with what happened to each restaurant.
+func (rc *RestaurantsClient) BatchEnrichRestaurantDetails(restaurantIds []string, requester enrichment.Requester, options EnrichOptions) (enrichment.Job, []EnrichmentResult, error) {
+	jobId, err := enrichment.CreateJob(rc.dbClient.Ctx, rc.dbClient.Db, requester)
+	if err != nil {
+		return enrichment.Job{}, []EnrichmentResult{}, err
 	}
 
-	slog.Info("[restaurants.EnrichRestaurantDetails] Enqueued enrichment job for restaurant", "places_id", place.Id)
+	// Restaurants are enriched independently, so one that fails doesn't affect the others. Results keep the
+	// order of restaurantIds.
+	results := make([]EnrichmentResult, len(restaurantIds))
+	semaphore := make(chan struct{}, rc.enrichConcurrency)
+	var wg sync.WaitGroup
+	for i, restaurantId := range restaurantIds {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i] = rc.enrichBatchItem(jobId, restaurantId, options)
+		}()
+	}
+	wg.Wait()
 
-	return restaurant, nil
+	job, err := enrichment.GetJob(rc.dbClient.Ctx, rc.dbClient.Db, jobId)
+	if err != nil {
+		return enrichment.Job{}, []EnrichmentResult{}, err
+	}
+	slog.Info("[restaurants.BatchEnrichRestaurantDetails] Created enrichment job", "jobId", jobId, "restaurants", len(restaurantIds))
+	return job, results, nil
 }
 
-func (rc *RestaurantsClient) BatchEnrichRestaurantDetails(restaurantIds []string) ([]Restaurant, error) {
-	restaurants := []Restaurant{}
-	for _, restaurantId := range restaurantIds {
-		restaurant, err := rc.enrichRestaurantDetails(restaurantId)
-		if err != nil {
-			return []Restaurant{}, err
-		}
-		restaurants = append(restaurants, restaurant)
+// enrichBatchItem enriches one restaurant of a batch, turning a failure into a result and a failed job item
+func (rc *RestaurantsClient) enrichBatchItem(jobId string, restaurantId string, options EnrichOptions) EnrichmentResult {
+	result, err := rc.enrichRestaurantDetails(jobId, restaurantId, options)
+	if err == nil {
+		return result
 	}
-	return restaurants, nil
+	slog.Error("[restaurants.enrichBatchItem] Failed to enrich restaurant", "error", err, "places_id", restaurantId, "jobId", jobId)
+	result = failedResult(restaurantId, err)
+
+	reason := result.Error
+	if result.Outcome == EnrichmentOutcomeNotFound {
+		reason = result.Reason
+	}
+	err = enrichment.AddItem(rc.dbClient.Ctx, rc.dbClient.Db, jobId, restaurantId, enrichment.StatusFailed, reason)
+	if err != nil {
+		slog.Error("[restaurants.enrichBatchItem] Failed to record failed job item", "error", err, "places_id", restaurantId, "jobId", jobId)
+	}
+	return result
+}
+
+func failedResult(restaurantId string, err error) EnrichmentResult {
+	if errors.Is(err, ErrPlaceNotFound) {
+		return EnrichmentResult{RestaurantId: restaurantId, Outcome: EnrichmentOutcomeNotFound, Reason: "no place with this id"}
+	}
+	return EnrichmentResult{RestaurantId: restaurantId, Outcome: EnrichmentOutcomeError, Error: err.Error()}
+}
+
+// shouldSkipEnrichment reports whether a restaurant with the given status is left alone, and why. Calls in
+// progress are never repeated; force overrides freshness and queued jobs.
+func shouldSkipEnrichment(status EnrichmentStatus, updatedAt time.Time, force bool, now time.Time) (bool, string) {
+	switch {
+	case status == EnrichmentStatusInProgress:
+		return true, "already in progress"
+	case force:
+		return false, ""
+	case status == EnrichmentStatusQueued:
+		return true, "already queued"
+	case status == EnrichmentStatusCompleted && updatedAt.After(now.Add(-EnrichmentMaxAge)):
+		return true, "recently enriched"
+	default:
+		return false, ""
+	}
+}
+
+func skippedResult(restaurant Restaurant, reason string) EnrichmentResult {
+	return EnrichmentResult{RestaurantId: restaurant.Id, Outcome: EnrichmentOutcomeSkipped, Reason: reason, Restaurant: &restaurant}
 }
 
 func (rc *RestaurantsClient) UpdateRestaurantPhoneNumber(placesId string, phoneNumber string) (Restaurant, error) {
@@ -259,10 +486,14 @@ func (rc *RestaurantsClient) UpdateRestaurantPhoneNumber(placesId string, phoneN
 		`UPDATE public.restaurants 
 		 SET phone_number = $1, updated_at = NOW() 
 		 WHERE places_id = $2
-		 RETURNING places_id, name, address, phone_number, open_hours, nutrition_info, created_at, updated_at, enrichment_status, rating`,
+		 RETURNING places_id, name, address, phone_number, open_hours, nutrition_info, created_at, updated_at, enrichment_status, rating, time_zone, utc_offset_minutes, special_days`,
 		phoneNumber, placesId,
 	).Scan(&restaurant.Id, &restaurant.Name, &restaurant.Address, &restaurant.PhoneNumber, &restaurant.OpenHours,
-		&restaurant.NutritionInfo, &restaurant.CreatedAt, &restaurant.UpdatedAt, &restaurant.EnrichmentStatus, &restaurant.Rating)
+		&restaurant.NutritionInfo, &restaurant.CreatedAt, &restaurant.UpdatedAt, &restaurant.EnrichmentStatus, &restaurant.Rating,
+		&restaurant.TimeZone, &restaurant.UtcOffsetMinutes, &restaurant.SpecialDays)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Restaurant{}, ErrRestaurantNotFound
+	}
 	if err != nil {
 		slog.Error("[restaurants.UpdateRestaurantPhoneNumber] Failed to update phone number", "error", err)
 		return Restaurant{}, err
@@ -277,9 +508,17 @@ func (rc *RestaurantsClient) UpdateRestaurantNutritionInfo(eocr EndOfCallReportM
 		nutritionInfo[result.Name] = result.Result
 	}
 
-	var placesId string
+	// Updating the call and restaurant and recording webhook deliveries happen together, so integrators are
+	// notified exactly when the new status is visible
+	tx, err := rc.dbClient.Db.Begin(rc.dbClient.Ctx)
+	if err != nil {
+		slog.Error("[restaurants.UpdateRestaurantNutritionInfo] Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback(rc.dbClient.Ctx)
 
-	err := rc.dbClient.Db.QueryRow(rc.dbClient.Ctx,
+	var placesId string
+	err = tx.QueryRow(rc.dbClient.Ctx,
 		`UPDATE public.calls SET call_status = $1, transcript = $2, structured_outputs = $3, summary = $4, success_evaluation = $5, ended_reason = $6, updated_at = NOW() WHERE vapi_call_id = $7 returning places_id`,
 		"completed", eocr.Message.Artifact.Transcript, eocr.Message.Artifact.StructuredOutputs, eocr.Message.Analysis.Summary, eocr.Message.Analysis.SuccessEvaluation, eocr.Message.EndedReason, eocr.Message.Call.ID,
 	).Scan(&placesId)
@@ -288,18 +527,46 @@ func (rc *RestaurantsClient) UpdateRestaurantNutritionInfo(eocr EndOfCallReportM
 	}
 
 	status := EnrichmentStatusCompleted
+	eventType := webhooks.EventEnrichmentCompleted
 	if eocr.Message.Analysis.SuccessEvaluation == "false" || eocr.Message.EndedReason != "customer-ended-call" {
 		slog.Info("[restaurants.UpdateRestaurantNutritionInfo] Call was not successful", "places_id", placesId, "call_id", eocr.Message.Call.ID)
 		status = EnrichmentStatusFailed
+		eventType = webhooks.EventEnrichmentFailed
 	}
-	_, err = rc.dbClient.Db.Exec(rc.dbClient.Ctx,
-		`UPDATE public.restaurants SET nutrition_info = $1, enrichment_status = $2, updated_at = NOW() WHERE places_id = $3`,
+	var name string
+	err = tx.QueryRow(rc.dbClient.Ctx,
+		`UPDATE public.restaurants SET nutrition_info = $1, enrichment_status = $2, updated_at = NOW() WHERE places_id = $3 RETURNING name`,
 		nutritionInfo, status, placesId,
-	)
+	).Scan(&name)
 	if err != nil {
 		slog.Error("[restaurants.UpdateRestaurantNutritionInfo] Failed to update restaurant nutrition info", "error", err)
 		return err
 	}
+	// Charge the call before its job item is completed, while the job it belongs to can still be found
+	err = usage.RecordCall(rc.dbClient.Ctx, tx, placesId, eocr.Message.Call.ID, eocr.Message.Cost)
+	if err != nil {
+		return err
+	}
+	err = enrichment.SetRestaurantStatus(rc.dbClient.Ctx, tx, placesId, enrichment.Status(status))
+	if err != nil {
+		return err
+	}
+
+	err = webhooks.Enqueue(rc.dbClient.Ctx, tx, eventType, webhooks.RestaurantEvent{
+		RestaurantId:     placesId,
+		Name:             name,
+		EnrichmentStatus: string(status),
+		NutritionInfo:    nutritionInfo,
+	})
+	if err != nil {
+		slog.Error("[restaurants.UpdateRestaurantNutritionInfo] Failed to enqueue webhooks", "error", err)
+		return err
+	}
+
+	if err = tx.Commit(rc.dbClient.Ctx); err != nil {
+		slog.Error("[restaurants.UpdateRestaurantNutritionInfo] Failed to commit transaction", "error", err)
+		return err
+	}
 	slog.Info("[restaurants.UpdateRestaurantNutritionInfo] Updated restaurant nutrition info", "places_id", placesId)
 	return nil
 }
@@ -0,0 +1,66 @@
+package places
+
+import (
+	"log/slog"
+	"time"
+	_ "time/tzdata" // embed the zone database so LoadLocation works in containers without one
+)
+
+// loadLocation loads an IANA time zone, returning false for an empty or unknown name
+func loadLocation(timeZone string) (*time.Location, bool) {
+	if timeZone == "" {
+		return nil, false
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		slog.Error("[places.loadLocation] Failed to load time zone", "timeZone", timeZone, "error", err)
+		return nil, false
+	}
+	return location, true
+}
+
+// hoursFromPlace builds the hours we store for a place. The weekly hours come from the regular schedule, since
+// the current hours of the coming week include one-off closures that would otherwise repeat every week; those are
+// kept as special days instead. When Places gives us its IANA time zone the weekly hours are kept in local time
+// and converted when evaluated, so they stay correct across DST changes. Otherwise they are converted to UTC with
+// the current offset and TimeZone is left empty.
+func hoursFromPlace(place Place) Hours {
+	hours := Hours{UtcOffsetMinutes: place.UtcOffsetMinutes}
+	weekly := periodsToTimeRanges(place.RegularOpeningHours.Periods)
+	location, ok := loadLocation(place.TimeZone.Id)
+	if ok {
+		hours.TimeZone = place.TimeZone.Id
+		hours.OpenHours = weekly
+	} else {
+		location = time.FixedZone("", place.UtcOffsetMinutes*60)
+		hours.OpenHours = timeRangesToUtc(weekly, place.UtcOffsetMinutes)
+	}
+	hours.SpecialDays = specialDaysFromOpeningHours(place.CurrentOpeningHours, location)
+	return hours
+}
+
+// LocalOpenHours returns a restaurant's weekly hours in its local time along with that time zone.
+// Rows stored before time zones were recorded hold UTC hours; those are shifted by the stored UTC offset
+// (or left in UTC if there is none).
+func LocalOpenHours(restaurant Restaurant) ([]TimeRange, *time.Location) {
+	if restaurant.TimeZone != nil {
+		if location, ok := loadLocation(*restaurant.TimeZone); ok {
+			return restaurant.OpenHours, location
+		}
+		if restaurant.UtcOffsetMinutes != nil {
+			return restaurant.OpenHours, time.FixedZone("", *restaurant.UtcOffsetMinutes*60)
+		}
+		return restaurant.OpenHours, time.UTC
+	}
+	if restaurant.UtcOffsetMinutes != nil {
+		return timeRangesToUtc(restaurant.OpenHours, -*restaurant.UtcOffsetMinutes), time.FixedZone("", *restaurant.UtcOffsetMinutes*60)
+	}
+	return restaurant.OpenHours, time.UTC
+}
+
+func timeZonePtr(timeZone string) *string {
+	if timeZone == "" {
+		return nil
+	}
+	return &timeZone
+}
@@ -1,6 +1,10 @@
 package places
 
-import "time"
+import (
+	"eatsavvy/internal/dietary"
+	"eatsavvy/internal/jobs"
+	"time"
+)
 
 type EnrichmentStatus string
 
@@ -10,8 +14,45 @@ const (
 	EnrichmentStatusQueued     EnrichmentStatus = "queued"
 	EnrichmentStatusCompleted  EnrichmentStatus = "completed"
 	EnrichmentStatusFailed     EnrichmentStatus = "failed"
+	EnrichmentStatusCancelled  EnrichmentStatus = "cancelled"
 )
 
+// EnrichmentMaxAge is how long completed enrichment data is considered fresh
+const EnrichmentMaxAge = 30 * 24 * time.Hour
+
+// EnrichOptions adjust how BatchEnrichRestaurantDetails queues restaurants
+type EnrichOptions struct {
+	// Force re-enriches restaurants that are still fresh and replaces jobs that are queued but not started.
+	// A call that is already in progress is never repeated.
+	Force    bool
+	Priority jobs.Priority
+	// NotBefore holds the job back until the given time, after which the usual call windows apply
+	NotBefore *time.Time
+}
+
+type EnrichmentOutcome string
+
+const (
+	EnrichmentOutcomeEnqueued EnrichmentOutcome = "enqueued"
+	EnrichmentOutcomeSkipped  EnrichmentOutcome = "skipped"
+	EnrichmentOutcomeNotFound EnrichmentOutcome = "not_found"
+	EnrichmentOutcomeError    EnrichmentOutcome = "error"
+)
+
+// EnrichmentResult reports what enriching one restaurant id did, with a snapshot of the restaurant unless it failed
+type EnrichmentResult struct {
+	RestaurantId string            `json:"id"`
+	Outcome      EnrichmentOutcome `json:"outcome"`
+	Reason       string            `json:"reason,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	Restaurant   *Restaurant       `json:"restaurant,omitempty"`
+}
+
+// Failed reports whether the restaurant could not be enriched
+func (r EnrichmentResult) Failed() bool {
+	return r.Outcome == EnrichmentOutcomeNotFound || r.Outcome == EnrichmentOutcomeError
+}
+
 type NutritionInfo struct {
 	CookingOils           string `json:"oil"`
 	NutFree               bool   `json:"nutFree"`
@@ -25,11 +66,27 @@ type Restaurant struct {
 	Address          string           `json:"address"`
 	PhoneNumber      string           `json:"phoneNumber"`
 	OpenHours        []TimeRange      `json:"openHours"`
+	TimeZone         *string          `json:"timeZone"` // IANA zone OpenHours are in; nil means OpenHours are in UTC
+	SpecialDays      []SpecialDay     `json:"specialDays,omitempty"`
 	NutritionInfo    *NutritionInfo   `json:"nutritionInfo"`
 	Rating           *float64         `json:"rating"`
 	CreatedAt        time.Time        `json:"createdAt"`
 	UpdatedAt        time.Time        `json:"updatedAt"`
 	EnrichmentStatus EnrichmentStatus `json:"enrichmentStatus"`
+	// OpenNow, NextOpenAt, NextCloseAt and WeekdayDescriptions are computed for API responses from the hours above
+	// (see openhours); they are nil when the hours are unknown
+	OpenNow             *bool      `json:"openNow"`
+	NextOpenAt          *time.Time `json:"nextOpenAt"`
+	NextCloseAt         *time.Time `json:"nextCloseAt"`
+	WeekdayDescriptions []string   `json:"weekdayDescriptions"`
+	// Compatibility is computed for API responses when a dietary profile is given (see ScoreCompatibility)
+	Compatibility *dietary.Compatibility `json:"compatibility,omitempty"`
+	// OpenHoursUpdatedAt is when OpenHours was last fetched from Places (nil if never recorded)
+	OpenHoursUpdatedAt *time.Time `json:"-"`
+	// UtcOffsetMinutes is the restaurant's UTC offset when its hours were last fetched, used for local-time call windows
+	UtcOffsetMinutes *int `json:"-"`
+	// EnrichmentJobId is the job currently responsible for enriching the restaurant; jobs with any other id are stale
+	EnrichmentJobId *string `json:"-"`
 }
 
 type Places struct {
@@ -43,15 +100,28 @@ type Place struct {
 	Address             string       `json:"formattedAddress"`
 	NationalPhoneNumber string       `json:"nationalPhoneNumber"`
 	CurrentOpeningHours OpeningHours `json:"currentOpeningHours"`
+	RegularOpeningHours OpeningHours `json:"regularOpeningHours"`
 	UtcOffsetMinutes    int          `json:"utcOffsetMinutes"`
+	TimeZone            TimeZone     `json:"timeZone"`
 	Rating              float64      `json:"rating"`
 }
 
+// TimeZone is an IANA time zone as returned by Places
+type TimeZone struct {
+	Id string `json:"id"`
+}
+
 type OpeningHours struct {
 	NextOpenTime        string   `json:"nextOpenTime"`
 	OpenNow             bool     `json:"openNow"`
 	Periods             []Period `json:"periods"`
 	WeekdayDescriptions []string `json:"weekdayDescriptions"`
+	// SpecialDays lists dates in the next week whose hours differ from the regular schedule (e.g. holidays)
+	SpecialDays []SpecialDayRef `json:"specialDays"`
+}
+
+type SpecialDayRef struct {
+	Date Date `json:"date"`
 }
 
 type Period struct {
@@ -90,6 +160,30 @@ type TimeRange struct {
 	Close TimePoint `json:"close"`
 }
 
+// SpecialDay is a local calendar day with exceptional hours. While it lasts (Start to End) it replaces
+// the weekly OpenHours: the restaurant is only open during Hours, and closed all day if Hours is empty.
+type SpecialDay struct {
+	Date  string        `json:"date"` // YYYY-MM-DD in the restaurant's local time
+	Start time.Time     `json:"start"`
+	End   time.Time     `json:"end"`
+	Hours []DatedPeriod `json:"hours"`
+}
+
+// Hours is everything the worker needs from Places to decide when a restaurant can be called
+type Hours struct {
+	// OpenHours are in TimeZone's local time, or in UTC if TimeZone is empty
+	OpenHours        []TimeRange
+	SpecialDays      []SpecialDay
+	UtcOffsetMinutes int
+	TimeZone         string
+}
+
+// DatedPeriod is an open/close window at absolute times
+type DatedPeriod struct {
+	Open  time.Time `json:"open"`
+	Close time.Time `json:"close"`
+}
+
 type StructuredOutput struct {
 	Name   string      `json:"name"`
 	Result interface{} `json:"result"`
@@ -102,13 +196,15 @@ type EndOfCallReportMessage struct {
 			StructuredOutputs map[string]StructuredOutput `json:"structuredOutputs"`
 		} `json:"artifact"`
 		Analysis struct {
-			Summary           string `json:summary`
+			Summary           string `json:"summary"`
 			SuccessEvaluation string `json:"successEvaluation"`
 		}
 		Call struct {
 			ID string `json:"id"`
 		} `json:"call"`
 		EndedReason string `json:"endedReason"`
+		// Cost is the call's total cost in USD
+		Cost float64 `json:"cost"`
 	} `json:"message"`
 }
 
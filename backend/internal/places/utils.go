@@ -5,6 +5,7 @@ import (
 	"slices"
 	"sort"
 	"strings"
+	"time"
 )
 
 func getGooglePlacesFieldMask(fields []string, needsPlacesPrefix bool) string {
@@ -54,16 +55,13 @@ func applyUtcOffset(tp TimePoint, utcOffsetMinutes int) TimePoint {
 	}
 }
 
-// periodsToTimeRanges converts a slice of Period to a slice of TimeRange in UTC, sorted by weekday index
-func periodsToTimeRanges(periods []Period, utcOffsetMinutes int) []TimeRange {
+// periodsToTimeRanges converts a slice of Period to a slice of TimeRange in the place's local time, sorted by weekday index
+func periodsToTimeRanges(periods []Period) []TimeRange {
 	ranges := make([]TimeRange, len(periods))
 	for i, p := range periods {
-		open := TimePoint{Weekday: p.Open.Day, Hour: p.Open.Hour, Minute: p.Open.Minute}
-		close := TimePoint{Weekday: p.Close.Day, Hour: p.Close.Hour, Minute: p.Close.Minute}
-
 		ranges[i] = TimeRange{
-			Open:  applyUtcOffset(open, utcOffsetMinutes),
-			Close: applyUtcOffset(close, utcOffsetMinutes),
+			Open:  TimePoint{Weekday: p.Open.Day, Hour: p.Open.Hour, Minute: p.Open.Minute},
+			Close: TimePoint{Weekday: p.Close.Day, Hour: p.Close.Hour, Minute: p.Close.Minute},
 		}
 	}
 	// Sort by weekday index
@@ -72,3 +70,57 @@ func periodsToTimeRanges(periods []Period, utcOffsetMinutes int) []TimeRange {
 	})
 	return ranges
 }
+
+// timeRangesToUtc converts local time ranges to UTC with a fixed offset
+func timeRangesToUtc(ranges []TimeRange, utcOffsetMinutes int) []TimeRange {
+	converted := make([]TimeRange, len(ranges))
+	for i, r := range ranges {
+		converted[i] = TimeRange{
+			Open:  applyUtcOffset(r.Open, utcOffsetMinutes),
+			Close: applyUtcOffset(r.Close, utcOffsetMinutes),
+		}
+	}
+	return converted
+}
+
+// specialDaysFromOpeningHours builds the exceptional days flagged by Places in the current hours, with their real
+// hours taken from its dated periods; a special day without periods is a full-day closure.
+func specialDaysFromOpeningHours(hours OpeningHours, location *time.Location) []SpecialDay {
+	dates := map[Date]bool{}
+	for _, specialDay := range hours.SpecialDays {
+		dates[specialDay.Date] = true
+	}
+
+	specialDays := []SpecialDay{}
+	for date := range dates {
+		start := time.Date(date.Year, time.Month(date.Month), date.Day, 0, 0, 0, 0, location)
+		specialDay := SpecialDay{
+			Date:  start.Format(time.DateOnly),
+			Start: start.UTC(),
+			End:   start.AddDate(0, 0, 1).UTC(),
+			Hours: []DatedPeriod{},
+		}
+		for _, period := range hours.Periods {
+			if period.Open.Date == nil || *period.Open.Date != date {
+				continue
+			}
+			open := time.Date(date.Year, time.Month(date.Month), date.Day, period.Open.Hour, period.Open.Minute, 0, 0, location)
+			var close time.Time
+			if period.Close.Date != nil {
+				closeDate := period.Close.Date
+				close = time.Date(closeDate.Year, time.Month(closeDate.Month), closeDate.Day, period.Close.Hour, period.Close.Minute, 0, 0, location)
+			} else {
+				close = time.Date(date.Year, time.Month(date.Month), date.Day, period.Close.Hour, period.Close.Minute, 0, 0, location)
+				if !close.After(open) {
+					close = close.AddDate(0, 0, 1)
+				}
+			}
+			specialDay.Hours = append(specialDay.Hours, DatedPeriod{Open: open.UTC(), Close: close.UTC()})
+		}
+		specialDays = append(specialDays, specialDay)
+	}
+	sort.Slice(specialDays, func(i, j int) bool {
+		return specialDays[i].Start.Before(specialDays[j].Start)
+	})
+	return specialDays
+}
@@ -1,6 +1,11 @@
 package places
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
 
 func TestGetGooglePlacesFieldMask(t *testing.T) {
 	fields := []string{"displayName", "currentOpeningHours", "currentSecondaryOpeningHours", "regularOpeningHours", "regularSecondaryOpeningHours", "nationalPhoneNumber", "restroom"}
@@ -17,3 +22,139 @@ func TestGetGooglePlacesFieldMaskWithTextSearch(t *testing.T) {
 		t.Errorf("Expected field mask to be 'places.displayName,places.currentOpeningHours,places.currentSecondaryOpeningHours,places.regularOpeningHours,places.regularSecondaryOpeningHours,places.nationalPhoneNumber,places.restroom', but got '%s'", fieldMask)
 	}
 }
+
+func TestSpecialDaysFromOpeningHours(t *testing.T) {
+	christmas := Date{Year: 2026, Month: 12, Day: 25}
+	christmasEve := Date{Year: 2026, Month: 12, Day: 24}
+	place := Place{
+		// UTC-8, without a time zone so the offset is used
+		UtcOffsetMinutes: -480,
+		CurrentOpeningHours: OpeningHours{
+			Periods: []Period{
+				{
+					Open:  TimeSlot{Date: &christmasEve, Day: 4, Hour: 9, Minute: 0},
+					Close: TimeSlot{Date: &christmasEve, Day: 4, Hour: 14, Minute: 0},
+				},
+			},
+			SpecialDays: []SpecialDayRef{{Date: christmasEve}, {Date: christmas}},
+		},
+	}
+	specialDays := hoursFromPlace(place).SpecialDays
+	if len(specialDays) != 2 {
+		t.Fatalf("Expected 2 special days, but got %+v", specialDays)
+	}
+	eve := specialDays[0]
+	if eve.Date != "2026-12-24" || !eve.Start.Equal(time.Date(2026, 12, 24, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Christmas Eve starting at 08:00 UTC, but got %+v", eve)
+	}
+	if len(eve.Hours) != 1 || !eve.Hours[0].Open.Equal(time.Date(2026, 12, 24, 17, 0, 0, 0, time.UTC)) ||
+		!eve.Hours[0].Close.Equal(time.Date(2026, 12, 24, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Christmas Eve hours 17:00-22:00 UTC, but got %+v", eve.Hours)
+	}
+	if specialDays[1].Date != "2026-12-25" || len(specialDays[1].Hours) != 0 {
+		t.Errorf("Expected Christmas to be closed all day, but got %+v", specialDays[1])
+	}
+}
+
+func TestHoursFromPlace(t *testing.T) {
+	closedMonday := Date{Year: 2026, Month: 1, Day: 5}
+	place := Place{
+		UtcOffsetMinutes: -300,
+		RegularOpeningHours: OpeningHours{
+			Periods: []Period{
+				{Open: TimeSlot{Day: 1, Hour: 9, Minute: 0}, Close: TimeSlot{Day: 1, Hour: 17, Minute: 0}},
+			},
+		},
+		// Closed this Monday only, so the current hours of the coming week have no Monday period
+		CurrentOpeningHours: OpeningHours{
+			Periods:     []Period{},
+			SpecialDays: []SpecialDayRef{{Date: closedMonday}},
+		},
+	}
+
+	place.TimeZone = TimeZone{Id: "America/New_York"}
+	hours := hoursFromPlace(place)
+	if hours.TimeZone != "America/New_York" {
+		t.Errorf("Expected time zone America/New_York, but got %q", hours.TimeZone)
+	}
+	if len(hours.OpenHours) != 1 || hours.OpenHours[0].Open != (TimePoint{Weekday: 1, Hour: 9, Minute: 0}) {
+		t.Fatalf("Expected the regular Monday hours in local time, but got %+v", hours.OpenHours)
+	}
+	if len(hours.SpecialDays) != 1 || hours.SpecialDays[0].Date != "2026-01-05" || len(hours.SpecialDays[0].Hours) != 0 {
+		t.Errorf("Expected the closure only as a special day, but got %+v", hours.SpecialDays)
+	}
+
+	place.TimeZone = TimeZone{}
+	hours = hoursFromPlace(place)
+	if hours.TimeZone != "" {
+		t.Errorf("Expected no time zone, but got %q", hours.TimeZone)
+	}
+	if hours.OpenHours[0].Open != (TimePoint{Weekday: 1, Hour: 14, Minute: 0}) {
+		t.Errorf("Expected hours converted to UTC without a time zone, but got %+v", hours.OpenHours[0])
+	}
+}
+
+func TestLocalOpenHours(t *testing.T) {
+	newYork := "America/New_York"
+	offset := -300
+	localHours := []TimeRange{{Open: TimePoint{Weekday: 1, Hour: 9}, Close: TimePoint{Weekday: 1, Hour: 17}}}
+	utcHours := []TimeRange{{Open: TimePoint{Weekday: 1, Hour: 14}, Close: TimePoint{Weekday: 1, Hour: 22}}}
+
+	openHours, location := LocalOpenHours(Restaurant{OpenHours: localHours, TimeZone: &newYork, UtcOffsetMinutes: &offset})
+	if location.String() != newYork || openHours[0] != localHours[0] {
+		t.Errorf("Expected local hours in %s, but got %+v in %s", newYork, openHours, location)
+	}
+
+	openHours, location = LocalOpenHours(Restaurant{OpenHours: utcHours, UtcOffsetMinutes: &offset})
+	_, locationOffset := time.Date(2026, 1, 5, 0, 0, 0, 0, location).Zone()
+	if locationOffset != offset*60 || openHours[0] != localHours[0] {
+		t.Errorf("Expected legacy UTC hours shifted to UTC-5, but got %+v at offset %d", openHours, locationOffset)
+	}
+
+	openHours, location = LocalOpenHours(Restaurant{OpenHours: utcHours})
+	if location != time.UTC || openHours[0] != utcHours[0] {
+		t.Errorf("Expected legacy hours to stay in UTC, but got %+v in %s", openHours, location)
+	}
+}
+
+func TestShouldSkipEnrichment(t *testing.T) {
+	now := time.Date(2026, 1, 20, 12, 0, 0, 0, time.UTC)
+	fresh := now.Add(-24 * time.Hour)
+	stale := now.Add(-EnrichmentMaxAge - time.Hour)
+	tests := []struct {
+		status    EnrichmentStatus
+		updatedAt time.Time
+		force     bool
+		skip      bool
+		reason    string
+	}{
+		{EnrichmentStatusCompleted, fresh, false, true, "recently enriched"},
+		{EnrichmentStatusCompleted, fresh, true, false, ""},
+		{EnrichmentStatusCompleted, stale, false, false, ""},
+		{EnrichmentStatusQueued, fresh, false, true, "already queued"},
+		{EnrichmentStatusQueued, fresh, true, false, ""},
+		{EnrichmentStatusInProgress, fresh, true, true, "already in progress"},
+		{EnrichmentStatusFailed, fresh, false, false, ""},
+		{EnrichmentStatusCancelled, fresh, false, false, ""},
+	}
+	for _, test := range tests {
+		skip, reason := shouldSkipEnrichment(test.status, test.updatedAt, test.force, now)
+		if skip != test.skip || reason != test.reason {
+			t.Errorf("%s (force %v): expected skip %v %q, but got %v %q", test.status, test.force, test.skip, test.reason, skip, reason)
+		}
+	}
+}
+
+func TestFailedResult(t *testing.T) {
+	notFound := failedResult("place-1", fmt.Errorf("enrich: %w", ErrPlaceNotFound))
+	if notFound.Outcome != EnrichmentOutcomeNotFound || notFound.Error != "" || !notFound.Failed() {
+		t.Errorf("Expected a not found result, but got %+v", notFound)
+	}
+	failed := failedResult("place-2", errors.New("failed to get place details: quota exceeded"))
+	if failed.Outcome != EnrichmentOutcomeError || failed.Error != "failed to get place details: quota exceeded" || !failed.Failed() {
+		t.Errorf("Expected an error result with the message, but got %+v", failed)
+	}
+	if (EnrichmentResult{Outcome: EnrichmentOutcomeSkipped}).Failed() {
+		t.Errorf("Expected a skipped result not to count as failed")
+	}
+}
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many requests a store handles between deletions of idle buckets
+const sweepEvery = 1000
+
+// MemoryStore keeps buckets in this process
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]time.Time
+	takes   int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]time.Time{}}
+}
+
+func (m *MemoryStore) Take(key string, limit Limit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	tat, allowed, retryAfter := take(m.buckets[key], now, limit)
+	m.buckets[key] = tat
+
+	m.takes++
+	if m.takes%sweepEvery == 0 {
+		// A bucket whose tat has passed is full, the same as no bucket
+		for key, tat := range m.buckets {
+			if tat.Before(now) {
+				delete(m.buckets, key)
+			}
+		}
+	}
+	return allowed, retryAfter, nil
+}
+
+func (m *MemoryStore) Close() {}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"eatsavvy/pkg/db"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// errNoBucket means the bucket was deleted between refusing a request and looking up when to retry
+var errNoBucket = errors.New("rate limit bucket not found")
+
+// PostgresStore keeps buckets in the rate_limit_buckets table, so every API replica shares them
+type PostgresStore struct {
+	dbClient *db.DatabaseClient
+	takes    int
+}
+
+func NewPostgresStore() *PostgresStore {
+	return &PostgresStore{dbClient: db.NewDatabaseClient()}
+}
+
+// Take runs the same algorithm as take in a single statement, using the database's clock so replicas agree.
+// The conditional upsert returns no row when the request isn't allowed.
+func (p *PostgresStore) Take(key string, limit Limit) (bool, time.Duration, error) {
+	interval := limit.interval().Seconds()
+	tolerance := limit.tolerance().Seconds()
+
+	var tat time.Time
+	err := p.dbClient.Db.QueryRow(p.dbClient.Ctx,
+		`INSERT INTO public.rate_limit_buckets (key, tat) VALUES ($1, NOW() + make_interval(secs => $2))
+			ON CONFLICT (key) DO UPDATE SET tat = GREATEST(rate_limit_buckets.tat, NOW()) + make_interval(secs => $2)
+			WHERE rate_limit_buckets.tat - NOW() <= make_interval(secs => $3)
+			RETURNING tat`,
+		key, interval, tolerance,
+	).Scan(&tat)
+	if err == nil {
+		p.sweep()
+		return true, 0, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("[ratelimit.Take] Failed to take from bucket", "error", err, "key", key)
+		return false, 0, err
+	}
+
+	var waitSeconds float64
+	err = p.dbClient.Db.QueryRow(p.dbClient.Ctx,
+		`SELECT EXTRACT(EPOCH FROM tat - NOW())::float8 - $2 FROM public.rate_limit_buckets WHERE key = $1`,
+		key, tolerance,
+	).Scan(&waitSeconds)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, 0, errNoBucket
+	}
+	if err != nil {
+		slog.Error("[ratelimit.Take] Failed to get retry time", "error", err, "key", key)
+		return false, 0, err
+	}
+	return false, time.Duration(max(waitSeconds, 0) * float64(time.Second)), nil
+}
+
+// sweep occasionally deletes full buckets, which are the same as no bucket
+func (p *PostgresStore) sweep() {
+	p.takes++
+	if p.takes%sweepEvery != 0 {
+		return
+	}
+	_, err := p.dbClient.Db.Exec(p.dbClient.Ctx, `DELETE FROM public.rate_limit_buckets WHERE tat < NOW()`)
+	if err != nil {
+		slog.Error("[ratelimit.sweep] Failed to delete full buckets", "error", err)
+	}
+}
+
+func (p *PostgresStore) Close() {
+	p.dbClient.Close()
+}
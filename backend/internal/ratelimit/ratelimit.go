@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Class groups routes that share a limit, so expensive routes can be limited more tightly
+type Class string
+
+const (
+	// ClassIp applies to every request from an address, before authentication
+	ClassIp Class = "ip"
+	// ClassDefault applies to every authenticated request
+	ClassDefault Class = "default"
+	// ClassSearch applies to Places searches, on top of ClassDefault
+	ClassSearch Class = "search"
+	// ClassEnrich applies to requests that queue calls, on top of ClassDefault
+	ClassEnrich Class = "enrich"
+)
+
+// Limit allows Count requests per Period, in bursts of up to Count. A zero Count disables the limit.
+type Limit struct {
+	Count  int
+	Period time.Duration
+}
+
+// ParseLimit parses a limit like "30/m" (30 per minute), "5/s" or "1000/h". "off" disables the limit.
+func ParseLimit(value string) (Limit, error) {
+	if value == "off" {
+		return Limit{}, nil
+	}
+	count, unit, ok := strings.Cut(value, "/")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <count>/<s|m|h>", value)
+	}
+	periods := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+	period, ok := periods[unit]
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <count>/<s|m|h>", value)
+	}
+	return Limit{Count: n, Period: period}, nil
+}
+
+func (l Limit) Enabled() bool {
+	return l.Count > 0
+}
+
+// interval is the time it takes to earn one request back
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Count)
+}
+
+// tolerance is how far ahead of now a bucket's tat may be while still allowing a request, i.e. the burst
+func (l Limit) tolerance() time.Duration {
+	return l.interval() * time.Duration(l.Count-1)
+}
+
+// take applies one request at now to a bucket, using the generic cell rate algorithm: instead of a token count,
+// a bucket stores its theoretical arrival time (tat), the time at which it would be full again. This behaves
+// exactly like a token bucket but needs only one value, which is easy to update atomically in a shared store.
+// It returns the bucket's new tat if the request is allowed, or how long until it would be.
+func take(tat time.Time, now time.Time, limit Limit) (time.Time, bool, time.Duration) {
+	if tat.Before(now) {
+		tat = now
+	}
+	if wait := tat.Sub(now) - limit.tolerance(); wait > 0 {
+		return tat, false, wait
+	}
+	return tat.Add(limit.interval()), true, 0
+}
+
+// Store holds the buckets
+type Store interface {
+	// Take applies one request to the bucket for key, returning whether it is allowed and if not, when to retry
+	Take(key string, limit Limit) (bool, time.Duration, error)
+	Close()
+}
+
+// Limiter enforces per-class limits, read from RATE_LIMIT_IP, RATE_LIMIT_DEFAULT, RATE_LIMIT_SEARCH and
+// RATE_LIMIT_ENRICH. With RATE_LIMIT_STORE=postgres, buckets are shared by every replica through the database;
+// otherwise each replica limits on its own.
+type Limiter struct {
+	store  Store
+	limits map[Class]Limit
+}
+
+var defaultLimits = map[Class]string{
+	ClassIp:      "300/m",
+	ClassDefault: "120/m",
+	ClassSearch:  "30/m",
+	ClassEnrich:  "10/m",
+}
+
+func NewLimiter() *Limiter {
+	limits := map[Class]Limit{}
+	for class, fallback := range defaultLimits {
+		key := "RATE_LIMIT_" + strings.ToUpper(string(class))
+		value := os.Getenv(key)
+		if value == "" {
+			value = fallback
+		}
+		limit, err := ParseLimit(value)
+		if err != nil {
+			slog.Error("[ratelimit.NewLimiter] Invalid rate limit, using fallback", "key", key, "error", err, "fallback", fallback)
+			limit, _ = ParseLimit(fallback)
+		}
+		limits[class] = limit
+	}
+	var store Store = NewMemoryStore()
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		store = NewPostgresStore()
+	}
+	return &Limiter{store: store, limits: limits}
+}
+
+func (l *Limiter) Close() {
+	l.store.Close()
+}
+
+// Allow takes a request from subject's bucket for class, returning whether it is allowed and if not, when to
+// retry. If the store fails, requests are allowed rather than taking the API down with it.
+func (l *Limiter) Allow(class Class, subject string) (bool, time.Duration) {
+	limit := l.limits[class]
+	if !limit.Enabled() {
+		return true, 0
+	}
+	allowed, retryAfter, err := l.store.Take(string(class)+":"+subject, limit)
+	if err != nil {
+		slog.Error("[ratelimit.Allow] Failed to check rate limit, allowing request", "error", err, "class", class)
+		return true, 0
+	}
+	return allowed, retryAfter
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Limit
+		valid    bool
+	}{
+		{"30/m", Limit{Count: 30, Period: time.Minute}, true},
+		{"5/s", Limit{Count: 5, Period: time.Second}, true},
+		{"1000/h", Limit{Count: 1000, Period: time.Hour}, true},
+		{"off", Limit{}, true},
+		{"30", Limit{}, false},
+		{"0/m", Limit{}, false},
+		{"30/d", Limit{}, false},
+	}
+	for _, test := range tests {
+		limit, err := ParseLimit(test.value)
+		if (err == nil) != test.valid || limit != test.expected {
+			t.Errorf("%q: expected %+v (valid %v), but got %+v (%v)", test.value, test.expected, test.valid, limit, err)
+		}
+	}
+}
+
+func TestTake(t *testing.T) {
+	limit := Limit{Count: 3, Period: 3 * time.Second}
+	now := time.Date(2026, 1, 25, 16, 0, 0, 0, time.UTC)
+	var tat time.Time
+
+	// A full bucket allows a burst of Count requests
+	for i := range 3 {
+		var allowed bool
+		tat, allowed, _ = take(tat, now, limit)
+		if !allowed {
+			t.Fatalf("Expected request %d of the burst to be allowed", i+1)
+		}
+	}
+	_, allowed, retryAfter := take(tat, now, limit)
+	if allowed || retryAfter != time.Second {
+		t.Errorf("Expected the 4th request to wait 1s, but got allowed %v, retry after %v", allowed, retryAfter)
+	}
+
+	// One request is earned back per interval
+	_, allowed, _ = take(tat, now.Add(time.Second), limit)
+	if !allowed {
+		t.Errorf("Expected a request to be allowed after one interval")
+	}
+
+	// An idle bucket refills, but not beyond the burst
+	tat, _, _ = take(tat, now.Add(time.Hour), limit)
+	tat, _, _ = take(tat, now.Add(time.Hour), limit)
+	tat, _, _ = take(tat, now.Add(time.Hour), limit)
+	if _, allowed, _ = take(tat, now.Add(time.Hour), limit); allowed {
+		t.Errorf("Expected an idle bucket to refill to at most the burst")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Count: 2, Period: time.Hour}
+	for i, expected := range []bool{true, true, false} {
+		allowed, retryAfter, _ := store.Take("search:key:a", limit)
+		if allowed != expected || (!allowed && retryAfter <= 0) {
+			t.Errorf("Request %d: expected allowed %v, but got %v (retry after %v)", i+1, expected, allowed, retryAfter)
+		}
+	}
+	if allowed, _, _ := store.Take("search:key:b", limit); !allowed {
+		t.Errorf("Expected buckets to be separate per key")
+	}
+}
@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"cmp"
+	"context"
+	"eatsavvy/internal/config"
+	"eatsavvy/internal/places"
+	"eatsavvy/pkg/db"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Scheduler periodically re-enriches completed restaurants whose data is older than places.EnrichmentMaxAge,
+// most searched (then highest rated) first, enqueueing at most dailyBudget refreshes per UTC day.
+type Scheduler struct {
+	store       refreshStore
+	interval    time.Duration
+	dailyBudget int
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		store:       &postgresStore{dbClient: db.NewDatabaseClient()},
+		interval:    config.GetEnvDuration("REFRESH_SCHEDULE_INTERVAL", 1*time.Hour),
+		dailyBudget: config.GetEnvInt("REFRESH_DAILY_BUDGET", 20),
+	}
+}
+
+func (s *Scheduler) Close() {
+	s.store.close()
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	defer s.Close()
+	if s.dailyBudget <= 0 {
+		slog.Info("[scheduler.Start] Refresh budget is 0, scheduled re-enrichment disabled")
+		return
+	}
+	slog.Info("[scheduler.Start] Starting refresh scheduler", "interval", s.interval, "dailyBudget", s.dailyBudget)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		scheduled, err := s.scheduleRefreshes(ctx)
+		if err != nil {
+			slog.Error("[scheduler.Start] Failed to schedule refreshes", "error", err)
+		} else if scheduled > 0 {
+			slog.Info("[scheduler.Start] Scheduled refreshes", "count", scheduled)
+		}
+		select {
+		case <-ctx.Done():
+			slog.Info("[scheduler.Start] Stopping refresh scheduler")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type staleRestaurant struct {
+	placesId    string
+	name        string
+	status      places.EnrichmentStatus
+	updatedAt   time.Time
+	searchCount int
+	rating      *float64
+}
+
+func (s *Scheduler) scheduleRefreshes(ctx context.Context) (int, error) {
+	run, locked, err := s.store.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer run.rollback(ctx)
+	if !locked {
+		slog.Info("[scheduler.scheduleRefreshes] Another replica is scheduling refreshes, skipping")
+		return 0, nil
+	}
+
+	scheduledToday, err := run.scheduledToday(ctx)
+	if err != nil {
+		return 0, err
+	}
+	remaining := remainingBudget(s.dailyBudget, scheduledToday)
+	if remaining == 0 {
+		return 0, nil
+	}
+
+	candidates, err := run.staleCandidates(ctx)
+	if err != nil {
+		return 0, err
+	}
+	scheduled, err := run.queueRefreshes(ctx, selectRefreshes(candidates, remaining, time.Now()))
+	if err != nil {
+		return 0, err
+	}
+	if err = run.commit(ctx); err != nil {
+		return 0, err
+	}
+	return scheduled, nil
+}
+
+// selectRefreshes picks up to limit completed restaurants whose data is older than places.EnrichmentMaxAge, most
+// searched first, then highest rated (unrated last), then least recently enriched
+func selectRefreshes(candidates []staleRestaurant, limit int, now time.Time) []staleRestaurant {
+	selected := []staleRestaurant{}
+	for _, candidate := range candidates {
+		if candidate.status == places.EnrichmentStatusCompleted && candidate.updatedAt.Before(now.Add(-places.EnrichmentMaxAge)) {
+			selected = append(selected, candidate)
+		}
+	}
+	slices.SortStableFunc(selected, func(a, b staleRestaurant) int {
+		if a.searchCount != b.searchCount {
+			return cmp.Compare(b.searchCount, a.searchCount)
+		}
+		if (a.rating == nil) != (b.rating == nil) {
+			if a.rating == nil {
+				return 1
+			}
+			return -1
+		}
+		if a.rating != nil && *a.rating != *b.rating {
+			return cmp.Compare(*b.rating, *a.rating)
+		}
+		return a.updatedAt.Compare(b.updatedAt)
+	})
+	return selected[:min(limit, len(selected))]
+}
+
+func remainingBudget(dailyBudget int, scheduledToday int) int {
+	if scheduledToday >= dailyBudget {
+		return 0
+	}
+	return dailyBudget - scheduledToday
+}
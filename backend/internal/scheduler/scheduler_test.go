@@ -0,0 +1,133 @@
+package scheduler
+
+import (
+	"context"
+	"eatsavvy/internal/places"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRemainingBudget(t *testing.T) {
+	tests := []struct {
+		dailyBudget    int
+		scheduledToday int
+		want           int
+	}{
+		{dailyBudget: 20, scheduledToday: 0, want: 20},
+		{dailyBudget: 20, scheduledToday: 15, want: 5},
+		{dailyBudget: 20, scheduledToday: 20, want: 0},
+		{dailyBudget: 20, scheduledToday: 25, want: 0},
+	}
+
+	for _, tt := range tests {
+		got := remainingBudget(tt.dailyBudget, tt.scheduledToday)
+		if got != tt.want {
+			t.Errorf("remainingBudget(%d, %d) = %d, want %d", tt.dailyBudget, tt.scheduledToday, got, tt.want)
+		}
+	}
+}
+
+func TestSelectRefreshes(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	stale := now.Add(-places.EnrichmentMaxAge - time.Hour)
+	older := stale.Add(-24 * time.Hour)
+	high, low := 4.8, 3.9
+	candidates := []staleRestaurant{
+		{placesId: "fresh", status: places.EnrichmentStatusCompleted, updatedAt: now.Add(-time.Hour), searchCount: 99},
+		{placesId: "queued", status: places.EnrichmentStatusQueued, updatedAt: stale, searchCount: 99},
+		{placesId: "unrated", status: places.EnrichmentStatusCompleted, updatedAt: stale, searchCount: 5},
+		{placesId: "low", status: places.EnrichmentStatusCompleted, updatedAt: stale, searchCount: 5, rating: &low},
+		{placesId: "popular", status: places.EnrichmentStatusCompleted, updatedAt: stale, searchCount: 12, rating: &low},
+		{placesId: "high", status: places.EnrichmentStatusCompleted, updatedAt: stale, searchCount: 5, rating: &high},
+		{placesId: "high-older", status: places.EnrichmentStatusCompleted, updatedAt: older, searchCount: 5, rating: &high},
+	}
+
+	tests := []struct {
+		limit int
+		want  []string
+	}{
+		{limit: 10, want: []string{"popular", "high-older", "high", "low", "unrated"}},
+		{limit: 2, want: []string{"popular", "high-older"}},
+		{limit: 0, want: []string{}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, restaurant := range selectRefreshes(candidates, tt.limit, now) {
+			got = append(got, restaurant.placesId)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("selectRefreshes(limit %d) = %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+}
+
+// fakeStore is a refreshStore whose lock is either free or held by another replica
+type fakeStore struct {
+	lockHeld   bool
+	candidates []staleRestaurant
+	run        *fakeRun
+}
+
+func (f *fakeStore) begin(ctx context.Context) (refreshRun, bool, error) {
+	f.run = &fakeRun{candidates: f.candidates}
+	return f.run, !f.lockHeld, nil
+}
+
+func (f *fakeStore) close() {}
+
+type fakeRun struct {
+	candidates []staleRestaurant
+	read       bool
+	queued     []string
+	committed  bool
+	rolledBack bool
+}
+
+func (f *fakeRun) scheduledToday(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeRun) staleCandidates(ctx context.Context) ([]staleRestaurant, error) {
+	f.read = true
+	return f.candidates, nil
+}
+
+func (f *fakeRun) queueRefreshes(ctx context.Context, restaurants []staleRestaurant) (int, error) {
+	for _, restaurant := range restaurants {
+		f.queued = append(f.queued, restaurant.placesId)
+	}
+	return len(restaurants), nil
+}
+
+func (f *fakeRun) commit(ctx context.Context) error {
+	f.committed = true
+	return nil
+}
+
+func (f *fakeRun) rollback(ctx context.Context) {
+	f.rolledBack = true
+}
+
+func TestScheduleRefreshes(t *testing.T) {
+	candidates := []staleRestaurant{
+		{placesId: "stale", status: places.EnrichmentStatusCompleted, updatedAt: time.Now().Add(-places.EnrichmentMaxAge - time.Hour)},
+	}
+
+	store := &fakeStore{candidates: candidates}
+	scheduler := &Scheduler{store: store, dailyBudget: 20}
+	scheduled, err := scheduler.scheduleRefreshes(context.Background())
+	if err != nil || scheduled != 1 || !slices.Equal(store.run.queued, []string{"stale"}) || !store.run.committed {
+		t.Errorf("Expected the stale restaurant to be queued and committed, but got %d %v (%v)", scheduled, store.run.queued, err)
+	}
+
+	store = &fakeStore{lockHeld: true, candidates: candidates}
+	scheduler = &Scheduler{store: store, dailyBudget: 20}
+	scheduled, err = scheduler.scheduleRefreshes(context.Background())
+	if err != nil || scheduled != 0 {
+		t.Errorf("Expected nothing scheduled while another replica holds the lock, but got %d (%v)", scheduled, err)
+	}
+	if store.run.read || len(store.run.queued) > 0 || store.run.committed || !store.run.rolledBack {
+		t.Errorf("Expected the run to be skipped and rolled back while the lock is held, but got %+v", store.run)
+	}
+}
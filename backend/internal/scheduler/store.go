@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"context"
+	"eatsavvy/internal/enrichment"
+	"eatsavvy/internal/jobs"
+	"eatsavvy/internal/outbox"
+	"eatsavvy/internal/places"
+	"eatsavvy/pkg/db"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// refreshLockId is the Postgres advisory lock that makes sure only one worker replica schedules refreshes at a time
+const refreshLockId = 7461001
+
+// refreshStore is where the scheduler reads candidates and queues refreshes; postgresStore in production
+type refreshStore interface {
+	// begin starts a scheduling run and takes the scheduler lock. locked is false if another replica holds it,
+	// in which case the run must only be rolled back.
+	begin(ctx context.Context) (run refreshRun, locked bool, err error)
+	close()
+}
+
+// refreshRun is one scheduling run; nothing it queues is visible until commit
+type refreshRun interface {
+	// scheduledToday counts the refreshes scheduled since the start of the UTC day
+	scheduledToday(ctx context.Context) (int, error)
+	// staleCandidates returns the restaurants that may need refreshing (see selectRefreshes)
+	staleCandidates(ctx context.Context) ([]staleRestaurant, error)
+	// queueRefreshes queues the restaurants that are still completed and returns how many it queued
+	queueRefreshes(ctx context.Context, restaurants []staleRestaurant) (int, error)
+	commit(ctx context.Context) error
+	rollback(ctx context.Context)
+}
+
+type postgresStore struct {
+	dbClient *db.DatabaseClient
+}
+
+func (p *postgresStore) close() {
+	p.dbClient.Close()
+}
+
+// begin runs in a transaction holding pg_try_advisory_xact_lock, so the lock is released when the run ends
+func (p *postgresStore) begin(ctx context.Context) (refreshRun, bool, error) {
+	tx, err := p.dbClient.Db.Begin(ctx)
+	if err != nil {
+		slog.Error("[scheduler.begin] Failed to begin transaction", "error", err)
+		return nil, false, err
+	}
+	run := &postgresRun{tx: tx}
+	var locked bool
+	err = tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, refreshLockId).Scan(&locked)
+	if err != nil {
+		slog.Error("[scheduler.begin] Failed to take scheduler lock", "error", err)
+		tx.Rollback(ctx)
+		return nil, false, err
+	}
+	return run, locked, nil
+}
+
+type postgresRun struct {
+	tx pgx.Tx
+}
+
+func (r *postgresRun) scheduledToday(ctx context.Context) (int, error) {
+	var scheduledToday int
+	err := r.tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM public.restaurants WHERE refresh_scheduled_at >= date_trunc('day', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'`,
+	).Scan(&scheduledToday)
+	if err != nil {
+		slog.Error("[scheduler.scheduledToday] Failed to count today's refreshes", "error", err)
+		return 0, err
+	}
+	return scheduledToday, nil
+}
+
+func (r *postgresRun) staleCandidates(ctx context.Context) ([]staleRestaurant, error) {
+	rows, err := r.tx.Query(ctx,
+		`SELECT places_id, name, enrichment_status, updated_at, search_count, rating FROM public.restaurants
+			WHERE enrichment_status = $1 AND updated_at < NOW() - make_interval(secs => $2)`,
+		places.EnrichmentStatusCompleted, places.EnrichmentMaxAge.Seconds(),
+	)
+	if err != nil {
+		slog.Error("[scheduler.staleCandidates] Failed to query stale restaurants", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	stale := []staleRestaurant{}
+	for rows.Next() {
+		var restaurant staleRestaurant
+		err = rows.Scan(&restaurant.placesId, &restaurant.name, &restaurant.status, &restaurant.updatedAt,
+			&restaurant.searchCount, &restaurant.rating)
+		if err != nil {
+			slog.Error("[scheduler.staleCandidates] Failed to scan stale restaurant", "error", err)
+			return nil, err
+		}
+		stale = append(stale, restaurant)
+	}
+	return stale, rows.Err()
+}
+
+// queueRefreshes only queues restaurants that are still completed, since one may have been queued by a request
+// since the candidates were read
+func (r *postgresRun) queueRefreshes(ctx context.Context, restaurants []staleRestaurant) (int, error) {
+	// Refreshes scheduled in the same run share one enrichment job, so they can be followed and cancelled together
+	var enrichmentJobId string
+	queued := 0
+	for _, restaurant := range restaurants {
+		job := jobs.NewEnrichmentJob(restaurant.placesId, restaurant.name)
+		err := r.tx.QueryRow(ctx,
+			`UPDATE public.restaurants SET enrichment_status = $1, enrichment_job_id = $2, enrichment_job_due_at = NOW(),
+				refresh_scheduled_at = NOW() WHERE places_id = $3 AND enrichment_status = $4 RETURNING places_id`,
+			places.EnrichmentStatusQueued, job.Id, restaurant.placesId, places.EnrichmentStatusCompleted,
+		).Scan(&restaurant.placesId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			slog.Error("[scheduler.queueRefreshes] Failed to mark restaurant queued", "error", err, "places_id", restaurant.placesId)
+			return 0, err
+		}
+		if enrichmentJobId == "" {
+			enrichmentJobId, err = enrichment.CreateJob(ctx, r.tx, enrichment.Requester{Name: enrichment.RequestedByScheduler})
+			if err != nil {
+				return 0, err
+			}
+		}
+		err = outbox.Enqueue(ctx, r.tx, jobs.EnrichmentQueue, job)
+		if err != nil {
+			slog.Error("[scheduler.queueRefreshes] Failed to enqueue refresh job", "error", err, "places_id", restaurant.placesId)
+			return 0, err
+		}
+		err = enrichment.AddItem(ctx, r.tx, enrichmentJobId, restaurant.placesId, enrichment.StatusQueued, "")
+		if err != nil {
+			return 0, err
+		}
+		slog.Info("[scheduler.queueRefreshes] Scheduled refresh", "places_id", restaurant.placesId, "jobId", job.Id)
+		queued++
+	}
+	return queued, nil
+}
+
+func (r *postgresRun) commit(ctx context.Context) error {
+	if err := r.tx.Commit(ctx); err != nil {
+		slog.Error("[scheduler.commit] Failed to commit transaction", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (r *postgresRun) rollback(ctx context.Context) {
+	r.tx.Rollback(ctx)
+}
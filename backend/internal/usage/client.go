@@ -0,0 +1,75 @@
+package usage
+
+import (
+	"eatsavvy/pkg/db"
+	"log/slog"
+)
+
+type UsageClient struct {
+	dbClient *db.DatabaseClient
+	limits   Limits
+}
+
+// NewUsageClient reads the per-account limits from the environment (see LimitsFromEnv)
+func NewUsageClient() *UsageClient {
+	return &UsageClient{
+		dbClient: db.NewDatabaseClient(),
+		limits:   LimitsFromEnv(),
+	}
+}
+
+func (uc *UsageClient) Close() {
+	uc.dbClient.Close()
+}
+
+// GetUsage totals the account's ledger entries for the current UTC day and month
+func (uc *UsageClient) GetUsage(account Account) (Usage, error) {
+	usage := Usage{Account: account, ByKind: map[Kind]KindUsage{}, Limits: uc.limits}
+	err := uc.dbClient.Db.QueryRow(uc.dbClient.Ctx,
+		`SELECT date_trunc('day', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+			date_trunc('month', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'`,
+	).Scan(&usage.DayStart, &usage.MonthStart)
+	if err != nil {
+		slog.Error("[usage.GetUsage] Failed to get period start", "error", err)
+		return Usage{}, err
+	}
+
+	rows, err := uc.dbClient.Db.Query(uc.dbClient.Ctx,
+		`SELECT kind, COUNT(*) FILTER (WHERE created_at >= $3), COUNT(*), COALESCE(SUM(cost_usd), 0)::float8
+			FROM public.usage_ledger
+			WHERE created_at >= $4 AND key_id IS NOT DISTINCT FROM $1::uuid AND user_id IS NOT DISTINCT FROM $2::uuid
+			GROUP BY kind`,
+		account.KeyId, account.UserId, usage.DayStart, usage.MonthStart,
+	)
+	if err != nil {
+		slog.Error("[usage.GetUsage] Failed to total usage", "error", err)
+		return Usage{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind Kind
+		var today int
+		var month KindUsage
+		if err = rows.Scan(&kind, &today, &month.Count, &month.CostUsd); err != nil {
+			slog.Error("[usage.GetUsage] Failed to scan usage", "error", err)
+			return Usage{}, err
+		}
+		usage.ByKind[kind] = month
+		usage.CostUsdMonth += month.CostUsd
+		if kind == KindEnrichment {
+			usage.EnrichmentsToday = today
+			usage.EnrichmentsMonth = month.Count
+		}
+	}
+	return usage, nil
+}
+
+// Allow checks the account's usage against its limits (see Usage.Allow). It only screens requests up front: the
+// quotas are enforced as each restaurant is queued (see ReserveEnrichment).
+func (uc *UsageClient) Allow(account Account, enrichments int) error {
+	usage, err := uc.GetUsage(account)
+	if err != nil {
+		return err
+	}
+	return usage.Allow(enrichments)
+}
@@ -0,0 +1,207 @@
+package usage
+
+import (
+	"context"
+	"eatsavvy/internal/apperrors"
+	"eatsavvy/internal/config"
+	"eatsavvy/internal/enrichment"
+	pkgDb "eatsavvy/pkg/db"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Kind is what a ledger entry records
+type Kind string
+
+const (
+	// KindEnrichment is a restaurant queued for a call. It costs nothing itself (the call is KindVapiCall) but
+	// counts against the enrichment quotas.
+	KindEnrichment    Kind = "enrichment"
+	KindPlacesSearch  Kind = "places_search"
+	KindPlacesDetails Kind = "places_details"
+	KindVapiCall      Kind = "vapi_call"
+)
+
+var Kinds = []Kind{KindEnrichment, KindPlacesSearch, KindPlacesDetails, KindVapiCall}
+
+// ErrQuotaExceeded is a rate limit error without a retry delay, since quotas reset at the start of a UTC day or month
+var ErrQuotaExceeded = apperrors.New(apperrors.KindRateLimited, "quota exceeded")
+
+// Account is who usage is charged to: an API key or a signed in user. Usage by the scheduler has neither.
+type Account struct {
+	KeyId  *string `json:"keyId"`
+	UserId *string `json:"userId"`
+}
+
+// Costs are the prices of Places requests, which (unlike Vapi calls) aren't reported back to us
+type Costs struct {
+	PlacesSearchUsd  float64
+	PlacesDetailsUsd float64
+}
+
+// CostsFromEnv reads PLACES_SEARCH_COST_USD and PLACES_DETAILS_COST_USD, defaulting to Google's list prices
+func CostsFromEnv() Costs {
+	return Costs{
+		PlacesSearchUsd:  config.GetEnvFloat("PLACES_SEARCH_COST_USD", 0.032),
+		PlacesDetailsUsd: config.GetEnvFloat("PLACES_DETAILS_COST_USD", 0.017),
+	}
+}
+
+// Limits apply to each API key and each user separately. Zero means unlimited.
+type Limits struct {
+	DailyEnrichments   int     `json:"dailyEnrichments"`
+	MonthlyEnrichments int     `json:"monthlyEnrichments"`
+	MonthlyBudgetUsd   float64 `json:"monthlyBudgetUsd"`
+}
+
+// LimitsFromEnv reads ENRICH_DAILY_QUOTA, ENRICH_MONTHLY_QUOTA and MONTHLY_BUDGET_USD
+func LimitsFromEnv() Limits {
+	return Limits{
+		DailyEnrichments:   config.GetEnvInt("ENRICH_DAILY_QUOTA", 50),
+		MonthlyEnrichments: config.GetEnvInt("ENRICH_MONTHLY_QUOTA", 500),
+		MonthlyBudgetUsd:   config.GetEnvFloat("MONTHLY_BUDGET_USD", 100),
+	}
+}
+
+type KindUsage struct {
+	Count   int     `json:"count"`
+	CostUsd float64 `json:"costUsd"`
+}
+
+// Usage is an account's usage in the current UTC day and month
+type Usage struct {
+	Account          Account            `json:"account"`
+	DayStart         time.Time          `json:"dayStart"`
+	MonthStart       time.Time          `json:"monthStart"`
+	EnrichmentsToday int                `json:"enrichmentsToday"`
+	EnrichmentsMonth int                `json:"enrichmentsThisMonth"`
+	CostUsdMonth     float64            `json:"costUsdThisMonth"`
+	ByKind           map[Kind]KindUsage `json:"byKindThisMonth"`
+	Limits           Limits             `json:"limits"`
+}
+
+// Allow returns an error wrapping ErrQuotaExceeded if queueing another enrichments restaurants would go over the
+// account's quotas, or if it has spent its budget. Pass 0 to check only the budget, e.g. for a search.
+func (u Usage) Allow(enrichments int) error {
+	if u.Limits.MonthlyBudgetUsd > 0 && u.CostUsdMonth >= u.Limits.MonthlyBudgetUsd {
+		return fmt.Errorf("%w: spent $%.2f of the $%.2f monthly budget", ErrQuotaExceeded, u.CostUsdMonth, u.Limits.MonthlyBudgetUsd)
+	}
+	if enrichments == 0 {
+		return nil
+	}
+	if u.Limits.DailyEnrichments > 0 && u.EnrichmentsToday+enrichments > u.Limits.DailyEnrichments {
+		return fmt.Errorf("%w: %d of %d daily enrichments left", ErrQuotaExceeded, max(u.Limits.DailyEnrichments-u.EnrichmentsToday, 0), u.Limits.DailyEnrichments)
+	}
+	if u.Limits.MonthlyEnrichments > 0 && u.EnrichmentsMonth+enrichments > u.Limits.MonthlyEnrichments {
+		return fmt.Errorf("%w: %d of %d monthly enrichments left", ErrQuotaExceeded, max(u.Limits.MonthlyEnrichments-u.EnrichmentsMonth, 0), u.Limits.MonthlyEnrichments)
+	}
+	return nil
+}
+
+// DBTX is implemented by both *pgxpool.Pool and pgx.Tx, so usage can be recorded inside the caller's transaction
+type DBTX interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Record adds an entry to the ledger. placesId and reference (e.g. a search query) may be empty.
+func Record(ctx context.Context, db DBTX, account Account, kind Kind, placesId string, reference string, costUsd float64) error {
+	_, err := db.Exec(ctx,
+		`INSERT INTO public.usage_ledger (key_id, user_id, kind, places_id, reference, cost_usd)
+			VALUES ($1::uuid, $2::uuid, $3, NULLIF($4, ''), NULLIF($5, ''), $6)`,
+		account.KeyId, account.UserId, kind, placesId, reference, costUsd,
+	)
+	if err != nil {
+		slog.Error("[usage.Record] Failed to record usage", "error", err, "kind", kind)
+		return err
+	}
+	return nil
+}
+
+// RecordForJob adds an entry charged to whoever requested the enrichment job
+func RecordForJob(ctx context.Context, db DBTX, jobId string, kind Kind, placesId string, costUsd float64) error {
+	if !pkgDb.IsUUID(jobId) {
+		return apperrors.Validation("invalid enrichment job id")
+	}
+	_, err := db.Exec(ctx,
+		`INSERT INTO public.usage_ledger (key_id, user_id, enrichment_job_id, kind, places_id, cost_usd)
+			SELECT requested_by_key_id, requested_by_user_id, id, $2::text, $3::text, $4::numeric FROM public.enrichment_jobs WHERE id = $1::uuid`,
+		jobId, kind, placesId, costUsd,
+	)
+	if err != nil {
+		slog.Error("[usage.RecordForJob] Failed to record usage", "error", err, "kind", kind, "jobId", jobId)
+		return err
+	}
+	return nil
+}
+
+// ReserveEnrichment records a restaurant queued for the enrichment job jobId against the quotas of whoever requested
+// it, or returns an error wrapping ErrQuotaExceeded if that would go over their limits. It must run in the
+// transaction that queues the restaurant: it holds a per-account advisory lock until the transaction ends, so
+// concurrent batches for one account can't both take the last of its quota. Jobs requested by neither a key nor a
+// user (the scheduler) aren't limited.
+func ReserveEnrichment(ctx context.Context, tx DBTX, jobId string, placesId string, limits Limits) error {
+	if !pkgDb.IsUUID(jobId) {
+		return apperrors.Validation("invalid enrichment job id")
+	}
+	var account Account
+	err := tx.QueryRow(ctx,
+		`SELECT requested_by_key_id::text, requested_by_user_id::text FROM public.enrichment_jobs WHERE id = $1::uuid`,
+		jobId,
+	).Scan(&account.KeyId, &account.UserId)
+	if err != nil {
+		slog.Error("[usage.ReserveEnrichment] Failed to get job requester", "error", err, "jobId", jobId)
+		return err
+	}
+	if account.KeyId != nil || account.UserId != nil {
+		_, err = tx.Exec(ctx,
+			`SELECT pg_advisory_xact_lock(hashtextextended('usage:' || COALESCE('key:' || $1, 'user:' || $2), 0))`,
+			account.KeyId, account.UserId,
+		)
+		if err != nil {
+			slog.Error("[usage.ReserveEnrichment] Failed to lock account usage", "error", err, "jobId", jobId)
+			return err
+		}
+		usage := Usage{Account: account, Limits: limits}
+		err = tx.QueryRow(ctx,
+			`SELECT COUNT(*) FILTER (WHERE kind = $3 AND created_at >= date_trunc('day', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'),
+				COUNT(*) FILTER (WHERE kind = $3), COALESCE(SUM(cost_usd), 0)::float8
+				FROM public.usage_ledger
+				WHERE created_at >= date_trunc('month', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+					AND key_id IS NOT DISTINCT FROM $1::uuid AND user_id IS NOT DISTINCT FROM $2::uuid`,
+			account.KeyId, account.UserId, KindEnrichment,
+		).Scan(&usage.EnrichmentsToday, &usage.EnrichmentsMonth, &usage.CostUsdMonth)
+		if err != nil {
+			slog.Error("[usage.ReserveEnrichment] Failed to total usage", "error", err, "jobId", jobId)
+			return err
+		}
+		if err = usage.Allow(1); err != nil {
+			return err
+		}
+	}
+	return RecordForJob(ctx, tx, jobId, KindEnrichment, placesId, 0)
+}
+
+// RecordCall adds the cost of a call to the ledger, charged to whoever requested the job the restaurant is
+// currently being enriched for. It must run before the job item leaves in_progress.
+func RecordCall(ctx context.Context, db DBTX, placesId string, callId string, costUsd float64) error {
+	_, err := db.Exec(ctx,
+		`INSERT INTO public.usage_ledger (key_id, user_id, enrichment_job_id, kind, places_id, reference, cost_usd)
+			SELECT j.requested_by_key_id, j.requested_by_user_id, j.id, $2::text, $1::text, $3::text, $4::numeric
+			FROM (SELECT 1) one
+			LEFT JOIN public.enrichment_job_items i ON i.places_id = $1 AND i.status IN ($5, $6)
+			LEFT JOIN public.enrichment_jobs j ON j.id = i.job_id
+			ORDER BY i.created_at DESC NULLS LAST
+			LIMIT 1`,
+		placesId, KindVapiCall, callId, costUsd, enrichment.StatusQueued, enrichment.StatusInProgress,
+	)
+	if err != nil {
+		slog.Error("[usage.RecordCall] Failed to record call cost", "error", err, "places_id", placesId, "call_id", callId)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package usage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAllow(t *testing.T) {
+	limits := Limits{DailyEnrichments: 10, MonthlyEnrichments: 100, MonthlyBudgetUsd: 50}
+	tests := []struct {
+		name        string
+		usage       Usage
+		enrichments int
+		allowed     bool
+	}{
+		{"fresh account", Usage{Limits: limits}, 10, true},
+		{"over the daily quota", Usage{Limits: limits, EnrichmentsToday: 8, EnrichmentsMonth: 8}, 3, false},
+		{"fills the daily quota", Usage{Limits: limits, EnrichmentsToday: 8, EnrichmentsMonth: 8}, 2, true},
+		{"over the monthly quota", Usage{Limits: limits, EnrichmentsToday: 0, EnrichmentsMonth: 99}, 2, false},
+		{"budget spent", Usage{Limits: limits, CostUsdMonth: 50}, 0, false},
+		{"search within budget", Usage{Limits: limits, EnrichmentsToday: 10, CostUsdMonth: 49.99}, 0, true},
+		{"unlimited", Usage{EnrichmentsToday: 1000, CostUsdMonth: 1000}, 25, true},
+	}
+	for _, test := range tests {
+		err := test.usage.Allow(test.enrichments)
+		if (err == nil) != test.allowed {
+			t.Errorf("%s: expected allowed %v, but got %v", test.name, test.allowed, err)
+		}
+		if err != nil && !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("%s: expected ErrQuotaExceeded, but got %v", test.name, err)
+		}
+	}
+}
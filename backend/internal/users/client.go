@@ -0,0 +1,101 @@
+package users
+
+import (
+	"eatsavvy/pkg/db"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// lastSeenResolution is how stale last_seen_at may get, so authenticating doesn't write on every request
+const lastSeenResolution = time.Minute
+
+// maxCachedUsers bounds the users remembered between requests; the cache is emptied when it fills up
+const maxCachedUsers = 10000
+
+type UsersClient struct {
+	dbClient *db.DatabaseClient
+	seen     *seenUsers
+}
+
+func NewUsersClient() *UsersClient {
+	return &UsersClient{
+		dbClient: db.NewDatabaseClient(),
+		seen:     &seenUsers{users: map[string]seenUser{}},
+	}
+}
+
+func (uc *UsersClient) Close() {
+	uc.dbClient.Close()
+}
+
+// UpsertUser returns the user for issuer and subject, creating them on first sign in. The email and name
+// are refreshed from the token, since users can change them with the provider. Users seen within
+// lastSeenResolution with the same email and name are returned without touching the database.
+func (uc *UsersClient) UpsertUser(issuer string, subject string, email string, name string) (User, error) {
+	if user, ok := uc.seen.get(issuer, subject, email, name, time.Now()); ok {
+		return user, nil
+	}
+	user, err := scanUser(uc.dbClient.Db.QueryRow(uc.dbClient.Ctx,
+		`INSERT INTO public.users (issuer, subject, email, name) VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+			ON CONFLICT (issuer, subject) DO UPDATE SET
+				email = COALESCE(EXCLUDED.email, users.email),
+				name = COALESCE(EXCLUDED.name, users.name),
+				last_seen_at = CASE WHEN users.last_seen_at < NOW() - make_interval(secs => $5) THEN NOW() ELSE users.last_seen_at END
+			RETURNING id::text, issuer, subject, email, name, created_at, last_seen_at`,
+		issuer, subject, email, name, lastSeenResolution.Seconds(),
+	))
+	if err != nil {
+		slog.Error("[users.UpsertUser] Failed to upsert user", "error", err, "issuer", issuer, "subject", subject)
+		return User{}, err
+	}
+	uc.seen.put(user, time.Now())
+	return user, nil
+}
+
+type seenUser struct {
+	user   User
+	seenAt time.Time
+}
+
+// seenUsers remembers recently upserted users, keyed by issuer and subject
+type seenUsers struct {
+	mu    sync.Mutex
+	users map[string]seenUser
+}
+
+// get returns the user if they were upserted within lastSeenResolution of now and the token's email and name
+// wouldn't change them. Empty claims never change a user (see UpsertUser).
+func (s *seenUsers) get(issuer string, subject string, email string, name string, now time.Time) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen, ok := s.users[issuer+"\x00"+subject]
+	if !ok || now.Sub(seen.seenAt) >= lastSeenResolution {
+		return User{}, false
+	}
+	if (email != "" && (seen.user.Email == nil || *seen.user.Email != email)) ||
+		(name != "" && (seen.user.Name == nil || *seen.user.Name != name)) {
+		return User{}, false
+	}
+	return seen.user, true
+}
+
+func (s *seenUsers) put(user User, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.users) >= maxCachedUsers {
+		clear(s.users)
+	}
+	s.users[user.Issuer+"\x00"+user.Subject] = seenUser{user: user, seenAt: now}
+}
+
+func scanUser(row pgx.Row) (User, error) {
+	var user User
+	err := row.Scan(&user.Id, &user.Issuer, &user.Subject, &user.Email, &user.Name, &user.CreatedAt, &user.LastSeenAt)
+	if err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
@@ -0,0 +1,41 @@
+package users
+
+import (
+	"context"
+	"time"
+)
+
+// User is someone who signed in with the OIDC provider, identified by the token's issuer and subject
+type User struct {
+	Id         string    `json:"id"`
+	Issuer     string    `json:"issuer"`
+	Subject    string    `json:"subject"`
+	Email      *string   `json:"email"`
+	Name       *string   `json:"name"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+}
+
+// DisplayName is what the user's enrichment jobs are attributed to
+func (u User) DisplayName() string {
+	if u.Email != nil && *u.Email != "" {
+		return *u.Email
+	}
+	if u.Name != nil && *u.Name != "" {
+		return *u.Name
+	}
+	return u.Subject
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying the signed in user
+func NewContext(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// FromContext returns the user the request was authenticated as, if it was authenticated with a token
+func FromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(contextKey{}).(User)
+	return user, ok
+}
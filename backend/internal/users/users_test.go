@@ -0,0 +1,70 @@
+package users
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDisplayName(t *testing.T) {
+	email := "ada@example.com"
+	name := "Ada"
+	empty := ""
+	tests := []struct {
+		name     string
+		user     User
+		expected string
+	}{
+		{"email", User{Subject: "sub-1", Email: &email, Name: &name}, email},
+		{"name without email", User{Subject: "sub-1", Email: &empty, Name: &name}, name},
+		{"subject only", User{Subject: "sub-1"}, "sub-1"},
+	}
+	for _, test := range tests {
+		if displayName := test.user.DisplayName(); displayName != test.expected {
+			t.Errorf("%s: expected %q, but got %q", test.name, test.expected, displayName)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Errorf("Expected no user in an empty context")
+	}
+	ctx := NewContext(context.Background(), User{Id: "user-1"})
+	if user, ok := FromContext(ctx); !ok || user.Id != "user-1" {
+		t.Errorf("Expected user-1 from the context, but got %+v", user)
+	}
+}
+
+func TestSeenUsers(t *testing.T) {
+	email := "ada@example.com"
+	name := "Ada"
+	seen := &seenUsers{users: map[string]seenUser{}}
+	now := time.Now()
+	seen.put(User{Id: "user-1", Issuer: "https://issuer", Subject: "sub-1", Email: &email, Name: &name}, now)
+
+	tests := []struct {
+		name    string
+		subject string
+		email   string
+		display string
+		at      time.Time
+		found   bool
+	}{
+		{"same claims", "sub-1", email, name, now.Add(time.Second), true},
+		{"claims left out", "sub-1", "", "", now.Add(time.Second), true},
+		{"changed email", "sub-1", "ada@example.org", name, now.Add(time.Second), false},
+		{"changed name", "sub-1", email, "Ada L.", now.Add(time.Second), false},
+		{"last seen is stale", "sub-1", email, name, now.Add(lastSeenResolution), false},
+		{"first sight", "sub-2", email, name, now.Add(time.Second), false},
+	}
+	for _, test := range tests {
+		user, ok := seen.get("https://issuer", test.subject, test.email, test.display, test.at)
+		if ok != test.found {
+			t.Errorf("%s: expected found %v, but got %v", test.name, test.found, ok)
+		}
+		if ok && user.Id != "user-1" {
+			t.Errorf("%s: expected user-1, but got %+v", test.name, user)
+		}
+	}
+}
@@ -63,7 +63,7 @@ Close with a short thank-you and end the call promptly.`,
 				},
 			},
 			"server": map[string]interface{}{
-				"url":                      os.Getenv("EATSAVVY_API_URL") + "/process-eocr",
+				"url":                      os.Getenv("EATSAVVY_API_URL") + "/v1/process-eocr",
 				"staticIpAddressesEnabled": true,
 				"headers": map[string]string{
 					"Authorization": "Bearer " + os.Getenv("EATSAVVY_API_KEY"),
@@ -0,0 +1,140 @@
+package webhooks
+
+import (
+	"eatsavvy/pkg/db"
+	"log/slog"
+)
+
+// deliveryLogLimit is how many of a subscription's most recent deliveries ListDeliveries returns
+const deliveryLogLimit = 100
+
+type WebhooksClient struct {
+	dbClient *db.DatabaseClient
+}
+
+func NewWebhooksClient() *WebhooksClient {
+	return &WebhooksClient{
+		dbClient: db.NewDatabaseClient(),
+	}
+}
+
+func (wc *WebhooksClient) Close() {
+	wc.dbClient.Close()
+}
+
+// CreateSubscription stores a subscription, generating a secret if none was given. The returned subscription
+// includes the secret; it is not returned again.
+func (wc *WebhooksClient) CreateSubscription(subscription Subscription) (Subscription, error) {
+	err := ValidateSubscription(subscription)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if subscription.Secret == "" {
+		subscription.Secret, err = generateSecret()
+		if err != nil {
+			slog.Error("[webhooks.CreateSubscription] Failed to generate secret", "error", err)
+			return Subscription{}, err
+		}
+	}
+	err = wc.dbClient.Db.QueryRow(wc.dbClient.Ctx,
+		`INSERT INTO public.webhook_subscriptions (url, secret, event_types) VALUES ($1, $2, $3) RETURNING id::text, created_at`,
+		subscription.Url, subscription.Secret, eventTypeNames(subscription.EventTypes),
+	).Scan(&subscription.Id, &subscription.CreatedAt)
+	if err != nil {
+		slog.Error("[webhooks.CreateSubscription] Failed to insert subscription", "error", err)
+		return Subscription{}, err
+	}
+	slog.Info("[webhooks.CreateSubscription] Created webhook subscription", "id", subscription.Id, "url", subscription.Url)
+	return subscription, nil
+}
+
+func (wc *WebhooksClient) ListSubscriptions() ([]Subscription, error) {
+	rows, err := wc.dbClient.Db.Query(wc.dbClient.Ctx,
+		`SELECT id::text, url, event_types, created_at FROM public.webhook_subscriptions ORDER BY created_at`,
+	)
+	if err != nil {
+		slog.Error("[webhooks.ListSubscriptions] Failed to list subscriptions", "error", err)
+		return []Subscription{}, err
+	}
+	defer rows.Close()
+	subscriptions := []Subscription{}
+	for rows.Next() {
+		var subscription Subscription
+		var eventTypes []string
+		err = rows.Scan(&subscription.Id, &subscription.Url, &eventTypes, &subscription.CreatedAt)
+		if err != nil {
+			slog.Error("[webhooks.ListSubscriptions] Failed to scan subscription", "error", err)
+			return []Subscription{}, err
+		}
+		for _, eventType := range eventTypes {
+			subscription.EventTypes = append(subscription.EventTypes, EventType(eventType))
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, nil
+}
+
+// DeleteSubscription removes a subscription along with its delivery log
+func (wc *WebhooksClient) DeleteSubscription(id string) error {
+	tag, err := wc.dbClient.Db.Exec(wc.dbClient.Ctx,
+		`DELETE FROM public.webhook_subscriptions WHERE id::text = $1`,
+		id,
+	)
+	if err != nil {
+		slog.Error("[webhooks.DeleteSubscription] Failed to delete subscription", "error", err, "id", id)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSubscriptionNotFound
+	}
+	slog.Info("[webhooks.DeleteSubscription] Deleted webhook subscription", "id", id)
+	return nil
+}
+
+// ListDeliveries returns the most recent deliveries for a subscription, newest first
+func (wc *WebhooksClient) ListDeliveries(subscriptionId string) ([]Delivery, error) {
+	var exists bool
+	err := wc.dbClient.Db.QueryRow(wc.dbClient.Ctx,
+		`SELECT EXISTS (SELECT 1 FROM public.webhook_subscriptions WHERE id::text = $1)`,
+		subscriptionId,
+	).Scan(&exists)
+	if err != nil {
+		slog.Error("[webhooks.ListDeliveries] Failed to look up subscription", "error", err, "id", subscriptionId)
+		return []Delivery{}, err
+	}
+	if !exists {
+		return []Delivery{}, ErrSubscriptionNotFound
+	}
+
+	rows, err := wc.dbClient.Db.Query(wc.dbClient.Ctx,
+		`SELECT id, event_id::text, event_type, status, attempts, last_status_code, last_error, next_attempt_at, delivered_at, created_at, payload
+			FROM public.webhook_deliveries WHERE subscription_id::text = $1 ORDER BY created_at DESC, id DESC LIMIT $2`,
+		subscriptionId, deliveryLogLimit,
+	)
+	if err != nil {
+		slog.Error("[webhooks.ListDeliveries] Failed to list deliveries", "error", err, "id", subscriptionId)
+		return []Delivery{}, err
+	}
+	defer rows.Close()
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var delivery Delivery
+		err = rows.Scan(&delivery.Id, &delivery.EventId, &delivery.EventType, &delivery.Status, &delivery.Attempts,
+			&delivery.LastStatusCode, &delivery.LastError, &delivery.NextAttemptAt, &delivery.DeliveredAt, &delivery.CreatedAt,
+			&delivery.Payload)
+		if err != nil {
+			slog.Error("[webhooks.ListDeliveries] Failed to scan delivery", "error", err, "id", subscriptionId)
+			return []Delivery{}, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func eventTypeNames(eventTypes []EventType) []string {
+	names := make([]string, len(eventTypes))
+	for i, eventType := range eventTypes {
+		names[i] = string(eventType)
+	}
+	return names
+}
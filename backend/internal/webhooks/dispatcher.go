@@ -0,0 +1,188 @@
+package webhooks
+
+import (
+	"context"
+	"eatsavvy/internal/config"
+	"eatsavvy/pkg/backoff"
+	"eatsavvy/pkg/db"
+	"eatsavvy/pkg/http"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	dispatchBatchSize = 20
+	// leaseMargin is added to the time a batch can take to send, covering the database writes in between
+	leaseMargin = time.Minute
+)
+
+// retryBackoff is how long a delivery waits after failed attempts
+var retryBackoff = backoff.Exponential{Initial: 30 * time.Second, Max: 6 * time.Hour}
+
+// Dispatcher sends pending webhook deliveries, retrying failures with exponential backoff until maxAttempts
+type Dispatcher struct {
+	dbClient     *db.DatabaseClient
+	httpClient   *http.Http
+	pollInterval time.Duration
+	maxAttempts  int
+	// deliveryLease is how long a claimed delivery is hidden from other dispatchers; if a worker dies mid-delivery
+	// the delivery is retried once the lease runs out
+	deliveryLease time.Duration
+}
+
+func NewDispatcher() *Dispatcher {
+	timeout := config.GetEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+	return &Dispatcher{
+		dbClient: db.NewDatabaseClient(),
+		// Subscription URLs come from integrators, so only public addresses are dialed
+		httpClient:    http.NewPublicClientWithTimeout(timeout),
+		pollInterval:  config.GetEnvDuration("WEBHOOK_POLL_INTERVAL", 2*time.Second),
+		maxAttempts:   config.GetEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		deliveryLease: deliveryLease(timeout),
+	}
+}
+
+// deliveryLease outlasts a whole batch of deliveries timing out one after another, so a slow batch isn't claimed
+// again by another dispatcher while it is still being sent
+func deliveryLease(timeout time.Duration) time.Duration {
+	return dispatchBatchSize*timeout + leaseMargin
+}
+
+func (d *Dispatcher) Close() {
+	d.dbClient.Close()
+}
+
+func (d *Dispatcher) Start(ctx context.Context) {
+	slog.Info("[webhooks.Dispatcher.Start] Starting webhook dispatcher", "pollInterval", d.pollInterval, "maxAttempts", d.maxAttempts)
+	defer d.Close()
+
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	for {
+		sent, err := d.dispatchBatch(ctx)
+		if err != nil {
+			slog.Error("[webhooks.Dispatcher.Start] Failed to dispatch webhook batch", "error", err)
+		}
+		if sent == dispatchBatchSize && ctx.Err() == nil {
+			// There may be more pending deliveries, don't wait for the next tick
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			slog.Info("[webhooks.Dispatcher.Start] Stopping webhook dispatcher")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type pendingDelivery struct {
+	id        int64
+	eventType EventType
+	payload   []byte
+	attempts  int
+	url       string
+	secret    string
+}
+
+// dispatchBatch claims due deliveries by pushing their next attempt out by the delivery lease (SKIP LOCKED, so
+// several worker replicas can dispatch at once), then sends them outside of any transaction
+func (d *Dispatcher) dispatchBatch(ctx context.Context) (int, error) {
+	rows, err := d.dbClient.Db.Query(ctx,
+		`UPDATE public.webhook_deliveries d SET next_attempt_at = NOW() + make_interval(secs => $1)
+			FROM public.webhook_subscriptions s
+			WHERE s.id = d.subscription_id AND d.id IN (
+				SELECT id FROM public.webhook_deliveries WHERE status = $2 AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED
+			)
+			RETURNING d.id, d.event_type, d.payload, d.attempts, s.url, s.secret`,
+		d.deliveryLease.Seconds(), DeliveryStatusPending, dispatchBatchSize,
+	)
+	if err != nil {
+		slog.Error("[webhooks.Dispatcher.dispatchBatch] Failed to claim pending deliveries", "error", err)
+		return 0, err
+	}
+	claimed := []pendingDelivery{}
+	for rows.Next() {
+		var delivery pendingDelivery
+		err = rows.Scan(&delivery.id, &delivery.eventType, &delivery.payload, &delivery.attempts, &delivery.url, &delivery.secret)
+		if err != nil {
+			rows.Close()
+			slog.Error("[webhooks.Dispatcher.dispatchBatch] Failed to scan delivery", "error", err)
+			return 0, err
+		}
+		claimed = append(claimed, delivery)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		slog.Error("[webhooks.Dispatcher.dispatchBatch] Failed to claim pending deliveries", "error", err)
+		return 0, err
+	}
+
+	for _, delivery := range claimed {
+		statusCode, err := d.send(delivery)
+		err = d.recordAttempt(ctx, delivery, statusCode, err)
+		if err != nil {
+			return len(claimed), err
+		}
+	}
+	return len(claimed), nil
+}
+
+func (d *Dispatcher) send(delivery pendingDelivery) (int, error) {
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		HeaderEvent:     string(delivery.eventType),
+		HeaderDelivery:  fmt.Sprint(delivery.id),
+		HeaderSignature: Sign(delivery.secret, time.Now(), delivery.payload),
+	}
+	_, statusCode, err := d.httpClient.PostBytes(delivery.url, delivery.payload, headers)
+	if err != nil {
+		return 0, err
+	}
+	if statusCode < 200 || statusCode >= 300 {
+		return statusCode, fmt.Errorf("endpoint responded with status %d", statusCode)
+	}
+	return statusCode, nil
+}
+
+func (d *Dispatcher) recordAttempt(ctx context.Context, delivery pendingDelivery, statusCode int, sendErr error) error {
+	var lastStatusCode *int
+	if statusCode != 0 {
+		lastStatusCode = &statusCode
+	}
+	attempts := delivery.attempts + 1
+
+	if sendErr == nil {
+		_, err := d.dbClient.Db.Exec(ctx,
+			`UPDATE public.webhook_deliveries SET status = $1, attempts = $2, last_status_code = $3, last_error = NULL, delivered_at = NOW()
+				WHERE id = $4`,
+			DeliveryStatusDelivered, attempts, lastStatusCode, delivery.id,
+		)
+		if err != nil {
+			slog.Error("[webhooks.Dispatcher.recordAttempt] Failed to mark delivery delivered", "error", err, "id", delivery.id)
+			return err
+		}
+		slog.Info("[webhooks.Dispatcher.recordAttempt] Delivered webhook", "id", delivery.id, "eventType", delivery.eventType)
+		return nil
+	}
+
+	status := DeliveryStatusPending
+	if attempts >= d.maxAttempts {
+		status = DeliveryStatusFailed
+	}
+	slog.Error("[webhooks.Dispatcher.recordAttempt] Failed to deliver webhook", "error", sendErr, "id", delivery.id,
+		"attempts", attempts, "status", status)
+	_, err := d.dbClient.Db.Exec(ctx,
+		`UPDATE public.webhook_deliveries SET status = $1, attempts = $2, last_status_code = $3, last_error = $4,
+			next_attempt_at = NOW() + make_interval(secs => $5) WHERE id = $6`,
+		status, attempts, lastStatusCode, sendErr.Error(), retryBackoff.Delay(attempts).Seconds(), delivery.id,
+	)
+	if err != nil {
+		slog.Error("[webhooks.Dispatcher.recordAttempt] Failed to record failed delivery", "error", err, "id", delivery.id)
+		return errors.Join(sendErr, err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"eatsavvy/internal/apperrors"
+	"eatsavvy/pkg/http"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type EventType string
+
+const (
+	EventEnrichmentCompleted EventType = "restaurant.enrichment.completed"
+	EventEnrichmentFailed    EventType = "restaurant.enrichment.failed"
+)
+
+// EventTypes are the events a subscription can ask for
+var EventTypes = []EventType{EventEnrichmentCompleted, EventEnrichmentFailed}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusDelivered DeliveryStatus = "delivered"
+	DeliveryStatusFailed    DeliveryStatus = "failed"
+)
+
+const (
+	// HeaderEvent, HeaderDelivery and HeaderSignature are sent with every callback
+	HeaderEvent     = "X-EatSavvy-Event"
+	HeaderDelivery  = "X-EatSavvy-Delivery"
+	HeaderSignature = "X-EatSavvy-Signature"
+)
+
+var ErrSubscriptionNotFound = apperrors.NotFound("webhook subscription not found")
+
+type Subscription struct {
+	Id         string      `json:"id"`
+	Url        string      `json:"url"`
+	Secret     string      `json:"secret,omitempty"` // only returned when the subscription is created
+	EventTypes []EventType `json:"eventTypes"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
+
+type Delivery struct {
+	Id             int64           `json:"id"`
+	EventId        string          `json:"eventId"`
+	EventType      EventType       `json:"eventType"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode *int            `json:"lastStatusCode"`
+	LastError      *string         `json:"lastError"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+// RestaurantEvent is the data of an enrichment event. The callback body wraps it as
+// {"id": <event id>, "type": <event type>, "createdAt": <time>, "data": <RestaurantEvent>}.
+type RestaurantEvent struct {
+	RestaurantId     string `json:"restaurantId"`
+	Name             string `json:"name"`
+	EnrichmentStatus string `json:"enrichmentStatus"`
+	NutritionInfo    any    `json:"nutritionInfo,omitempty"`
+}
+
+// Enqueue records a delivery of the event for every subscription to eventType as part of tx, so callbacks are
+// only sent for changes that commit. The worker's Dispatcher sends them.
+func Enqueue(ctx context.Context, tx pgx.Tx, eventType EventType, data RestaurantEvent) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		slog.Error("[webhooks.Enqueue] Failed to encode event", "error", err)
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		`WITH event AS (SELECT gen_random_uuid() AS id)
+		INSERT INTO public.webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT s.id, event.id, $1, jsonb_build_object('id', event.id, 'type', $1::text, 'createdAt', NOW(), 'data', $2::jsonb)
+			FROM public.webhook_subscriptions s, event WHERE $1 = ANY(s.event_types)`,
+		string(eventType), string(payload),
+	)
+	if err != nil {
+		slog.Error("[webhooks.Enqueue] Failed to insert webhook deliveries", "error", err, "eventType", eventType)
+		return err
+	}
+	return nil
+}
+
+// Sign returns the signature header for a callback body sent at timestamp: "t=<unix seconds>,v1=<hex HMAC-SHA256
+// of "<unix seconds>.<body>" keyed with the subscription secret>". Receivers should recompute it and reject
+// stale timestamps to prevent replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(body)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateSubscription checks a subscription's URL and event types before it is stored. URLs naming an internal
+// address are refused up front; the dispatcher checks the address it actually connects to as well, since a name
+// can resolve anywhere.
+func ValidateSubscription(subscription Subscription) error {
+	parsed, err := url.Parse(subscription.Url)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" {
+		return apperrors.Newf(apperrors.KindValidation, "invalid url %q: expected an absolute https URL", subscription.Url)
+	}
+	if ip, err := netip.ParseAddr(parsed.Hostname()); (err == nil && !http.IsPublicIP(ip)) || parsed.Hostname() == "localhost" {
+		return apperrors.Newf(apperrors.KindValidation, "invalid url %q: expected a public address", subscription.Url)
+	}
+	if len(subscription.EventTypes) == 0 {
+		return apperrors.Validation("at least one event type is required")
+	}
+	for _, eventType := range subscription.EventTypes {
+		if !slices.Contains(EventTypes, eventType) {
+			return apperrors.Newf(apperrors.KindValidation, "unknown event type %q", eventType)
+		}
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
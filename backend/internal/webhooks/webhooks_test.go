@@ -0,0 +1,106 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"1","type":"restaurant.enrichment.completed"}`)
+	timestamp := time.Unix(1767225600, 0)
+	signature := Sign("whsec_test", timestamp, body)
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1767225600." + string(body)))
+	want := "t=1767225600,v1=" + hex.EncodeToString(mac.Sum(nil))
+	if signature != want {
+		t.Errorf("Sign() = %q, want %q", signature, want)
+	}
+	if Sign("other", timestamp, body) == signature {
+		t.Errorf("Expected a different secret to give a different signature")
+	}
+	if Sign("whsec_test", timestamp.Add(time.Second), body) == signature {
+		t.Errorf("Expected a different timestamp to give a different signature")
+	}
+}
+
+func TestValidateSubscription(t *testing.T) {
+	tests := []struct {
+		name         string
+		subscription Subscription
+		wantErr      string
+	}{
+		{
+			name:         "valid",
+			subscription: Subscription{Url: "https://example.com/hooks", EventTypes: []EventType{EventEnrichmentCompleted}},
+		},
+		{
+			name:         "relative url",
+			subscription: Subscription{Url: "/hooks", EventTypes: []EventType{EventEnrichmentCompleted}},
+			wantErr:      "invalid url",
+		},
+		{
+			name:         "unsupported scheme",
+			subscription: Subscription{Url: "ftp://example.com", EventTypes: []EventType{EventEnrichmentCompleted}},
+			wantErr:      "invalid url",
+		},
+		{
+			name:         "plain http",
+			subscription: Subscription{Url: "http://example.com/hooks", EventTypes: []EventType{EventEnrichmentCompleted}},
+			wantErr:      "expected an absolute https URL",
+		},
+		{
+			name:         "loopback",
+			subscription: Subscription{Url: "https://127.0.0.1:8080/hooks", EventTypes: []EventType{EventEnrichmentCompleted}},
+			wantErr:      "expected a public address",
+		},
+		{
+			name:         "localhost",
+			subscription: Subscription{Url: "https://localhost/hooks", EventTypes: []EventType{EventEnrichmentCompleted}},
+			wantErr:      "expected a public address",
+		},
+		{
+			name:         "cloud metadata",
+			subscription: Subscription{Url: "https://169.254.169.254/latest", EventTypes: []EventType{EventEnrichmentCompleted}},
+			wantErr:      "expected a public address",
+		},
+		{
+			name:         "private ipv6",
+			subscription: Subscription{Url: "https://[fd00::1]/hooks", EventTypes: []EventType{EventEnrichmentCompleted}},
+			wantErr:      "expected a public address",
+		},
+		{
+			name:         "no event types",
+			subscription: Subscription{Url: "https://example.com/hooks"},
+			wantErr:      "at least one event type",
+		},
+		{
+			name:         "unknown event type",
+			subscription: Subscription{Url: "https://example.com/hooks", EventTypes: []EventType{"restaurant.deleted"}},
+			wantErr:      "unknown event type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSubscription(tt.subscription)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("ValidateSubscription() error = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ValidateSubscription() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeliveryLease(t *testing.T) {
+	timeout := 10 * time.Second
+	if lease := deliveryLease(timeout); lease < dispatchBatchSize*timeout {
+		t.Errorf("deliveryLease(%v) = %v, shorter than a batch of %d timeouts", timeout, lease, dispatchBatchSize)
+	}
+}
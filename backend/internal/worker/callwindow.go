@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"eatsavvy/internal/config"
+	"eatsavvy/internal/openhours"
+	"eatsavvy/internal/places"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// callSearchStep and callSearchHorizon bound the search for the next acceptable call time
+const (
+	callSearchStep    = 5 * time.Minute
+	callSearchHorizon = 8 * 24 * time.Hour
+)
+
+// LocalWindow is a time-of-day range in the restaurant's local time, in minutes since midnight
+type LocalWindow struct {
+	Start int
+	End   int
+}
+
+func (lw LocalWindow) contains(t time.Time) bool {
+	mins := t.Hour()*60 + t.Minute()
+	return mins >= lw.Start && mins < lw.End
+}
+
+// CallWindowPolicy decides when during opening hours we may call a restaurant, so that we don't ring
+// during meal rushes, right after opening or just before close.
+type CallWindowPolicy struct {
+	// AvoidWindows are local times we never call in (e.g. lunch and dinner rush)
+	AvoidWindows []LocalWindow
+	// PreferredWindow, when set, is where callbacks are scheduled if the restaurant is callable in it that day
+	PreferredWindow *LocalWindow
+	// MinAfterOpen and MinBeforeClose keep calls away from opening and closing time
+	MinAfterOpen   time.Duration
+	MinBeforeClose time.Duration
+}
+
+// NewCallWindowPolicy reads the policy from CALL_AVOID_WINDOWS, CALL_PREFERRED_WINDOW (comma-separated
+// "HH:MM-HH:MM" local times, empty to disable), CALL_MIN_AFTER_OPEN and CALL_MIN_BEFORE_CLOSE (durations).
+func NewCallWindowPolicy() CallWindowPolicy {
+	policy := CallWindowPolicy{
+		AvoidWindows:   parseWindowsEnv("CALL_AVOID_WINDOWS", "11:30-13:30,17:30-20:00"),
+		MinAfterOpen:   config.GetEnvDuration("CALL_MIN_AFTER_OPEN", 30*time.Minute),
+		MinBeforeClose: config.GetEnvDuration("CALL_MIN_BEFORE_CLOSE", 30*time.Minute),
+	}
+	preferred := parseWindowsEnv("CALL_PREFERRED_WINDOW", "14:00-16:30")
+	if len(preferred) > 0 {
+		policy.PreferredWindow = &preferred[0]
+	}
+	return policy
+}
+
+func parseWindowsEnv(key string, fallback string) []LocalWindow {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		value = fallback
+	}
+	windows, err := parseWindows(value)
+	if err != nil {
+		slog.Error("[worker.parseWindowsEnv] Invalid call windows, using default", "key", key, "value", value, "error", err)
+		windows, _ = parseWindows(fallback)
+	}
+	return windows
+}
+
+func parseWindows(value string) ([]LocalWindow, error) {
+	windows := []LocalWindow{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var startHour, startMinute, endHour, endMinute int
+		_, err := fmt.Sscanf(part, "%d:%d-%d:%d", &startHour, &startMinute, &endHour, &endMinute)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", part, err)
+		}
+		window := LocalWindow{Start: startHour*60 + startMinute, End: endHour*60 + endMinute}
+		if window.Start >= window.End {
+			return nil, fmt.Errorf("invalid window %q: start must be before end", part)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// canCallAt reports whether t is an acceptable time to call: open for at least MinAfterOpen already
+// and MinBeforeClose still to go, and outside every avoided window in the restaurant's local time
+func (p CallWindowPolicy) canCallAt(openHours []places.TimeRange, specialDays []places.SpecialDay, location *time.Location, t time.Time) bool {
+	if !openhours.IsOpenAt(openHours, specialDays, location, t) {
+		return false
+	}
+	if p.MinAfterOpen > 0 && !openhours.IsOpenAt(openHours, specialDays, location, t.Add(-p.MinAfterOpen)) {
+		return false
+	}
+	if p.MinBeforeClose > 0 && !openhours.IsOpenAt(openHours, specialDays, location, t.Add(p.MinBeforeClose-time.Minute)) {
+		return false
+	}
+	local := t.In(location)
+	for _, window := range p.AvoidWindows {
+		if window.contains(local) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextCallDelay returns how long to wait until the next acceptable call time. The earliest acceptable time is
+// used unless a time inside PreferredWindow is available later that same local day.
+// If nothing is acceptable within the search horizon it falls back to the next opening.
+func (p CallWindowPolicy) nextCallDelay(openHours []places.TimeRange, specialDays []places.SpecialDay, location *time.Location, now time.Time) time.Duration {
+	start := now.Truncate(callSearchStep).Add(callSearchStep)
+	var earliest time.Time
+	for t := start; t.Before(now.Add(callSearchHorizon)); t = t.Add(callSearchStep) {
+		if !p.canCallAt(openHours, specialDays, location, t) {
+			continue
+		}
+		if earliest.IsZero() {
+			earliest = t
+			if p.PreferredWindow == nil || p.PreferredWindow.contains(t.In(location)) {
+				return t.Sub(now)
+			}
+		}
+		if p.PreferredWindow != nil && p.PreferredWindow.contains(t.In(location)) {
+			return t.Sub(now)
+		}
+		if !sameLocalDay(earliest, t, location) {
+			break
+		}
+	}
+	if !earliest.IsZero() {
+		return earliest.Sub(now)
+	}
+	return getCallbackTimeAt(openHours, specialDays, location, now)
+}
+
+func sameLocalDay(a time.Time, b time.Time, location *time.Location) bool {
+	aYear, aMonth, aDay := a.In(location).Date()
+	bYear, bMonth, bDay := b.In(location).Date()
+	return aYear == bYear && aMonth == bMonth && aDay == bDay
+}
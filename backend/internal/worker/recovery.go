@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"eatsavvy/internal/config"
+	"eatsavvy/internal/jobs"
+	"eatsavvy/internal/outbox"
+	"eatsavvy/internal/places"
+	"log/slog"
+	"time"
+)
+
+type lostJob struct {
+	placesId string
+	name     string
+}
+
+// recoverLostJobs re-enqueues restaurants that are queued but whose job should have come back by now
+// and isn't waiting in the outbox, e.g. because it was dropped by a broker restart before queues were durable.
+// The new job replaces the restaurant's enrichment_job_id, so if the old job does turn up it is skipped.
+func (w *Worker) recoverLostJobs() error {
+	grace := config.GetEnvDuration("JOB_RECOVERY_GRACE", 15*time.Minute)
+
+	tx, err := w.dbClient.Db.Begin(w.dbClient.Ctx)
+	if err != nil {
+		slog.Error("[worker.recoverLostJobs] Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback(w.dbClient.Ctx)
+
+	// Row locks keep other worker replicas that start at the same time from recovering the same restaurants
+	rows, err := tx.Query(w.dbClient.Ctx,
+		`SELECT r.places_id, r.name FROM public.restaurants r
+			WHERE r.enrichment_status = $1
+			AND (r.enrichment_job_due_at IS NULL OR r.enrichment_job_due_at < NOW() - make_interval(secs => $2))
+			AND NOT EXISTS (
+				SELECT 1 FROM public.outbox o WHERE o.message_id = r.enrichment_job_id AND o.published_at IS NULL
+			)
+			FOR UPDATE OF r SKIP LOCKED`,
+		places.EnrichmentStatusQueued, grace.Seconds(),
+	)
+	if err != nil {
+		slog.Error("[worker.recoverLostJobs] Failed to query queued restaurants", "error", err)
+		return err
+	}
+	lost := []lostJob{}
+	for rows.Next() {
+		var restaurant lostJob
+		err = rows.Scan(&restaurant.placesId, &restaurant.name)
+		if err != nil {
+			rows.Close()
+			slog.Error("[worker.recoverLostJobs] Failed to scan queued restaurant", "error", err)
+			return err
+		}
+		lost = append(lost, restaurant)
+	}
+	rows.Close()
+
+	for _, restaurant := range lost {
+		job := jobs.NewEnrichmentJob(restaurant.placesId, restaurant.name)
+		_, err = tx.Exec(w.dbClient.Ctx,
+			`UPDATE public.restaurants SET enrichment_job_id = $1, enrichment_job_due_at = NOW() WHERE places_id = $2`,
+			job.Id, restaurant.placesId,
+		)
+		if err != nil {
+			slog.Error("[worker.recoverLostJobs] Failed to update job id", "error", err, "places_id", restaurant.placesId)
+			return err
+		}
+		err = outbox.Enqueue(w.dbClient.Ctx, tx, jobs.EnrichmentQueue, job)
+		if err != nil {
+			slog.Error("[worker.recoverLostJobs] Failed to enqueue job", "error", err, "places_id", restaurant.placesId)
+			return err
+		}
+		slog.Info("[worker.recoverLostJobs] Re-enqueued lost job", "places_id", restaurant.placesId, "jobId", job.Id)
+	}
+
+	if err = tx.Commit(w.dbClient.Ctx); err != nil {
+		slog.Error("[worker.recoverLostJobs] Failed to commit transaction", "error", err)
+		return err
+	}
+	slog.Info("[worker.recoverLostJobs] Recovered lost jobs", "count", len(lost))
+	return nil
+}
@@ -2,41 +2,50 @@ package worker
 
 import (
 	"context"
+	"eatsavvy/internal/config"
+	"eatsavvy/internal/enrichment"
+	"eatsavvy/internal/jobs"
+	"eatsavvy/internal/openhours"
 	"eatsavvy/internal/places"
 	"eatsavvy/internal/vapi"
+	"eatsavvy/internal/webhooks"
 	"eatsavvy/pkg/db"
-	"eatsavvy/pkg/encoder"
 	"eatsavvy/pkg/queue"
 
+	"errors"
 	"log/slog"
 	"time"
 
-	"github.com/rabbitmq/amqp091-go"
+	"github.com/jackc/pgx/v5"
 )
 
 type Worker struct {
-	consumer   *queue.Consumer
-	publisher  *queue.Publisher
-	vapiClient *vapi.VapiClient
-	dbClient   *db.DatabaseClient
+	queue        queue.Queue
+	vapiClient   *vapi.VapiClient
+	dbClient     *db.DatabaseClient
+	placesClient *places.PlacesClient
+	callPolicy   CallWindowPolicy
+	// openHoursMaxAge is how old stored open hours may be before they are refreshed from Places; 0 disables refreshing
+	openHoursMaxAge time.Duration
 }
 
 func NewWorker() *Worker {
-	consumer := queue.NewConsumer("enrich_restaurant_details")
-	publisher := queue.NewPublisher("enrich_restaurant_details")
+	enrichmentQueue := queue.New(jobs.EnrichmentQueue)
 	vapiClient := vapi.NewVapiClient()
 	dbClient := db.NewDatabaseClient()
+	placesClient := places.NewPlacesClient()
 	return &Worker{
-		consumer:   consumer,
-		publisher:  publisher,
-		vapiClient: vapiClient,
-		dbClient:   dbClient,
+		queue:           enrichmentQueue,
+		vapiClient:      vapiClient,
+		dbClient:        dbClient,
+		placesClient:    placesClient,
+		callPolicy:      NewCallWindowPolicy(),
+		openHoursMaxAge: config.GetEnvDuration("OPEN_HOURS_MAX_AGE", 72*time.Hour),
 	}
 }
 
 func (w *Worker) Close() {
-	w.consumer.Close()
-	w.publisher.Close()
+	w.queue.Close()
 	w.dbClient.Close()
 }
 
@@ -47,7 +56,12 @@ func (w *Worker) Start() {
 	defer cancel()
 	defer w.Close()
 
-	msgs, err := w.consumer.ConsumeMessages(ctx)
+	err := w.recoverLostJobs()
+	if err != nil {
+		slog.Error("[worker.Start] Failed to recover lost jobs", "error", err)
+	}
+
+	msgs, err := w.queue.ConsumeMessages(ctx)
 	if err != nil {
 		slog.Error("[worker.Start] Failed to consume messages", "error", err)
 		return
@@ -57,11 +71,11 @@ func (w *Worker) Start() {
 
 	go func() {
 		for msg := range msgs {
-			restaurant, err := w.processMessage(msg)
+			job, err := w.processMessage(msg)
 			if err != nil {
-				slog.Error("[worker.processMessages] Failed to process message", "error", err)
-				if restaurant.Id != "" {
-					err = w.handleFailure(restaurant.Id)
+				slog.Error("[worker.processMessages] Failed to process message", "error", err, "jobId", job.Id, "traceId", job.TraceId)
+				if job.RestaurantId != "" {
+					err = w.handleFailure(job.RestaurantId)
 					if err != nil {
 						slog.Error("[worker.processMessages] Failed to handle failure", "error", err)
 					}
@@ -69,7 +83,7 @@ func (w *Worker) Start() {
 					slog.Error("[worker.processMessages] Failed to get restaurant ID", "error", err)
 				}
 			}
-			msg.Ack(false)
+			msg.Ack()
 		}
 		slog.Info("[worker.processMessages] Waiting for more messages...")
 	}()
@@ -79,34 +93,64 @@ func (w *Worker) Start() {
 	<-forever
 }
 
-func (w *Worker) processMessage(msg amqp091.Delivery) (places.Restaurant, error) {
-	var restaurant places.Restaurant
-	err := encoder.FromBytes(msg.Body, &restaurant)
+func (w *Worker) processMessage(msg queue.Delivery) (jobs.Job, error) {
+	job, err := jobs.Decode(msg.Body, msg.ContentType)
 	if err != nil {
-		slog.Error("[worker.processMessage] Failed to unmarshal message", "error", err)
-		return places.Restaurant{}, err
+		slog.Error("[worker.processMessage] Failed to decode job", "error", err)
+		return jobs.Job{}, err
+	}
+	slog.Info("[worker.processMessage] Processing job", "jobId", job.Id, "restaurantId", job.RestaurantId,
+		"attempt", job.Attempt, "schemaVersion", job.SchemaVersion, "traceId", job.TraceId)
+	// Always act on the current row rather than what was known at enqueue time; delayed jobs can sit for days
+	restaurant, err := w.getRestaurant(job.RestaurantId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		slog.Info("[worker.processMessage] Restaurant no longer exists, skipping job", "jobId", job.Id, "restaurantId", job.RestaurantId)
+		return job, nil
+	}
+	if err != nil {
+		slog.Error("[worker.processMessage] Failed to load restaurant", "error", err, "restaurantId", job.RestaurantId)
+		return job, err
+	}
+	if shouldSkipJob(restaurant.EnrichmentStatus) {
+		slog.Info("[worker.processMessage] Skipping job", "jobId", job.Id, "restaurantId", job.RestaurantId, "status", restaurant.EnrichmentStatus)
+		return job, nil
+	}
+	if isStaleJob(restaurant.EnrichmentJobId, job.Id) {
+		slog.Info("[worker.processMessage] Skipping job superseded by a newer job", "jobId", job.Id, "restaurantId", job.RestaurantId, "currentJobId", *restaurant.EnrichmentJobId)
+		return job, nil
+	}
+	if needsOpenHoursRefresh(restaurant, w.openHoursMaxAge, time.Now()) {
+		restaurant = w.refreshOpenHours(restaurant)
 	}
-	slog.Info("[worker.processMessage] Processing message", "message", restaurant)
 	now := time.Now().UTC()
-	currentDay := int(now.Weekday())
-	currentHour := now.Hour()
-	currentMinute := now.Minute()
-	openNow := isRestaurantOpen(restaurant.OpenHours, currentDay, currentHour, currentMinute)
-	if openNow {
-		slog.Info("[worker.processMessage] Restaurant is open", "restaurant", restaurant.Name)
+	openHours, location := places.LocalOpenHours(restaurant)
+	if w.callPolicy.canCallAt(openHours, restaurant.SpecialDays, location, now) {
+		slog.Info("[worker.processMessage] Restaurant is open and inside a call window", "restaurant", restaurant.Name)
+		claimed, err := w.claimJob(restaurant.Id, job.Id)
+		if err != nil {
+			return job, err
+		}
+		if !claimed {
+			slog.Info("[worker.processMessage] Skipping job claimed by another delivery", "jobId", job.Id, "restaurantId", job.RestaurantId)
+			return job, nil
+		}
 		vapiResponse, err := w.vapiClient.CreateCall(restaurant)
 		if err != nil {
 			slog.Error("[worker.processMessage] Failed to make Vapi phone call", "error", err)
-			return restaurant, err
+			return job, err
 		}
 		slog.Info("[worker.processMessage] Vapi phone call made", "callId", vapiResponse.Id)
 		_, err = w.dbClient.Db.Exec(w.dbClient.Ctx,
-			`UPDATE public.restaurants SET enrichment_status = $1, last_vapi_call_id = $2 WHERE places_id = $3`,
-			places.EnrichmentStatusInProgress, vapiResponse.Id, restaurant.Id,
+			`UPDATE public.restaurants SET last_vapi_call_id = $1 WHERE places_id = $2`,
+			vapiResponse.Id, restaurant.Id,
 		)
 		if err != nil {
-			slog.Error("[worker.processMessage] Failed to update enrichment status", "error", err)
-			return restaurant, err
+			slog.Error("[worker.processMessage] Failed to record Vapi call ID", "error", err)
+			return job, err
+		}
+		err = enrichment.SetRestaurantStatus(w.dbClient.Ctx, w.dbClient.Db, restaurant.Id, enrichment.StatusInProgress)
+		if err != nil {
+			return job, err
 		}
 		_, err = w.dbClient.Db.Exec(w.dbClient.Ctx,
 			`INSERT INTO public.calls (places_id, vapi_call_id, call_status) VALUES ($1, $2, $3)`,
@@ -114,88 +158,175 @@ func (w *Worker) processMessage(msg amqp091.Delivery) (places.Restaurant, error)
 		)
 		if err != nil {
 			slog.Error("[worker.processMessage] Failed to update Vapi call ID", "error", err)
-			return restaurant, err
+			return job, err
 		}
-		return restaurant, nil
+		return job, nil
 	}
-	slog.Info("[worker.processMessage] Restaurant is closed", "restaurant", restaurant.Name)
-	callbackTime := getCallbackTime(restaurant.OpenHours, currentDay, currentHour, currentMinute)
-	err = w.publisher.PublishDelayedMessage(restaurant, callbackTime)
+	slog.Info("[worker.processMessage] Restaurant is closed or outside call windows", "restaurant", restaurant.Name)
+	callbackTime := w.callPolicy.nextCallDelay(openHours, restaurant.SpecialDays, location, now)
+	err = w.queue.PublishDelayedMessage(job.Retry(), callbackTime)
 	if err != nil {
 		slog.Error("[worker.processMessage] Failed to publish message", "error", err)
-		return restaurant, err
+		return job, err
+	}
+	// Record when the job is expected back so recovery can tell a delayed job from a lost one
+	_, err = w.dbClient.Db.Exec(w.dbClient.Ctx,
+		`UPDATE public.restaurants SET enrichment_job_due_at = NOW() + make_interval(secs => $1) WHERE places_id = $2`,
+		callbackTime.Seconds(), restaurant.Id,
+	)
+	if err != nil {
+		slog.Error("[worker.processMessage] Failed to update job due time", "error", err)
+		return job, err
 	}
 	slog.Info("[worker.processMessage] Published message with delay", "delay", callbackTime, "restaurant", restaurant.Name)
+	return job, nil
+}
+
+// claimJob moves the restaurant from queued to in progress if it is still queued for this job, and reports whether
+// it did. Checking and claiming in one statement means that when a job is delivered twice at once, only one of the
+// deliveries places a call.
+func (w *Worker) claimJob(placesId string, jobId string) (bool, error) {
+	err := w.dbClient.Db.QueryRow(w.dbClient.Ctx,
+		`UPDATE public.restaurants SET enrichment_status = $1
+			WHERE places_id = $2 AND enrichment_status = $3 AND (enrichment_job_id IS NULL OR enrichment_job_id = $4)
+			RETURNING places_id`,
+		places.EnrichmentStatusInProgress, placesId, places.EnrichmentStatusQueued, jobId,
+	).Scan(&placesId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		slog.Error("[worker.claimJob] Failed to claim job", "error", err, "places_id", placesId, "jobId", jobId)
+		return false, err
+	}
+	return true, nil
+}
+
+func (w *Worker) getRestaurant(placesId string) (places.Restaurant, error) {
+	var restaurant places.Restaurant
+	err := w.dbClient.Db.QueryRow(w.dbClient.Ctx,
+		`SELECT places_id, name, address, phone_number, open_hours, nutrition_info, created_at, updated_at, enrichment_status, rating, open_hours_updated_at,
+			enrichment_job_id, special_days, utc_offset_minutes, time_zone
+			FROM public.restaurants WHERE places_id = $1`,
+		placesId,
+	).Scan(&restaurant.Id, &restaurant.Name, &restaurant.Address, &restaurant.PhoneNumber, &restaurant.OpenHours,
+		&restaurant.NutritionInfo, &restaurant.CreatedAt, &restaurant.UpdatedAt, &restaurant.EnrichmentStatus, &restaurant.Rating,
+		&restaurant.OpenHoursUpdatedAt, &restaurant.EnrichmentJobId, &restaurant.SpecialDays,
+		&restaurant.UtcOffsetMinutes, &restaurant.TimeZone)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("[worker.getRestaurant] Failed to get restaurant", "error", err)
+		}
+		return places.Restaurant{}, err
+	}
 	return restaurant, nil
 }
 
-func (w *Worker) handleFailure(restaurantId string) error {
-	_, err := w.dbClient.Db.Exec(w.dbClient.Ctx,
-		`UPDATE public.restaurants SET enrichment_status = $1 WHERE places_id = $2`,
-		places.EnrichmentStatusFailed, restaurantId,
+// refreshOpenHours fetches current open hours and special days from Places and stores them.
+// On failure the stored hours are kept.
+func (w *Worker) refreshOpenHours(restaurant places.Restaurant) places.Restaurant {
+	hours, err := w.placesClient.GetOpenHours(restaurant.Id)
+	if err != nil {
+		slog.Error("[worker.refreshOpenHours] Failed to refresh open hours, using stored hours", "error", err, "places_id", restaurant.Id)
+		return restaurant
+	}
+	var timeZone *string
+	if hours.TimeZone != "" {
+		timeZone = &hours.TimeZone
+	}
+	_, err = w.dbClient.Db.Exec(w.dbClient.Ctx,
+		`UPDATE public.restaurants SET open_hours = $1, special_days = $2, utc_offset_minutes = $3, time_zone = $4, open_hours_updated_at = NOW()
+			WHERE places_id = $5`,
+		hours.OpenHours, hours.SpecialDays, hours.UtcOffsetMinutes, timeZone, restaurant.Id,
 	)
 	if err != nil {
-		slog.Error("[worker.handleFailure] Failed to update enrichment status", "error", err)
-		return err
+		slog.Error("[worker.refreshOpenHours] Failed to store refreshed open hours, using stored hours", "error", err, "places_id", restaurant.Id)
+		return restaurant
 	}
-	slog.Info("[worker.processMessage] Updated enrichment status to failed", "places_id", restaurantId)
-	return nil
+	slog.Info("[worker.refreshOpenHours] Refreshed open hours", "places_id", restaurant.Id, "specialDays", len(hours.SpecialDays))
+	restaurant.OpenHours = hours.OpenHours
+	restaurant.SpecialDays = hours.SpecialDays
+	restaurant.UtcOffsetMinutes = &hours.UtcOffsetMinutes
+	restaurant.TimeZone = timeZone
+	return restaurant
 }
 
-// timeToMinutes converts a weekday/hour/minute to total minutes since start of week (Sunday 00:00)
-func timeToMinutes(weekday, hour, minute int) int {
-	return weekday*24*60 + hour*60 + minute
+// shouldSkipJob reports whether a job is obsolete given the restaurant's current status,
+// e.g. because another job already placed the call or the enrichment was cancelled
+func shouldSkipJob(status places.EnrichmentStatus) bool {
+	return status == places.EnrichmentStatusCompleted ||
+		status == places.EnrichmentStatusCancelled ||
+		status == places.EnrichmentStatusInProgress
 }
 
-func isRestaurantOpen(openHours []places.TimeRange, currentDay int, currentHour int, currentMinute int) bool {
-	currentMins := timeToMinutes(currentDay, currentHour, currentMinute)
-
-	for _, openHour := range openHours {
-		openMins := timeToMinutes(openHour.Open.Weekday, openHour.Open.Hour, openHour.Open.Minute)
-		closeMins := timeToMinutes(openHour.Close.Weekday, openHour.Close.Hour, openHour.Close.Minute)
+// isStaleJob reports whether a newer job (e.g. one re-enqueued by recovery) has taken over the restaurant.
+// Restaurants enqueued before job ids were recorded have no current job id and accept any job.
+func isStaleJob(currentJobId *string, jobId string) bool {
+	return currentJobId != nil && *currentJobId != jobId
+}
 
-		if closeMins > openMins {
-			// Normal case: opens and closes within the same week span
-			if currentMins >= openMins && currentMins < closeMins {
-				return true
-			}
-		} else {
-			// Week wrap: close time is "earlier" in the week than open time
-			// e.g., Sat 20:00 -> Sun 02:00 means open from Sat 20:00 to end of week OR start of week to Sun 02:00
-			if currentMins >= openMins || currentMins < closeMins {
-				return true
-			}
-		}
+func isOpenHoursStale(updatedAt *time.Time, maxAge time.Duration, now time.Time) bool {
+	if maxAge <= 0 {
+		return false
 	}
-	return false
+	return updatedAt == nil || now.Sub(*updatedAt) > maxAge
 }
 
-func getCallbackTime(openHours []places.TimeRange, currentDay int, currentHour int, currentMinute int) time.Duration {
-	currentMins := timeToMinutes(currentDay, currentHour, currentMinute)
-	weekMins := 7 * 24 * 60 // total minutes in a week
-
-	minWait := weekMins + 1 // initialize to more than a week (will be replaced)
-
-	for _, openHour := range openHours {
-		openMins := timeToMinutes(openHour.Open.Weekday, openHour.Open.Hour, openHour.Open.Minute)
+// needsOpenHoursRefresh reports whether the restaurant's hours should be fetched before deciding when to call.
+// Rows stored before UTC offsets were recorded can't be put in local time at all, so they are refreshed early.
+// Rows with an offset but no time zone, whether stored before time zones were recorded or because Places has
+// none for the place, use the offset until they are stale rather than fetching details on every job.
+func needsOpenHoursRefresh(restaurant places.Restaurant, maxAge time.Duration, now time.Time) bool {
+	if isOpenHoursStale(restaurant.OpenHoursUpdatedAt, maxAge, now) {
+		return true
+	}
+	return maxAge > 0 && restaurant.TimeZone == nil && restaurant.UtcOffsetMinutes == nil
+}
 
-		var wait int
-		if openMins > currentMins {
-			// Opens later this week
-			wait = openMins - currentMins
-		} else {
-			// Opens next week (wrap around)
-			wait = weekMins - currentMins + openMins
-		}
+func (w *Worker) handleFailure(restaurantId string) error {
+	tx, err := w.dbClient.Db.Begin(w.dbClient.Ctx)
+	if err != nil {
+		slog.Error("[worker.handleFailure] Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback(w.dbClient.Ctx)
 
-		if wait < minWait {
-			minWait = wait
-		}
+	var name string
+	err = tx.QueryRow(w.dbClient.Ctx,
+		`UPDATE public.restaurants SET enrichment_status = $1 WHERE places_id = $2 RETURNING name`,
+		places.EnrichmentStatusFailed, restaurantId,
+	).Scan(&name)
+	if err != nil {
+		slog.Error("[worker.handleFailure] Failed to update enrichment status", "error", err)
+		return err
 	}
+	err = enrichment.SetRestaurantStatus(w.dbClient.Ctx, tx, restaurantId, enrichment.StatusFailed)
+	if err != nil {
+		return err
+	}
+	err = webhooks.Enqueue(w.dbClient.Ctx, tx, webhooks.EventEnrichmentFailed, webhooks.RestaurantEvent{
+		RestaurantId:     restaurantId,
+		Name:             name,
+		EnrichmentStatus: string(places.EnrichmentStatusFailed),
+	})
+	if err != nil {
+		slog.Error("[worker.handleFailure] Failed to enqueue webhooks", "error", err)
+		return err
+	}
+	if err = tx.Commit(w.dbClient.Ctx); err != nil {
+		slog.Error("[worker.handleFailure] Failed to commit transaction", "error", err)
+		return err
+	}
+	slog.Info("[worker.processMessage] Updated enrichment status to failed", "places_id", restaurantId)
+	return nil
+}
 
-	if minWait > weekMins {
+// getCallbackTimeAt returns how long to wait for the restaurant's next opening (see openhours.NextOpenAt),
+// plus a 30 minute buffer
+func getCallbackTimeAt(openHours []places.TimeRange, specialDays []places.SpecialDay, location *time.Location, now time.Time) time.Duration {
+	next, ok := openhours.NextOpenAt(openHours, specialDays, location, now)
+	if !ok {
 		return 1 * time.Hour // default if no valid opening found
 	}
-
-	return time.Duration(minWait)*time.Minute + 30*time.Minute // add 30 min buffer
+	return next.Sub(now.Truncate(time.Minute)) + 30*time.Minute // add 30 min buffer
 }
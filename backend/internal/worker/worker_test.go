@@ -6,290 +6,6 @@ import (
 	"time"
 )
 
-func TestIsRestaurantOpen(t *testing.T) {
-	tests := []struct {
-		name          string
-		openHours     []places.TimeRange
-		currentDay    int
-		currentHour   int
-		currentMinute int
-		want          bool
-	}{
-		{
-			name: "[open] during regular hours same day",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
-				},
-			},
-			currentDay:  1,
-			currentHour: 14,
-			want:        true,
-		},
-		{
-			name: "[closed] before opening time",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
-				},
-			},
-			currentDay:  1,
-			currentHour: 8,
-			want:        false,
-		},
-		{
-			name: "[closed] after closing time",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
-				},
-			},
-			currentDay:  1,
-			currentHour: 23,
-			want:        false,
-		},
-		{
-			name: "[open] spans to next day - checking during late night",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 5, Hour: 18, Minute: 0},
-					Close: places.TimePoint{Weekday: 6, Hour: 2, Minute: 0},
-				},
-			},
-			currentDay:  5,
-			currentHour: 23,
-			want:        true,
-		},
-		{
-			name: "[open] from previous day - still open next day",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 5, Hour: 18, Minute: 0},
-					Close: places.TimePoint{Weekday: 6, Hour: 2, Minute: 0},
-				},
-			},
-			currentDay:  6,
-			currentHour: 1,
-			want:        true,
-		},
-		{
-			name: "[closed] on a different day",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
-				},
-			},
-			currentDay:  2,
-			currentHour: 14,
-			want:        false,
-		},
-		{
-			name:        "empty open hours",
-			openHours:   []places.TimeRange{},
-			currentDay:  1,
-			currentHour: 14,
-			want:        false,
-		},
-		{
-			name: "[open] multiple time ranges - open in second range",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 14, Minute: 0},
-				},
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 17, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
-				},
-			},
-			currentDay:  1,
-			currentHour: 19,
-			want:        true,
-		},
-		{
-			name: "[closed] multiple time ranges - closed between ranges",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 14, Minute: 0},
-				},
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 17, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
-				},
-			},
-			currentDay:  1,
-			currentHour: 15,
-			want:        false,
-		},
-		{
-			name: "[open] exactly at opening time - restaurant just opened",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
-				},
-			},
-			currentDay:    1,
-			currentHour:   9,
-			currentMinute: 0,
-			want:          true,
-		},
-		{
-			name: "[closed] exactly at closing hour - should be closed",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
-				},
-			},
-			currentDay:  1,
-			currentHour: 22,
-			want:        false,
-		},
-		{
-			name: "[open] opens at midnight (hour 0)",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 0, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 6, Minute: 0},
-				},
-			},
-			currentDay:  1,
-			currentHour: 3,
-			want:        true,
-		},
-		{
-			name: "[open] late night bar - checking at 1 AM same day it opened",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 5, Hour: 18, Minute: 0},
-					Close: places.TimePoint{Weekday: 6, Hour: 2, Minute: 0},
-				},
-			},
-			currentDay:  5,
-			currentHour: 23,
-			want:        true,
-		},
-		{
-			name: "[open] late night bar - checking next day before close",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 5, Hour: 18, Minute: 0},
-					Close: places.TimePoint{Weekday: 6, Hour: 2, Minute: 0},
-				},
-			},
-			currentDay:  6,
-			currentHour: 1,
-			want:        true,
-		},
-		{
-			name: "[closed] late night bar - checking next day after close",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 5, Hour: 18, Minute: 0},
-					Close: places.TimePoint{Weekday: 6, Hour: 2, Minute: 0},
-				},
-			},
-			currentDay:  6,
-			currentHour: 3,
-			want:        false,
-		},
-		{
-			name: "[closed] week wrap - Saturday night bar, checking Sunday after close",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 6, Hour: 20, Minute: 0},
-					Close: places.TimePoint{Weekday: 0, Hour: 2, Minute: 0},
-				},
-			},
-			currentDay:  0,
-			currentHour: 3,
-			want:        false,
-		},
-		{
-			name: "[open] week wrap - Saturday night bar, checking Sunday before close",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 6, Hour: 20, Minute: 0},
-					Close: places.TimePoint{Weekday: 0, Hour: 2, Minute: 0},
-				},
-			},
-			currentDay:  0,
-			currentHour: 1,
-			want:        true,
-		},
-		// BUG TEST: Week wrap on the opening day itself
-		{
-			name: "[open] week wrap - Saturday night bar, checking SATURDAY before midnight",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 6, Hour: 20, Minute: 0},
-					Close: places.TimePoint{Weekday: 0, Hour: 2, Minute: 0},
-				},
-			},
-			currentDay:  6,
-			currentHour: 23,
-			want:        true,
-		},
-		// BUG TEST: Minute precision - opened 30 min ago
-		{
-			name: "[open] minute precision - opened 30 min ago same hour",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
-				},
-			},
-			currentDay:    1,
-			currentHour:   9,
-			currentMinute: 30,
-			want:          true,
-		},
-		// BUG TEST: Minute precision - closes in 30 min
-		{
-			name: "[open] minute precision - closes in 30 min same hour",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 30},
-				},
-			},
-			currentDay:    1,
-			currentHour:   22,
-			currentMinute: 15,
-			want:          true,
-		},
-		// BUG TEST: Minute precision - closed 15 min ago
-		{
-			name: "[closed] minute precision - closed 15 min ago same hour",
-			openHours: []places.TimeRange{
-				{
-					Open:  places.TimePoint{Weekday: 1, Hour: 9, Minute: 0},
-					Close: places.TimePoint{Weekday: 1, Hour: 22, Minute: 0},
-				},
-			},
-			currentDay:    1,
-			currentHour:   22,
-			currentMinute: 15,
-			want:          false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := isRestaurantOpen(tt.openHours, tt.currentDay, tt.currentHour, tt.currentMinute)
-			if got != tt.want {
-				t.Errorf("isRestaurantOpen() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func TestGetCallbackTime(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -521,9 +237,283 @@ func TestGetCallbackTime(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := getCallbackTime(tt.openHours, tt.currentDay, tt.currentHour, tt.currentMinute)
+			// 2026-01-04 is a Sunday, so the day of the month matches the weekday index
+			now := time.Date(2026, 1, 4+tt.currentDay, tt.currentHour, tt.currentMinute, 0, 0, time.UTC)
+			got := getCallbackTimeAt(tt.openHours, nil, time.UTC, now)
+			if got != tt.want {
+				t.Errorf("getCallbackTimeAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsOpenHoursStale(t *testing.T) {
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-1 * time.Hour)
+	old := now.Add(-96 * time.Hour)
+	tests := []struct {
+		name      string
+		updatedAt *time.Time
+		maxAge    time.Duration
+		want      bool
+	}{
+		{name: "never fetched", updatedAt: nil, maxAge: 72 * time.Hour, want: true},
+		{name: "fetched recently", updatedAt: &recent, maxAge: 72 * time.Hour, want: false},
+		{name: "fetched before max age", updatedAt: &old, maxAge: 72 * time.Hour, want: true},
+		{name: "refresh disabled", updatedAt: nil, maxAge: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := isOpenHoursStale(tt.updatedAt, tt.maxAge, now)
+			if got != tt.want {
+				t.Errorf("isOpenHoursStale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeedsOpenHoursRefresh(t *testing.T) {
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-1 * time.Hour)
+	newYork := "America/New_York"
+	offset := -300
+	tests := []struct {
+		name       string
+		restaurant places.Restaurant
+		maxAge     time.Duration
+		want       bool
+	}{
+		{name: "time zone", restaurant: places.Restaurant{OpenHoursUpdatedAt: &recent, TimeZone: &newYork, UtcOffsetMinutes: &offset}, maxAge: 72 * time.Hour, want: false},
+		{name: "offset without time zone", restaurant: places.Restaurant{OpenHoursUpdatedAt: &recent, UtcOffsetMinutes: &offset}, maxAge: 72 * time.Hour, want: false},
+		{name: "neither time zone nor offset", restaurant: places.Restaurant{OpenHoursUpdatedAt: &recent}, maxAge: 72 * time.Hour, want: true},
+		{name: "never fetched", restaurant: places.Restaurant{TimeZone: &newYork, UtcOffsetMinutes: &offset}, maxAge: 72 * time.Hour, want: true},
+		{name: "refresh disabled", restaurant: places.Restaurant{}, maxAge: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := needsOpenHoursRefresh(tt.restaurant, tt.maxAge, now)
+			if got != tt.want {
+				t.Errorf("needsOpenHoursRefresh() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldSkipJob(t *testing.T) {
+	tests := []struct {
+		status places.EnrichmentStatus
+		want   bool
+	}{
+		{status: places.EnrichmentStatusQueued, want: false},
+		{status: places.EnrichmentStatusFailed, want: false},
+		{status: places.EnrichmentStatusInProgress, want: true},
+		{status: places.EnrichmentStatusCompleted, want: true},
+		{status: places.EnrichmentStatusCancelled, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			got := shouldSkipJob(tt.status)
+			if got != tt.want {
+				t.Errorf("shouldSkipJob(%s) = %v, want %v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsStaleJob(t *testing.T) {
+	current := "job-2"
+	if isStaleJob(nil, "job-1") {
+		t.Errorf("Expected any job to be accepted when no current job is recorded")
+	}
+	if isStaleJob(&current, "job-2") {
+		t.Errorf("Expected the current job not to be stale")
+	}
+	if !isStaleJob(&current, "job-1") {
+		t.Errorf("Expected a superseded job to be stale")
+	}
+}
+
+func TestGetCallbackTimeAtWithSpecialDays(t *testing.T) {
+	openHours := openEveryDay(9, 22)
+	// Christmas Day 2026 is a Friday, closed all day
+	christmas := places.SpecialDay{
+		Date:  "2026-12-25",
+		Start: time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2026, 12, 26, 0, 0, 0, 0, time.UTC),
+		Hours: []places.DatedPeriod{},
+	}
+	// New Year's Eve opens late
+	newYearsEve := places.SpecialDay{
+		Date:  "2026-12-31",
+		Start: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		Hours: []places.DatedPeriod{
+			{Open: time.Date(2026, 12, 31, 17, 0, 0, 0, time.UTC), Close: time.Date(2027, 1, 1, 1, 0, 0, 0, time.UTC)},
+		},
+	}
+	tests := []struct {
+		name        string
+		specialDays []places.SpecialDay
+		now         time.Time
+		want        time.Duration
+	}{
+		{
+			name: "no special days matches getCallbackTime",
+			now:  time.Date(2026, 12, 24, 23, 0, 0, 0, time.UTC),
+			want: 10*time.Hour + 30*time.Minute,
+		},
+		{
+			name:        "skips holiday closure to the day after",
+			specialDays: []places.SpecialDay{christmas},
+			now:         time.Date(2026, 12, 24, 23, 0, 0, 0, time.UTC),
+			want:        34*time.Hour + 30*time.Minute,
+		},
+		{
+			name:        "waits for special opening time",
+			specialDays: []places.SpecialDay{newYearsEve},
+			now:         time.Date(2026, 12, 31, 8, 0, 0, 0, time.UTC),
+			want:        9*time.Hour + 30*time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getCallbackTimeAt(openHours, tt.specialDays, time.UTC, tt.now)
+			if got != tt.want {
+				t.Errorf("getCallbackTimeAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []LocalWindow
+		wantErr bool
+	}{
+		{name: "empty disables windows", value: "", want: []LocalWindow{}},
+		{name: "single window", value: "14:00-16:30", want: []LocalWindow{{Start: 840, End: 990}}},
+		{name: "multiple windows with spaces", value: "11:30-13:30, 17:30-20:00", want: []LocalWindow{{Start: 690, End: 810}, {Start: 1050, End: 1200}}},
+		{name: "malformed window", value: "lunch", wantErr: true},
+		{name: "end before start", value: "20:00-17:30", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWindows(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseWindows() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("parseWindows() = %v, want %v", got, tt.want)
+				return
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("parseWindows() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// openEveryDay is open from openHour to closeHour every day of the week
+func openEveryDay(openHour int, closeHour int) []places.TimeRange {
+	openHours := []places.TimeRange{}
+	for day := 0; day < 7; day++ {
+		openHours = append(openHours, places.TimeRange{
+			Open:  places.TimePoint{Weekday: day, Hour: openHour, Minute: 0},
+			Close: places.TimePoint{Weekday: day, Hour: closeHour, Minute: 0},
+		})
+	}
+	return openHours
+}
+
+func testCallWindowPolicy() CallWindowPolicy {
+	return CallWindowPolicy{
+		AvoidWindows:    []LocalWindow{{Start: 690, End: 810}, {Start: 1050, End: 1200}},
+		PreferredWindow: &LocalWindow{Start: 840, End: 990},
+		MinAfterOpen:    30 * time.Minute,
+		MinBeforeClose:  30 * time.Minute,
+	}
+}
+
+func TestCanCallAt(t *testing.T) {
+	openHours := openEveryDay(9, 22)
+	policy := testCallWindowPolicy()
+	tests := []struct {
+		name     string
+		location *time.Location
+		at       time.Time
+		want     bool
+	}{
+		{name: "[callable] mid-morning", location: time.UTC, at: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC), want: true},
+		{name: "[not callable] closed", location: time.UTC, at: time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC), want: false},
+		{name: "[not callable] just opened", location: time.UTC, at: time.Date(2026, 3, 2, 9, 10, 0, 0, time.UTC), want: false},
+		{name: "[not callable] lunch rush", location: time.UTC, at: time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC), want: false},
+		{name: "[not callable] dinner rush", location: time.UTC, at: time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC), want: false},
+		{name: "[callable] after dinner rush", location: time.UTC, at: time.Date(2026, 3, 2, 21, 0, 0, 0, time.UTC), want: true},
+		{name: "[not callable] closing soon", location: time.UTC, at: time.Date(2026, 3, 2, 21, 40, 0, 0, time.UTC), want: false},
+		{name: "[not callable] lunch rush in restaurant's local time", location: time.FixedZone("", 2*60*60), at: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.canCallAt(openHours, nil, tt.location, tt.at)
+			if got != tt.want {
+				t.Errorf("canCallAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextCallDelay(t *testing.T) {
+	openHours := openEveryDay(9, 22)
+	withoutPreferred := testCallWindowPolicy()
+	withoutPreferred.PreferredWindow = nil
+	tests := []struct {
+		name   string
+		policy CallWindowPolicy
+		now    time.Time
+		want   time.Duration
+	}{
+		{
+			name:   "before opening waits for preferred window",
+			policy: testCallWindowPolicy(),
+			now:    time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC),
+			want:   6 * time.Hour,
+		},
+		{
+			name:   "before opening without preferred window calls after opening margin",
+			policy: withoutPreferred,
+			now:    time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC),
+			want:   1*time.Hour + 30*time.Minute,
+		},
+		{
+			name:   "after preferred window calls at the next acceptable time",
+			policy: testCallWindowPolicy(),
+			now:    time.Date(2026, 3, 2, 17, 40, 0, 0, time.UTC),
+			want:   2*time.Hour + 20*time.Minute,
+		},
+		{
+			name:   "too close to closing waits for next day's preferred window",
+			policy: testCallWindowPolicy(),
+			now:    time.Date(2026, 3, 2, 21, 45, 0, 0, time.UTC),
+			want:   16*time.Hour + 15*time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.nextCallDelay(openHours, nil, time.UTC, tt.now)
 			if got != tt.want {
-				t.Errorf("getCallbackTime() = %v, want %v", got, tt.want)
+				t.Errorf("nextCallDelay() = %v, want %v", got, tt.want)
 			}
 		})
 	}
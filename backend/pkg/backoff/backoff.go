@@ -0,0 +1,21 @@
+package backoff
+
+import "time"
+
+// Exponential waits Initial after the first failed attempt and doubles the wait after each one, capped at Max
+type Exponential struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// Delay is how long to wait after attempt consecutive failures, counting from 1
+func (e Exponential) Delay(attempt int) time.Duration {
+	delay := e.Initial
+	for i := 1; i < attempt && delay < e.Max; i++ {
+		delay *= 2
+	}
+	if delay > e.Max {
+		delay = e.Max
+	}
+	return delay
+}
@@ -0,0 +1,34 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialDelay(t *testing.T) {
+	backoff := Exponential{Initial: 30 * time.Second, Max: 6 * time.Hour}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: 30 * time.Second},
+		{attempt: 1, want: 30 * time.Second},
+		{attempt: 2, want: 1 * time.Minute},
+		{attempt: 5, want: 8 * time.Minute},
+		{attempt: 10, want: 256 * time.Minute},
+		{attempt: 11, want: 6 * time.Hour},
+		{attempt: 1000, want: 6 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		got := backoff.Delay(tt.attempt)
+		if got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+
+	capped := Exponential{Initial: time.Minute, Max: 10 * time.Second}
+	if got := capped.Delay(1); got != 10*time.Second {
+		t.Errorf("Expected the first delay to be capped at Max, but got %v", got)
+	}
+}
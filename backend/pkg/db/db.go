@@ -5,36 +5,80 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// defaultMaxConns is each client's pool size when DB_POOL_MAX_CONNS isn't set
+const defaultMaxConns = 4
+
+// DatabaseClient holds a connection pool, so it is safe for concurrent use: every statement and transaction
+// runs on a connection of its own
 type DatabaseClient struct {
-	Db  *pgx.Conn
+	Db  *pgxpool.Pool
 	Ctx context.Context
 }
 
 func NewDatabaseClient() *DatabaseClient {
 	ctx := context.Background()
-	db, err := pgx.Connect(ctx, generateConnectionString())
+	config, err := pgxpool.ParseConfig(generateConnectionString())
+	if err != nil {
+		slog.Error("[db.NewDatabaseClient] Invalid database config", "error", err)
+		return nil
+	}
+	config.MaxConns = int32(maxConns())
+	db, err := pgxpool.NewWithConfig(ctx, config)
+	if err == nil {
+		err = db.Ping(ctx)
+	}
 	if err != nil {
 		slog.Error("[db.NewDatabaseClient] Failed to connect to database", "error", err)
 		return nil
 	}
-	slog.Info("[db.NewDatabaseClient] Connected to database")
+	slog.Info("[db.NewDatabaseClient] Connected to database", "maxConns", config.MaxConns)
 	return &DatabaseClient{Db: db, Ctx: ctx}
 }
 
+// Connect opens a single connection outside any pool, for sessions that hold on to it such as LISTEN
+func Connect(ctx context.Context) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, generateConnectionString())
+	if err != nil {
+		slog.Error("[db.Connect] Failed to connect to database", "error", err)
+		return nil, err
+	}
+	return conn, nil
+}
+
 func (dc *DatabaseClient) Close() {
 	if dc.Db == nil {
 		slog.Error("[db.Close] Database connection is nil")
 		return
 	}
-	err := dc.Db.Close(dc.Ctx)
-	if err != nil {
-		slog.Error("[db.Close] Failed to close database connection", "error", err)
+	dc.Db.Close()
+	slog.Info("[db.Close] Closed database connection pool")
+}
+
+// IsUUID reports whether id is a valid uuid. Queries compare uuid columns with $1::uuid rather than casting the
+// column to text, so their indexes are used; that cast fails for anything else, so check ids first.
+func IsUUID(id string) bool {
+	var parsed pgtype.UUID
+	return parsed.Scan(id) == nil
+}
+
+func maxConns() int {
+	value := os.Getenv("DB_POOL_MAX_CONNS")
+	if value == "" {
+		return defaultMaxConns
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		slog.Error("[db.maxConns] Invalid DB_POOL_MAX_CONNS, using default", "value", value, "default", defaultMaxConns)
+		return defaultMaxConns
 	}
-	slog.Info("[db.Close] Closed database connection")
+	return number
 }
 
 func generateConnectionString() string {
@@ -6,17 +6,8 @@ import (
 	"log/slog"
 )
 
-func ToBytes(v interface{}) ([]byte, error) {
-	buffer := bytes.NewBuffer(nil)
-	encoder := gob.NewEncoder(buffer)
-	err := encoder.Encode(v)
-	if err != nil {
-		slog.Error("[encoder.ToBytes] Failed to encode value", "error", err)
-		return nil, err
-	}
-	return buffer.Bytes(), nil
-}
-
+// FromBytes decodes a gob-encoded value. Messages are now published as JSON (see ToJSON);
+// this is kept so that messages enqueued before the switch can still be read.
 func FromBytes(b []byte, v interface{}) error {
 	buffer := bytes.NewBuffer(b)
 	decoder := gob.NewDecoder(buffer)
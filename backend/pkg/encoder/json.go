@@ -0,0 +1,26 @@
+package encoder
+
+import (
+	"encoding/json"
+	"log/slog"
+)
+
+const ContentTypeJSON = "application/json"
+
+func ToJSON(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("[encoder.ToJSON] Failed to encode value", "error", err)
+		return nil, err
+	}
+	return b, nil
+}
+
+func FromJSON(b []byte, v interface{}) error {
+	err := json.Unmarshal(b, v)
+	if err != nil {
+		slog.Error("[encoder.FromJSON] Failed to decode value", "error", err)
+		return err
+	}
+	return nil
+}
@@ -3,9 +3,14 @@ package http
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
 )
 
 type Http struct {
@@ -18,6 +23,46 @@ func NewClient() *Http {
 	}
 }
 
+// NewClientWithTimeout returns a client whose requests fail once timeout elapses, for calling endpoints we don't control
+func NewClientWithTimeout(timeout time.Duration) *Http {
+	return &Http{
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// NewPublicClientWithTimeout is NewClientWithTimeout for URLs given to us by users: it refuses to connect to
+// loopback, private, link-local and other non-public addresses. The address is checked as it is dialed, after DNS
+// resolution, so a name that resolves (or is rebound) to an internal address is refused too.
+func NewPublicClientWithTimeout(timeout time.Duration) *Http {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialPublicOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialed instead of the destination, bypassing the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Http{
+		client: &http.Client{Timeout: timeout, Transport: transport},
+	}
+}
+
+// IsPublicIP reports whether ip is routable on the internet, i.e. not loopback, private, link-local, unspecified
+// or multicast
+func IsPublicIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+func dialPublicOnly(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublicIP(addrPort.Addr()) {
+		return fmt.Errorf("refusing to connect to non-public address %s", addrPort.Addr())
+	}
+	return nil
+}
+
 func (h *Http) Get(url string, headers map[string]string) ([]byte, int, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -56,6 +101,23 @@ func (h *Http) Post(url string, reqBody interface{}, headers map[string]string)
 	return body, statusCode, nil
 }
 
+// PostBytes sends body as is, for when the exact bytes matter (e.g. they are signed)
+func (h *Http) PostBytes(url string, body []byte, headers map[string]string) ([]byte, int, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		slog.Error("[http.PostBytes] Failed to create HTTP request", "error", err)
+		return nil, 0, err
+	}
+
+	respBody, statusCode, err := sendRequest(h.client, req, headers)
+	if err != nil {
+		slog.Error("[http.PostBytes] Failed to send HTTP request", "error", err)
+		return nil, 0, err
+	}
+
+	return respBody, statusCode, nil
+}
+
 func sendRequest(httpClient *http.Client, req *http.Request, headers map[string]string) ([]byte, int, error) {
 	for key, value := range headers {
 		req.Header.Set(key, value)
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.5", false},
+		{"172.16.3.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		got := IsPublicIP(netip.MustParseAddr(tt.ip))
+		if got != tt.want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestPublicClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// localhost resolves to a loopback address, which is refused as it is dialed
+	url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	_, _, err := NewPublicClientWithTimeout(time.Second).Get(url, nil)
+	if err == nil || !strings.Contains(err.Error(), "non-public address") {
+		t.Errorf("Expected the request to be refused, but got error %v", err)
+	}
+
+	_, statusCode, err := NewClientWithTimeout(time.Second).Get(url, nil)
+	if err != nil || statusCode != http.StatusOK {
+		t.Errorf("Expected the unrestricted client to connect, but got status %d and error %v", statusCode, err)
+	}
+}
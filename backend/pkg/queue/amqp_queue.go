@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// AMQPQueue is the RabbitMQ implementation of Queue. Publishing and consuming use separate
+// connections; the consumer connection is only opened once ConsumeMessages is called.
+type AMQPQueue struct {
+	queueName string
+	publisher *Publisher
+	consumer  *Consumer
+}
+
+func NewAMQPQueue(queueName string) *AMQPQueue {
+	return &AMQPQueue{
+		queueName: queueName,
+		publisher: NewPublisher(queueName),
+	}
+}
+
+func (q *AMQPQueue) Close() {
+	if q.consumer != nil {
+		q.consumer.Close()
+	}
+	q.publisher.Close()
+}
+
+func (q *AMQPQueue) PublishMessage(body interface{}) error {
+	return q.publisher.PublishMessage(body)
+}
+
+func (q *AMQPQueue) PublishDelayedMessage(body interface{}, delay time.Duration) error {
+	return q.publisher.PublishDelayedMessage(body, delay)
+}
+
+func (q *AMQPQueue) ConsumeMessages(ctx context.Context) (<-chan Delivery, error) {
+	if q.consumer == nil {
+		q.consumer = NewConsumer(q.queueName)
+	}
+	msgs, err := q.consumer.ConsumeMessages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make(chan Delivery)
+	go func() {
+		defer close(deliveries)
+		for msg := range msgs {
+			if q.forwardIfEarly(msg) {
+				continue
+			}
+			select {
+			case deliveries <- fromAMQPDelivery(msg):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+// forwardIfEarly handles messages returning from a TTL delay bucket that are not due yet,
+// sending them on to the next bucket. It reports whether the message was consumed this way.
+func (q *AMQPQueue) forwardIfEarly(msg amqp.Delivery) bool {
+	if q.publisher.queueClient.delayStrategy != DelayStrategyTTL {
+		return false
+	}
+	remaining := remainingDelay(msg.Headers, time.Now())
+	if _, ok := delayBucket(remaining); !ok {
+		return false
+	}
+	err := q.publisher.forwardDelayed(msg, remaining)
+	if err != nil {
+		slog.Error("[queue.AMQPQueue.forwardIfEarly] Failed to forward delayed message, requeueing", "error", err)
+		msg.Nack(false, true)
+		return true
+	}
+	msg.Ack(false)
+	return true
+}
+
+func fromAMQPDelivery(msg amqp.Delivery) Delivery {
+	return Delivery{
+		Body:        msg.Body,
+		ContentType: msg.ContentType,
+		MessageId:   msg.MessageId,
+		Headers:     msg.Headers,
+		Priority:    msg.Priority,
+		ack: func() error {
+			return msg.Ack(false)
+		},
+		nack: func(requeue bool) error {
+			return msg.Nack(false, requeue)
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package queue
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Queue is a named work queue. Messages are JSON-encoded on publish; bodies implementing Message
+// also carry their id, type and headers.
+type Queue interface {
+	PublishMessage(body interface{}) error
+	PublishDelayedMessage(body interface{}, delay time.Duration) error
+	// ConsumeMessages delivers messages until ctx is cancelled. Every delivery must be acked or nacked.
+	ConsumeMessages(ctx context.Context) (<-chan Delivery, error)
+	Close()
+}
+
+type Delivery struct {
+	Body        []byte
+	ContentType string
+	MessageId   string
+	Headers     map[string]interface{}
+	Priority    uint8
+	ack         func() error
+	nack        func(requeue bool) error
+}
+
+// Ack marks the message as processed so it is not delivered again
+func (d Delivery) Ack() error {
+	return d.ack()
+}
+
+// Nack rejects the message. With requeue it is delivered again, otherwise it is dropped.
+func (d Delivery) Nack(requeue bool) error {
+	return d.nack(requeue)
+}
+
+const (
+	BackendRabbitMQ = "rabbitmq"
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// New returns the queue implementation selected by QUEUE_BACKEND (rabbitmq, memory or postgres), defaulting to RabbitMQ
+func New(queueName string) Queue {
+	backend := os.Getenv("QUEUE_BACKEND")
+	switch backend {
+	case BackendMemory:
+		return NewMemoryQueue(queueName)
+	case BackendPostgres:
+		return NewPostgresQueue(queueName)
+	case "", BackendRabbitMQ:
+		return NewAMQPQueue(queueName)
+	default:
+		slog.Error("[queue.New] Unknown queue backend, using RabbitMQ", "backend", backend)
+		return NewAMQPQueue(queueName)
+	}
+}
@@ -2,7 +2,9 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 )
@@ -22,8 +24,46 @@ func (c *Consumer) Close() {
 	c.queueClient.Close()
 }
 
+// ConsumeMessages returns a channel of deliveries that survives reconnects: when the underlying
+// channel is closed it waits for the client to reconnect and resumes consuming. The returned
+// channel is closed once ctx is cancelled or the client is closed.
 func (c *Consumer) ConsumeMessages(ctx context.Context) (<-chan amqp091.Delivery, error) {
-	msgs, err := c.queueClient.queue.ConsumeWithContext(
+	deliveries := make(chan amqp091.Delivery)
+	go func() {
+		defer close(deliveries)
+		for {
+			msgs, err := c.consume(ctx)
+			if err != nil {
+				if ctx.Err() != nil || errors.Is(err, ErrClosed) {
+					return
+				}
+				slog.Error("[queue.Consumer.ConsumeMessages] Failed to consume messages, retrying", "error", err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(minReconnectDelay):
+				}
+				continue
+			}
+			for msg := range msgs {
+				select {
+				case deliveries <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+			slog.Info("[queue.Consumer.ConsumeMessages] Delivery channel closed, waiting for reconnect", "queueName", c.queueClient.queueName)
+		}
+	}()
+	return deliveries, nil
+}
+
+func (c *Consumer) consume(ctx context.Context) (<-chan amqp091.Delivery, error) {
+	ch, err := c.queueClient.channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := ch.ConsumeWithContext(
 		ctx,
 		c.queueClient.queueName,
 		"",
@@ -34,9 +74,8 @@ func (c *Consumer) ConsumeMessages(ctx context.Context) (<-chan amqp091.Delivery
 		nil,
 	)
 	if err != nil {
-		slog.Error("[queue.Consumer.ConsumeMessages] Failed to consume messages", "error", err)
+		slog.Error("[queue.Consumer.consume] Failed to consume messages", "error", err)
 		return nil, err
 	}
-
 	return msgs, nil
 }
@@ -0,0 +1,96 @@
+package queue
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type DelayStrategy string
+
+const (
+	// DelayStrategyPlugin uses the x-delayed-message exchange from the rabbitmq-delayed-message-exchange plugin
+	DelayStrategyPlugin DelayStrategy = "plugin"
+	// DelayStrategyTTL parks messages in fixed-TTL bucket queues that dead-letter back to the work queue
+	DelayStrategyTTL DelayStrategy = "ttl"
+)
+
+// HeaderDeliverAt is the time (unix milliseconds) a message parked in TTL buckets is due. A message that
+// comes back from a bucket before it is due is moved to the next bucket instead of being delivered.
+const HeaderDeliverAt = "x-deliver-at"
+
+// delayBuckets are the TTLs of the bucket queues, largest first. A delay is served by hopping through
+// the largest bucket that fits the remaining time, so a week is at most seven 24h hops plus a few smaller
+// ones, and the delivery is at most one smallest bucket early.
+var delayBuckets = []time.Duration{
+	24 * time.Hour,
+	6 * time.Hour,
+	1 * time.Hour,
+	15 * time.Minute,
+	5 * time.Minute,
+	1 * time.Minute,
+	10 * time.Second,
+}
+
+func getDelayStrategy() DelayStrategy {
+	strategy := DelayStrategy(os.Getenv("RABBITMQ_DELAY_STRATEGY"))
+	switch strategy {
+	case DelayStrategyTTL, DelayStrategyPlugin:
+		return strategy
+	case "":
+		return DelayStrategyPlugin
+	default:
+		slog.Error("[queue.getDelayStrategy] Unknown delay strategy, using plugin", "strategy", strategy)
+		return DelayStrategyPlugin
+	}
+}
+
+// delayBucket returns the largest bucket no longer than delay, or false if delay is shorter than every bucket
+func delayBucket(delay time.Duration) (time.Duration, bool) {
+	for _, bucket := range delayBuckets {
+		if bucket <= delay {
+			return bucket, true
+		}
+	}
+	return 0, false
+}
+
+func bucketQueueName(queueName string, bucket time.Duration) string {
+	return fmt.Sprintf("%s.delay.%s", queueName, bucket)
+}
+
+func declareDelayBuckets(ch *amqp.Channel, queueName string) error {
+	for _, bucket := range delayBuckets {
+		_, err := ch.QueueDeclare(
+			bucketQueueName(queueName, bucket), true, false, false, false, amqp.Table{
+				"x-message-ttl":             bucket.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queueName,
+			},
+		)
+		if err != nil {
+			slog.Error("[queue.declareDelayBuckets] Failed to declare delay bucket", "error", err, "bucket", bucket)
+			return err
+		}
+	}
+	return nil
+}
+
+// remainingDelay reads HeaderDeliverAt from a delivery and returns how long until it is due
+func remainingDelay(headers amqp.Table, now time.Time) time.Duration {
+	var deliverAtMillis int64
+	switch value := headers[HeaderDeliverAt].(type) {
+	case int64:
+		deliverAtMillis = value
+	case int32:
+		deliverAtMillis = int64(value)
+	case int:
+		deliverAtMillis = int64(value)
+	default:
+		return 0
+	}
+	return time.UnixMilli(deliverAtMillis).Sub(now)
+}
@@ -0,0 +1,158 @@
+package queue
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"eatsavvy/pkg/encoder"
+)
+
+// memoryQueues holds the messages for every in-memory queue in the process, so that
+// separate MemoryQueue values with the same name share messages like they would on a broker
+var memoryQueues = struct {
+	sync.Mutex
+	buffers map[string]*memoryBuffer
+}{buffers: map[string]*memoryBuffer{}}
+
+type memoryBuffer struct {
+	mu       sync.Mutex
+	messages []Delivery
+	notify   chan struct{}
+}
+
+// push adds the delivery behind every message of the same or higher priority
+func (b *memoryBuffer) push(d Delivery) {
+	b.mu.Lock()
+	i := len(b.messages)
+	for i > 0 && b.messages[i-1].Priority < d.Priority {
+		i--
+	}
+	b.messages = slices.Insert(b.messages, i, d)
+	b.mu.Unlock()
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (b *memoryBuffer) pop() (Delivery, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.messages) == 0 {
+		return Delivery{}, false
+	}
+	d := b.messages[0]
+	b.messages = b.messages[1:]
+	return d, true
+}
+
+// MemoryQueue is an in-process Queue for tests and local development. Messages are lost when the process exits.
+type MemoryQueue struct {
+	queueName string
+	buffer    *memoryBuffer
+	mu        sync.Mutex
+	timers    []*time.Timer
+}
+
+func NewMemoryQueue(queueName string) *MemoryQueue {
+	memoryQueues.Lock()
+	defer memoryQueues.Unlock()
+	buffer, ok := memoryQueues.buffers[queueName]
+	if !ok {
+		buffer = &memoryBuffer{notify: make(chan struct{}, 1)}
+		memoryQueues.buffers[queueName] = buffer
+	}
+	return &MemoryQueue{
+		queueName: queueName,
+		buffer:    buffer,
+	}
+}
+
+// Close stops pending delayed messages published through this queue
+func (q *MemoryQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, timer := range q.timers {
+		timer.Stop()
+	}
+	q.timers = nil
+}
+
+func (q *MemoryQueue) PublishMessage(body interface{}) error {
+	delivery, err := q.newDelivery(body)
+	if err != nil {
+		slog.Error("[queue.MemoryQueue.PublishMessage] Failed to convert body to bytes", "error", err)
+		return err
+	}
+	q.buffer.push(delivery)
+	return nil
+}
+
+func (q *MemoryQueue) PublishDelayedMessage(body interface{}, delay time.Duration) error {
+	delivery, err := q.newDelivery(body)
+	if err != nil {
+		slog.Error("[queue.MemoryQueue.PublishDelayedMessage] Failed to convert body to bytes", "error", err)
+		return err
+	}
+	timer := time.AfterFunc(delay, func() {
+		q.buffer.push(delivery)
+	})
+	q.mu.Lock()
+	q.timers = append(q.timers, timer)
+	q.mu.Unlock()
+	return nil
+}
+
+func (q *MemoryQueue) ConsumeMessages(ctx context.Context) (<-chan Delivery, error) {
+	deliveries := make(chan Delivery)
+	go func() {
+		defer close(deliveries)
+		for {
+			delivery, ok := q.buffer.pop()
+			if !ok {
+				select {
+				case <-ctx.Done():
+					return
+				case <-q.buffer.notify:
+				}
+				continue
+			}
+			select {
+			case deliveries <- delivery:
+			case <-ctx.Done():
+				// Put it back for the next consumer
+				q.buffer.push(delivery)
+				return
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+func (q *MemoryQueue) newDelivery(body interface{}) (Delivery, error) {
+	bodyBytes, err := encoder.ToJSON(body)
+	if err != nil {
+		return Delivery{}, err
+	}
+	publishing := newPublishing(body, bodyBytes, encoder.ContentTypeJSON)
+	delivery := Delivery{
+		Body:        publishing.Body,
+		ContentType: publishing.ContentType,
+		MessageId:   publishing.MessageId,
+		Headers:     publishing.Headers,
+		Priority:    publishing.Priority,
+	}
+	delivery.ack = func() error {
+		return nil
+	}
+	delivery.nack = func(requeue bool) error {
+		if requeue {
+			q.buffer.push(delivery)
+		}
+		return nil
+	}
+	return delivery, nil
+}
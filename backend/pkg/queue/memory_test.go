@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, deliveries <-chan Delivery) Delivery {
+	t.Helper()
+	select {
+	case delivery := <-deliveries:
+		return delivery
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for a delivery")
+		return Delivery{}
+	}
+}
+
+func TestMemoryQueuePublishAndConsume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	publisher := NewMemoryQueue("test_publish_and_consume")
+	consumer := NewMemoryQueue("test_publish_and_consume")
+	defer publisher.Close()
+	defer consumer.Close()
+
+	deliveries, err := consumer.ConsumeMessages(ctx)
+	if err != nil {
+		t.Fatalf("Failed to consume messages: %v", err)
+	}
+	err = publisher.PublishMessage(map[string]string{"restaurantId": "place-1"})
+	if err != nil {
+		t.Fatalf("Failed to publish message: %v", err)
+	}
+	delivery := receive(t, deliveries)
+	if string(delivery.Body) != `{"restaurantId":"place-1"}` || delivery.ContentType != "application/json" {
+		t.Errorf("Expected JSON body for place-1, but got %q (%s)", delivery.Body, delivery.ContentType)
+	}
+	if err = delivery.Ack(); err != nil {
+		t.Errorf("Expected ack to succeed, but got error: %v", err)
+	}
+}
+
+func TestMemoryQueueNackRequeues(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := NewMemoryQueue("test_nack_requeues")
+	defer q.Close()
+
+	deliveries, _ := q.ConsumeMessages(ctx)
+	q.PublishMessage("first")
+	receive(t, deliveries).Nack(true)
+	redelivered := receive(t, deliveries)
+	if string(redelivered.Body) != `"first"` {
+		t.Errorf("Expected nacked message to be redelivered, but got %q", redelivered.Body)
+	}
+}
+
+func TestMemoryQueueDelayedMessage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := NewMemoryQueue("test_delayed_message")
+	defer q.Close()
+
+	deliveries, _ := q.ConsumeMessages(ctx)
+	start := time.Now()
+	q.PublishDelayedMessage("later", 50*time.Millisecond)
+	delivery := receive(t, deliveries)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected delayed message after at least 50ms, but got it after %v", elapsed)
+	}
+	if string(delivery.Body) != `"later"` {
+		t.Errorf("Expected delayed message body, but got %q", delivery.Body)
+	}
+}
+
+type prioritizedMessage struct {
+	Name     string `json:"name"`
+	priority uint8
+}
+
+func (m prioritizedMessage) Properties() Properties {
+	return Properties{MessageId: m.Name, Priority: m.priority}
+}
+
+func TestMemoryQueuePriority(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := NewMemoryQueue("test_priority")
+	defer q.Close()
+
+	q.PublishMessage(prioritizedMessage{Name: "normal-1"})
+	q.PublishMessage(prioritizedMessage{Name: "normal-2"})
+	q.PublishMessage(prioritizedMessage{Name: "high", priority: 5})
+	deliveries, _ := q.ConsumeMessages(ctx)
+	for _, expected := range []string{"high", "normal-1", "normal-2"} {
+		delivery := receive(t, deliveries)
+		if delivery.MessageId != expected {
+			t.Errorf("Expected %s next, but got %s", expected, delivery.MessageId)
+		}
+	}
+}
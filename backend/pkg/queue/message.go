@@ -0,0 +1,48 @@
+package queue
+
+import (
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Message is implemented by bodies that carry their own AMQP properties (id, type, headers).
+// Bodies that don't implement it are published as plain JSON.
+type Message interface {
+	Properties() Properties
+}
+
+// MaxPriority is the highest message priority work queues are declared with; consumers get
+// higher priority messages first
+const MaxPriority = 10
+
+type Properties struct {
+	MessageId     string
+	Type          string
+	CorrelationId string
+	Timestamp     time.Time
+	Headers       map[string]interface{}
+	// Priority ranges from 0 (the default) to MaxPriority
+	Priority uint8
+}
+
+func newPublishing(body interface{}, bodyBytes []byte, contentType string) amqp.Publishing {
+	publishing := amqp.Publishing{
+		ContentType:  contentType,
+		DeliveryMode: amqp.Persistent,
+		Body:         bodyBytes,
+		Headers:      amqp.Table{},
+	}
+	if message, ok := body.(Message); ok {
+		props := message.Properties()
+		publishing.MessageId = props.MessageId
+		publishing.Type = props.Type
+		publishing.CorrelationId = props.CorrelationId
+		publishing.Timestamp = props.Timestamp
+		publishing.Priority = min(props.Priority, MaxPriority)
+		for key, value := range props.Headers {
+			publishing.Headers[key] = value
+		}
+	}
+	return publishing
+}
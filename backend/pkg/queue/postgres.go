@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"eatsavvy/pkg/db"
+	"eatsavvy/pkg/encoder"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	postgresPollInterval = 1 * time.Second
+	// postgresLeaseTimeout is how long a delivered message stays invisible to other consumers.
+	// If it is neither acked nor nacked by then (e.g. the consumer crashed) it is delivered again.
+	postgresLeaseTimeout = 10 * time.Minute
+)
+
+// PostgresQueue is a Queue backed by the public.queue_messages table. Consumers claim rows with
+// FOR UPDATE SKIP LOCKED and delays are expressed as a future run_at, so no broker is needed.
+type PostgresQueue struct {
+	queueName string
+	dbClient  *db.DatabaseClient
+}
+
+func NewPostgresQueue(queueName string) *PostgresQueue {
+	return &PostgresQueue{
+		queueName: queueName,
+		dbClient:  db.NewDatabaseClient(),
+	}
+}
+
+func (q *PostgresQueue) Close() {
+	q.dbClient.Close()
+}
+
+func (q *PostgresQueue) PublishMessage(body interface{}) error {
+	return q.insert(body, 0)
+}
+
+func (q *PostgresQueue) PublishDelayedMessage(body interface{}, delay time.Duration) error {
+	return q.insert(body, delay)
+}
+
+func (q *PostgresQueue) insert(body interface{}, delay time.Duration) error {
+	bodyBytes, err := encoder.ToJSON(body)
+	if err != nil {
+		slog.Error("[queue.PostgresQueue.insert] Failed to convert body to bytes", "error", err)
+		return err
+	}
+	publishing := newPublishing(body, bodyBytes, encoder.ContentTypeJSON)
+
+	_, err = q.dbClient.Db.Exec(q.dbClient.Ctx,
+		`INSERT INTO public.queue_messages (queue_name, message_id, content_type, headers, body, run_at, priority)
+			VALUES ($1, $2, $3, $4, $5, NOW() + make_interval(secs => $6), $7)`,
+		q.queueName, publishing.MessageId, publishing.ContentType, map[string]interface{}(publishing.Headers),
+		publishing.Body, delay.Seconds(), int16(publishing.Priority),
+	)
+	if err != nil {
+		slog.Error("[queue.PostgresQueue.insert] Failed to insert message", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (q *PostgresQueue) ConsumeMessages(ctx context.Context) (<-chan Delivery, error) {
+	deliveries := make(chan Delivery)
+	go func() {
+		defer close(deliveries)
+		for {
+			delivery, err := q.claim()
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				slog.Error("[queue.PostgresQueue.ConsumeMessages] Failed to claim message", "error", err)
+			}
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(postgresPollInterval):
+				}
+				continue
+			}
+			select {
+			case deliveries <- delivery:
+			case <-ctx.Done():
+				// Release the lease so another consumer can pick it up straight away
+				delivery.Nack(true)
+				return
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+// claim leases the next ready message, returning pgx.ErrNoRows if there is none
+func (q *PostgresQueue) claim() (Delivery, error) {
+	var id int64
+	var delivery Delivery
+	var messageId, contentType *string
+	var priority int16
+	err := q.dbClient.Db.QueryRow(q.dbClient.Ctx,
+		`UPDATE public.queue_messages SET locked_until = NOW() + make_interval(secs => $2), attempts = attempts + 1
+			WHERE id = (
+				SELECT id FROM public.queue_messages
+				WHERE queue_name = $1 AND run_at <= NOW() AND (locked_until IS NULL OR locked_until < NOW())
+				ORDER BY priority DESC, run_at, id LIMIT 1 FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, message_id, content_type, headers, body, priority`,
+		q.queueName, postgresLeaseTimeout.Seconds(),
+	).Scan(&id, &messageId, &contentType, &delivery.Headers, &delivery.Body, &priority)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if messageId != nil {
+		delivery.MessageId = *messageId
+	}
+	if contentType != nil {
+		delivery.ContentType = *contentType
+	}
+	delivery.Priority = uint8(priority)
+	delivery.ack = func() error {
+		return q.ack(id)
+	}
+	delivery.nack = func(requeue bool) error {
+		if !requeue {
+			return q.ack(id)
+		}
+		return q.release(id)
+	}
+	return delivery, nil
+}
+
+func (q *PostgresQueue) ack(id int64) error {
+	_, err := q.dbClient.Db.Exec(q.dbClient.Ctx, `DELETE FROM public.queue_messages WHERE id = $1`, id)
+	if err != nil {
+		slog.Error("[queue.PostgresQueue.ack] Failed to delete message", "error", err, "id", id)
+		return err
+	}
+	return nil
+}
+
+func (q *PostgresQueue) release(id int64) error {
+	_, err := q.dbClient.Db.Exec(q.dbClient.Ctx, `UPDATE public.queue_messages SET locked_until = NULL WHERE id = $1`, id)
+	if err != nil {
+		slog.Error("[queue.PostgresQueue.release] Failed to release message", "error", err, "id", id)
+		return err
+	}
+	return nil
+}
@@ -25,26 +25,18 @@ func (p *Publisher) Close() {
 	p.queueClient.Close()
 }
 
+// PublishMessage publishes body as JSON and returns once the broker has confirmed it
 func (p *Publisher) PublishMessage(body interface{}) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	bodyBytes, err := encoder.ToBytes(body)
+	bodyBytes, err := encoder.ToJSON(body)
 	slog.Info("[queue.Publisher.PublishMessage] Publishing message", "body", body)
 	if err != nil {
 		slog.Error("[queue.Publisher.PublishMessage] Failed to convert body to bytes", "error", err)
 		return err
 	}
-	err = p.queueClient.queue.PublishWithContext(
-		ctx,
-		"",
-		p.queueClient.queueName,
-		false,
-		false,
-		amqp.Publishing{
-			Body: bodyBytes,
-		},
-	)
+	err = p.queueClient.publish(ctx, "", p.queueClient.queueName, newPublishing(body, bodyBytes, encoder.ContentTypeJSON))
 	if err != nil {
 		slog.Error("[queue.Publisher.PublishMessage] Failed to publish message", "error", err)
 		return err
@@ -56,28 +48,61 @@ func (p *Publisher) PublishDelayedMessage(body interface{}, delay time.Duration)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	bodyBytes, err := encoder.ToBytes(body)
+	bodyBytes, err := encoder.ToJSON(body)
 	if err != nil {
 		slog.Error("[queue.Publisher.PublishDelayedMessage] Failed to convert body to bytes", "error", err)
 		return err
 	}
-	err = p.queueClient.queue.PublishWithContext(
-		ctx,
-		"delayed-exchange",
-		p.queueClient.queueName,
-		false,
-		false,
-		amqp.Publishing{
-			Body: bodyBytes,
-			Headers: amqp.Table{
-				"x-delay": delay.Milliseconds(),
-			},
-		},
-	)
-	slog.Info("[queue.Publisher.PublishDelayedMessage] Published message with delay", "delay", delay.Milliseconds())
+	publishing := newPublishing(body, bodyBytes, encoder.ContentTypeJSON)
+	err = p.publishDelayed(ctx, publishing, delay)
 	if err != nil {
 		slog.Error("[queue.Publisher.PublishDelayedMessage] Failed to publish message", "error", err)
 		return err
 	}
+	slog.Info("[queue.Publisher.PublishDelayedMessage] Published message with delay", "delay", delay.Milliseconds())
 	return nil
 }
+
+func (p *Publisher) publishDelayed(ctx context.Context, publishing amqp.Publishing, delay time.Duration) error {
+	if p.queueClient.delayStrategy != DelayStrategyTTL {
+		publishing.Headers["x-delay"] = delay.Milliseconds()
+		return p.queueClient.publish(ctx, "delayed-exchange", p.queueClient.queueName, publishing)
+	}
+
+	bucket, ok := delayBucket(delay)
+	if !ok {
+		// Shorter than the smallest bucket, deliver now
+		delete(publishing.Headers, HeaderDeliverAt)
+		return p.queueClient.publish(ctx, "", p.queueClient.queueName, publishing)
+	}
+	if _, ok := publishing.Headers[HeaderDeliverAt]; !ok {
+		publishing.Headers[HeaderDeliverAt] = time.Now().Add(delay).UnixMilli()
+	}
+	return p.queueClient.publish(ctx, "", bucketQueueName(p.queueClient.queueName, bucket), publishing)
+}
+
+// forwardDelayed moves a message that came back from a TTL bucket before it was due into the next bucket
+func (p *Publisher) forwardDelayed(msg amqp.Delivery, remaining time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	headers := amqp.Table{}
+	for key, value := range msg.Headers {
+		// RabbitMQ records each dead-lettering in x-death, which would grow with every hop
+		if key != "x-death" {
+			headers[key] = value
+		}
+	}
+	publishing := amqp.Publishing{
+		ContentType:   msg.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		MessageId:     msg.MessageId,
+		Type:          msg.Type,
+		CorrelationId: msg.CorrelationId,
+		Timestamp:     msg.Timestamp,
+		Priority:      msg.Priority,
+		Headers:       headers,
+		Body:          msg.Body,
+	}
+	return p.publishDelayed(ctx, publishing, remaining)
+}
@@ -1,76 +1,278 @@
 package queue
 
 import (
+	"context"
+	"eatsavvy/pkg/backoff"
+	"errors"
 	"log/slog"
 	"os"
+	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+var ErrClosed = errors.New("queue client is closed")
+
+const (
+	minReconnectDelay = 1 * time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// reconnectBackoff is how long to wait after consecutive failed reconnects
+var reconnectBackoff = backoff.Exponential{Initial: minReconnectDelay, Max: maxReconnectDelay}
+
+// QueueClient owns a connection and channel to RabbitMQ. It reconnects in the background with
+// exponential backoff whenever either is closed, re-declaring the topology each time.
+// Callers get the current channel through channel(), which waits while a reconnect is in progress.
 type QueueClient struct {
+	mu        sync.RWMutex
 	queue     *amqp.Channel
 	conn      *amqp.Connection
+	ready     chan struct{} // closed once queue is usable, replaced on disconnect
+	done      chan struct{}
+	closed    bool
 	queueName string
+	// delayStrategy decides how delayed messages are held, see DelayStrategy
+	delayStrategy DelayStrategy
 }
 
 func NewQueueClient(queueName string) *QueueClient {
-	conn, err := amqp.Dial("amqp://" +
-		os.Getenv("RABBITMQ_USER") + ":" +
-		os.Getenv("RABBITMQ_PASSWORD") + "@" +
-		os.Getenv("RABBITMQ_HOST") + ":" +
-		os.Getenv("RABBITMQ_PORT") + "/")
+	qc := &QueueClient{
+		ready:         make(chan struct{}),
+		done:          make(chan struct{}),
+		queueName:     queueName,
+		delayStrategy: getDelayStrategy(),
+	}
+	go qc.run()
+	return qc
+}
+
+// run connects and then waits for the connection or channel to close, reconnecting until Close is called
+func (qc *QueueClient) run() {
+	attempt := 0
+	for {
+		conn, ch, err := qc.connect()
+		if isPreconditionFailed(err) {
+			// Redeclaring never succeeds until the existing queue is changed by hand, so don't keep retrying
+			slog.Error("[queue.run] Queue exists with different arguments, exiting", "queueName", qc.queueName, "error", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			attempt++
+			delay := reconnectBackoff.Delay(attempt)
+			slog.Error("[queue.run] Failed to connect, retrying", "queueName", qc.queueName, "error", err, "delay", delay)
+			select {
+			case <-qc.done:
+				return
+			case <-time.After(delay):
+			}
+			continue
+		}
+		attempt = 0
+
+		connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+		qc.mu.Lock()
+		if qc.closed {
+			qc.mu.Unlock()
+			ch.Close()
+			conn.Close()
+			return
+		}
+		qc.conn = conn
+		qc.queue = ch
+		close(qc.ready)
+		qc.mu.Unlock()
+		slog.Info("[queue.run] Connected", "queueName", qc.queueName)
+
+		select {
+		case <-qc.done:
+			return
+		case amqpErr := <-connClosed:
+			slog.Error("[queue.run] Connection closed, reconnecting", "queueName", qc.queueName, "error", amqpErr)
+		case amqpErr := <-chClosed:
+			slog.Error("[queue.run] Channel closed, reconnecting", "queueName", qc.queueName, "error", amqpErr)
+		}
+
+		qc.mu.Lock()
+		qc.queue = nil
+		qc.conn = nil
+		qc.ready = make(chan struct{})
+		qc.mu.Unlock()
+		// The connection may still be open if only the channel died; start over from a clean connection
+		if !conn.IsClosed() {
+			conn.Close()
+		}
+	}
+}
+
+func (qc *QueueClient) connect() (*amqp.Connection, *amqp.Channel, error) {
+	conn, err := amqp.Dial(generateConnectionString())
+	if err != nil {
+		return nil, nil, err
+	}
 
 	ch, err := conn.Channel()
 	if err != nil {
-		slog.Error("[queue.NewQueueClient] Failed to open a channel", "error", err)
+		slog.Error("[queue.connect] Failed to open a channel", "error", err)
+		conn.Close()
+		return nil, nil, err
 	}
 
-	// Declare a delayed message exchange
-	err = ch.ExchangeDeclare(
-		"delayed-exchange", "x-delayed-message", true, false, false, false, amqp.Table{
-			"x-delayed-type": "direct",
-		},
-	)
+	// Publisher confirms, so publishing only succeeds once the broker has taken responsibility for the message
+	err = ch.Confirm(false)
+	if err != nil {
+		slog.Error("[queue.connect] Failed to enable publisher confirms", "error", err)
+		conn.Close()
+		return nil, nil, err
+	}
 
+	err = declareTopology(ch, qc.queueName, qc.delayStrategy)
 	if err != nil {
-		slog.Error("[queue.NewQueueClient] Failed to declare delayed exchange", "error", err)
+		conn.Close()
+		return nil, nil, err
 	}
+	if oldName, ok := replacedQueue(qc.queueName); ok {
+		moveReplacedQueue(conn, oldName, qc.queueName)
+	}
+	return conn, ch, nil
+}
 
-	_, err = ch.QueueDeclare(
-		queueName, false, false, false, false, nil,
+// isPreconditionFailed reports whether the broker refused a declaration because it conflicts with what exists
+func isPreconditionFailed(err error) bool {
+	var amqpErr *amqp.Error
+	return errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed
+}
+
+func declareTopology(ch *amqp.Channel, queueName string, delayStrategy DelayStrategy) error {
+	// Durable so that queued jobs survive a broker restart (messages are published as persistent), and a
+	// priority queue so high priority messages jump ahead of a backlog
+	_, err := ch.QueueDeclare(
+		queueName, true, false, false, false, amqp.Table{
+			"x-max-priority": int32(MaxPriority),
+		},
 	)
 	if err != nil {
-		slog.Error("[queue.NewQueueClient] Failed to create queue", "error", err)
+		slog.Error("[queue.declareTopology] Failed to create queue", "error", err)
+		return err
 	}
 
-	// Bind the queue to the delayed exchange
-	err = ch.QueueBind(
-		queueName,          // queue name
-		queueName,          // routing key (same as queue name)
-		"delayed-exchange", // exchange
-		false,
-		nil,
-	)
+	if delayStrategy == DelayStrategyTTL {
+		err = declareDelayBuckets(ch, queueName)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Declare a delayed message exchange
+		err = ch.ExchangeDeclare(
+			"delayed-exchange", "x-delayed-message", true, false, false, false, amqp.Table{
+				"x-delayed-type": "direct",
+			},
+		)
+		if err != nil {
+			slog.Error("[queue.declareTopology] Failed to declare delayed exchange", "error", err)
+			return err
+		}
+
+		// Bind the queue to the delayed exchange
+		err = ch.QueueBind(
+			queueName,          // queue name
+			queueName,          // routing key (same as queue name)
+			"delayed-exchange", // exchange
+			false,
+			nil,
+		)
+		if err != nil {
+			slog.Error("[queue.declareTopology] Failed to bind queue to delayed exchange", "error", err)
+			return err
+		}
+	}
+
+	// Only hand each consumer one unacknowledged message at a time
+	err = ch.Qos(1, 0, false)
 	if err != nil {
-		slog.Error("[queue.NewQueueClient] Failed to bind queue to delayed exchange", "error", err)
+		slog.Error("[queue.declareTopology] Failed to set prefetch", "error", err)
+		return err
 	}
+	return nil
+}
 
-	return &QueueClient{
-		conn:      conn,
-		queue:     ch,
-		queueName: queueName,
+// channel returns the current channel, waiting for a reconnect if there isn't one
+func (qc *QueueClient) channel(ctx context.Context) (*amqp.Channel, error) {
+	for {
+		qc.mu.RLock()
+		ch, ready, closed := qc.queue, qc.ready, qc.closed
+		qc.mu.RUnlock()
+		if closed {
+			return nil, ErrClosed
+		}
+		if ch != nil && !ch.IsClosed() {
+			return ch, nil
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 }
 
-func (qc *QueueClient) Close() {
-	slog.Info("[queue.Close] Closing connection and channel", "queueName", qc.queueName)
-	err := qc.conn.Close()
+// publish sends a message and waits for the broker to confirm it
+func (qc *QueueClient) publish(ctx context.Context, exchange string, routingKey string, publishing amqp.Publishing) error {
+	ch, err := qc.channel(ctx)
+	if err != nil {
+		slog.Error("[queue.publish] No channel available", "error", err)
+		return err
+	}
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, false, false, publishing)
 	if err != nil {
-		slog.Error("[queue.Close] Failed to close connection", "error", err)
+		slog.Error("[queue.publish] Failed to publish message", "error", err)
+		return err
 	}
-	err = qc.queue.Close()
+	acked, err := confirmation.WaitContext(ctx)
 	if err != nil {
-		slog.Error("[queue.Close] Failed to close channel", "error", err)
+		slog.Error("[queue.publish] Failed waiting for publisher confirm", "error", err)
+		return err
+	}
+	if !acked {
+		return errors.New("message was nacked by the broker")
+	}
+	return nil
+}
+
+func (qc *QueueClient) Close() {
+	slog.Info("[queue.Close] Closing connection and channel", "queueName", qc.queueName)
+	qc.mu.Lock()
+	if qc.closed {
+		qc.mu.Unlock()
+		return
+	}
+	qc.closed = true
+	close(qc.done)
+	conn, ch := qc.conn, qc.queue
+	qc.mu.Unlock()
+
+	if ch != nil {
+		err := ch.Close()
+		if err != nil {
+			slog.Error("[queue.Close] Failed to close channel", "error", err)
+		}
+	}
+	if conn != nil {
+		err := conn.Close()
+		if err != nil {
+			slog.Error("[queue.Close] Failed to close connection", "error", err)
+		}
 	}
 	slog.Info("[queue.Close] Closed connection and channel", "queueName", qc.queueName)
 }
+
+func generateConnectionString() string {
+	return "amqp://" +
+		os.Getenv("RABBITMQ_USER") + ":" +
+		os.Getenv("RABBITMQ_PASSWORD") + "@" +
+		os.Getenv("RABBITMQ_HOST") + ":" +
+		os.Getenv("RABBITMQ_PORT") + "/"
+}
@@ -0,0 +1,52 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestDelayBucket(t *testing.T) {
+	tests := []struct {
+		delay  time.Duration
+		want   time.Duration
+		wantOk bool
+	}{
+		{delay: 5 * time.Second, want: 0, wantOk: false},
+		{delay: 10 * time.Second, want: 10 * time.Second, wantOk: true},
+		{delay: 45 * time.Minute, want: 15 * time.Minute, wantOk: true},
+		{delay: 2*time.Hour + 30*time.Minute, want: 1 * time.Hour, wantOk: true},
+		{delay: 7 * 24 * time.Hour, want: 24 * time.Hour, wantOk: true},
+	}
+
+	for _, tt := range tests {
+		got, ok := delayBucket(tt.delay)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("delayBucket(%v) = %v, %v, want %v, %v", tt.delay, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestRemainingDelay(t *testing.T) {
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	due := now.Add(90 * time.Minute).UnixMilli()
+	tests := []struct {
+		name    string
+		headers amqp.Table
+		want    time.Duration
+	}{
+		{name: "no header", headers: amqp.Table{}, want: 0},
+		{name: "due later", headers: amqp.Table{HeaderDeliverAt: due}, want: 90 * time.Minute},
+		{name: "already due", headers: amqp.Table{HeaderDeliverAt: now.Add(-time.Minute).UnixMilli()}, want: -time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := remainingDelay(tt.headers, now)
+			if got != tt.want {
+				t.Errorf("remainingDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
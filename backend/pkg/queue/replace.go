@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"log/slog"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	replacedMu sync.Mutex
+	// replaced maps a queue to the queue it replaced, see Replace
+	replaced = map[string]string{}
+)
+
+// Replace records that queueName replaces oldName, a RabbitMQ queue declared by an older build with arguments
+// that can't be redeclared (RabbitMQ refuses to change a queue's durability or arguments). Whenever a client for
+// queueName connects, it moves the messages left in oldName to queueName and deletes oldName once it is empty and
+// no longer consumed.
+func Replace(queueName string, oldName string) {
+	replacedMu.Lock()
+	defer replacedMu.Unlock()
+	replaced[queueName] = oldName
+}
+
+func replacedQueue(queueName string) (string, bool) {
+	replacedMu.Lock()
+	defer replacedMu.Unlock()
+	oldName, ok := replaced[queueName]
+	return oldName, ok
+}
+
+// moveReplacedQueue moves the messages in oldName to queueName and then deletes oldName. It uses its own channel,
+// since the broker closes a channel on any failed operation, e.g. when oldName doesn't exist. Failures are only
+// logged: the move is attempted again on the next connect.
+func moveReplacedQueue(conn *amqp.Connection, oldName string, queueName string) {
+	ch, err := conn.Channel()
+	if err != nil {
+		slog.Error("[queue.moveReplacedQueue] Failed to open a channel", "error", err)
+		return
+	}
+	defer ch.Close()
+
+	if _, err = ch.QueueDeclarePassive(oldName, false, false, false, false, nil); err != nil {
+		// Already deleted
+		return
+	}
+	if err = ch.Confirm(false); err != nil {
+		slog.Error("[queue.moveReplacedQueue] Failed to enable publisher confirms", "error", err)
+		return
+	}
+
+	moved := 0
+	for {
+		message, ok, err := ch.Get(oldName, false)
+		if err != nil {
+			slog.Error("[queue.moveReplacedQueue] Failed to get message", "oldName", oldName, "error", err)
+			return
+		}
+		if !ok {
+			break
+		}
+		confirmation, err := ch.PublishWithDeferredConfirm("", queueName, false, false, amqp.Publishing{
+			Headers:      message.Headers,
+			ContentType:  message.ContentType,
+			DeliveryMode: amqp.Persistent,
+			Priority:     message.Priority,
+			MessageId:    message.MessageId,
+			Body:         message.Body,
+		})
+		if err != nil || !confirmation.Wait() {
+			slog.Error("[queue.moveReplacedQueue] Failed to move message", "oldName", oldName, "queueName", queueName, "error", err)
+			message.Nack(false, true)
+			return
+		}
+		if err = message.Ack(false); err != nil {
+			slog.Error("[queue.moveReplacedQueue] Failed to ack moved message", "oldName", oldName, "error", err)
+			return
+		}
+		moved++
+	}
+
+	// Only if empty and unused, so messages published or consumed by older builds still running aren't lost
+	_, err = ch.QueueDelete(oldName, true, true, false)
+	if err != nil {
+		slog.Warn("[queue.moveReplacedQueue] Old queue is still in use, not deleting it yet", "oldName", oldName, "moved", moved, "error", err)
+		return
+	}
+	slog.Info("[queue.moveReplacedQueue] Moved messages and deleted old queue", "oldName", oldName, "queueName", queueName, "moved", moved)
+}
@@ -0,0 +1,121 @@
+// Sign in with the OIDC provider using the authorization code flow with PKCE. The ID token is kept in
+// sessionStorage and sent to the API as a bearer token.
+const OIDC_ISSUER = import.meta.env.VITE_OIDC_ISSUER as string | undefined;
+const OIDC_CLIENT_ID = import.meta.env.VITE_OIDC_CLIENT_ID as string | undefined;
+
+const TOKEN_KEY = 'eatsavvy.idToken';
+const PENDING_KEY = 'eatsavvy.pendingLogin';
+
+interface PendingLogin {
+  state: string;
+  codeVerifier: string;
+  // Echoed in the ID token, tying it to this login so a token issued for another one is refused
+  nonce: string;
+}
+
+export const loginEnabled = Boolean(OIDC_ISSUER && OIDC_CLIENT_ID);
+
+function redirectUri(): string {
+  return `${window.location.origin}${window.location.pathname}`;
+}
+
+function randomString(): string {
+  const bytes = crypto.getRandomValues(new Uint8Array(32));
+  return base64Url(bytes);
+}
+
+function base64Url(bytes: Uint8Array): string {
+  return btoa(String.fromCharCode(...bytes)).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+}
+
+async function discover(): Promise<{ authorization_endpoint: string; token_endpoint: string }> {
+  const response = await fetch(`${OIDC_ISSUER!.replace(/\/$/, '')}/.well-known/openid-configuration`);
+  if (!response.ok) {
+    throw new Error(`Failed to reach sign in provider: ${response.statusText}`);
+  }
+  return response.json();
+}
+
+function decodeClaims(token: string): { exp?: number; nonce?: string } {
+  return JSON.parse(atob(token.split('.')[1].replace(/-/g, '+').replace(/_/g, '/')));
+}
+
+// Returns the signed in user's token, or null if they aren't signed in or it has expired
+export function getToken(): string | null {
+  const token = sessionStorage.getItem(TOKEN_KEY);
+  if (!token) return null;
+  try {
+    const claims = decodeClaims(token);
+    if (typeof claims.exp === 'number' && claims.exp * 1000 < Date.now()) {
+      sessionStorage.removeItem(TOKEN_KEY);
+      return null;
+    }
+  } catch {
+    return null;
+  }
+  return token;
+}
+
+export async function login(): Promise<void> {
+  const { authorization_endpoint } = await discover();
+  const pending: PendingLogin = { state: randomString(), codeVerifier: randomString(), nonce: randomString() };
+  sessionStorage.setItem(PENDING_KEY, JSON.stringify(pending));
+  const challenge = new Uint8Array(await crypto.subtle.digest('SHA-256', new TextEncoder().encode(pending.codeVerifier)));
+  const params = new URLSearchParams({
+    response_type: 'code',
+    client_id: OIDC_CLIENT_ID!,
+    redirect_uri: redirectUri(),
+    scope: 'openid email profile',
+    state: pending.state,
+    nonce: pending.nonce,
+    code_challenge: base64Url(challenge),
+    code_challenge_method: 'S256',
+  });
+  window.location.assign(`${authorization_endpoint}?${params}`);
+}
+
+export function logout(): void {
+  sessionStorage.removeItem(TOKEN_KEY);
+}
+
+// Completes a login when the provider redirects back with a code. Returns whether a login was completed.
+export async function handleRedirect(): Promise<boolean> {
+  const url = new URL(window.location.href);
+  const code = url.searchParams.get('code');
+  const pendingJson = sessionStorage.getItem(PENDING_KEY);
+  if (!loginEnabled || !code || !pendingJson) return false;
+
+  sessionStorage.removeItem(PENDING_KEY);
+  window.history.replaceState(null, '', redirectUri());
+  const pending: PendingLogin = JSON.parse(pendingJson);
+  if (url.searchParams.get('state') !== pending.state) {
+    throw new Error('Sign in failed: state mismatch');
+  }
+  const { token_endpoint } = await discover();
+  const response = await fetch(token_endpoint, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/x-www-form-urlencoded' },
+    body: new URLSearchParams({
+      grant_type: 'authorization_code',
+      code,
+      client_id: OIDC_CLIENT_ID!,
+      redirect_uri: redirectUri(),
+      code_verifier: pending.codeVerifier,
+    }),
+  });
+  if (!response.ok) {
+    throw new Error(`Sign in failed: ${response.statusText}`);
+  }
+  const { id_token }: { id_token: string } = await response.json();
+  let nonce: string | undefined;
+  try {
+    nonce = decodeClaims(id_token).nonce;
+  } catch {
+    nonce = undefined;
+  }
+  if (nonce !== pending.nonce) {
+    throw new Error('Sign in failed: nonce mismatch');
+  }
+  sessionStorage.setItem(TOKEN_KEY, id_token);
+  return true;
+}
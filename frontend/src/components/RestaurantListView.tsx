@@ -1,18 +1,22 @@
 import { useMemo, useState, useEffect } from 'react';
 import { SearchBar } from './SearchBar';
 import { RestaurantRow, Restaurant, TimeRange } from './RestaurantRow';
-import { UtensilsCrossed, Sparkles, Loader2, AlertCircle } from 'lucide-react';
+import { UtensilsCrossed, Sparkles, Loader2, AlertCircle, LogIn, LogOut } from 'lucide-react';
+import { getToken, handleRedirect, login, loginEnabled, logout } from '../auth';
 
 const API_BASE_URL = import.meta.env.VITE_EATSAVVY_API_URL || 'https://api.eatsavvy.org';
+const API_URL = `${API_BASE_URL}/v1`;
+// A read-only key for browsing signed out; searching and enriching need a signed in user
 const API_KEY = import.meta.env.VITE_EATSAVVY_API_KEY;
 
-// Helper to create authenticated fetch requests
+// Helper to create authenticated fetch requests, as the signed in user if there is one
 function authFetch(url: string, options: RequestInit = {}): Promise<Response> {
+  const credential = getToken() ?? API_KEY;
   return fetch(url, {
     ...options,
     headers: {
       ...options.headers,
-      ...(API_KEY ? { 'Authorization': `Bearer ${API_KEY}` } : {}),
+      ...(credential ? { 'Authorization': `Bearer ${credential}` } : {}),
     },
   });
 }
@@ -31,6 +35,8 @@ interface ApiRestaurant {
   address: string;
   phoneNumber: string;
   openHours: TimeRange[] | null;
+  timeZone: string | null;
+  openNow: boolean | null;
   nutritionInfo: ApiNutritionInfo | null;
   rating: number | null;
   enrichmentStatus: Restaurant['enrichment_status'];
@@ -45,6 +51,8 @@ function transformRestaurant(api: ApiRestaurant): Restaurant {
     address: api.address || '',
     rating: api.rating,
     openHours: api.openHours,
+    timeZone: api.timeZone ?? null,
+    openNow: api.openNow ?? null,
     nutrition: {
       oil: api.nutritionInfo?.oil || 'Unknown',
       nutFree: api.nutritionInfo?.nutFree || false,
@@ -62,14 +70,20 @@ export function RestaurantListView() {
   const [error, setError] = useState<string | null>(null);
   // Track whether we're showing API search results (skip local filtering)
   const [isApiSearchResult, setIsApiSearchResult] = useState(false);
+  const [signedIn, setSignedIn] = useState(() => getToken() !== null);
+  // Show only the restaurants the signed in user asked to enrich
+  const [showMine, setShowMine] = useState(false);
 
   useEffect(() => {
     async function fetchRestaurants() {
       try {
         setLoading(true);
         setError(null);
+        if (await handleRedirect()) {
+          setSignedIn(true);
+        }
         
-        const response = await authFetch(`${API_BASE_URL}/restaurant`);
+        const response = await authFetch(`${API_URL}/${showMine ? 'me/restaurants' : 'restaurant'}`);
         if (!response.ok) {
           throw new Error(`Failed to fetch restaurants: ${response.statusText}`);
         }
@@ -83,7 +97,21 @@ export function RestaurantListView() {
     }
 
     fetchRestaurants();
-  }, []);
+  }, [signedIn, showMine]);
+
+  const handleLogin = async () => {
+    try {
+      await login();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to sign in');
+    }
+  };
+
+  const handleLogout = () => {
+    logout();
+    setShowMine(false);
+    setSignedIn(false);
+  };
 
   // Local filtering for real-time search as you type (only when not showing API search results)
   const filteredData = useMemo(() => {
@@ -115,7 +143,7 @@ export function RestaurantListView() {
         setLoading(true);
         setError(null);
         setIsApiSearchResult(false);
-        const response = await authFetch(`${API_BASE_URL}/restaurant`);
+        const response = await authFetch(`${API_URL}/restaurant`);
         if (!response.ok) {
           throw new Error(`Failed to fetch restaurants: ${response.statusText}`);
         }
@@ -128,11 +156,15 @@ export function RestaurantListView() {
       }
       return;
     }
+    // Signed out, the query only filters the restaurants already listed
+    if (!signedIn) {
+      return;
+    }
 
     try {
       setLoading(true);
       setError(null);
-      const response = await authFetch(`${API_BASE_URL}/search`, {
+      const response = await authFetch(`${API_URL}/search`, {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
@@ -165,7 +197,10 @@ export function RestaurantListView() {
   };
 
   const handleUpdatePhone = async (id: string, phoneNumber: string) => {
-    const response = await authFetch(`${API_BASE_URL}/restaurant/${id}`, {
+    if (!signedIn) {
+      throw new Error('Sign in to edit phone numbers');
+    }
+    const response = await authFetch(`${API_URL}/restaurant/${id}`, {
       method: 'PATCH',
       headers: {
         'Content-Type': 'application/json',
@@ -197,12 +232,12 @@ export function RestaurantListView() {
 
   const handleEnrich = async () => {
     const ids = Array.from(selectedIds);
-    if (ids.length === 0) return;
+    if (ids.length === 0 || !signedIn) return;
 
     try {
       setEnriching(true);
       setError(null);
-      const response = await authFetch(`${API_BASE_URL}/enrich`, {
+      const response = await authFetch(`${API_URL}/enrich`, {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
@@ -212,7 +247,10 @@ export function RestaurantListView() {
       if (!response.ok) {
         throw new Error(`Enrich failed: ${response.statusText}`);
       }
-      const enrichedRestaurants: ApiRestaurant[] = await response.json();
+      const { results }: { results: { id: string; outcome: 'enqueued' | 'skipped' | 'not_found' | 'error'; reason?: string; error?: string; restaurant?: ApiRestaurant }[] } = await response.json();
+      const enrichedRestaurants = results.flatMap(result => result.restaurant ? [result.restaurant] : []);
+      // 207: some restaurants couldn't be enriched, the rest were still queued
+      const failed = results.filter(result => result.outcome === 'not_found' || result.outcome === 'error');
       
       // Update the restaurants list with enriched data
       setRestaurants(prev => {
@@ -220,8 +258,11 @@ export function RestaurantListView() {
         return prev.map(r => enrichedMap.get(r.id) || r);
       });
       
-      // Clear selection after successful enrichment
-      setSelectedIds(new Set());
+      // Clear selection after successful enrichment, keeping the restaurants that failed selected
+      setSelectedIds(new Set(failed.map(result => result.id)));
+      if (failed.length > 0) {
+        setError(`Failed to enrich ${failed.length} ${failed.length === 1 ? 'restaurant' : 'restaurants'}: ${failed.map(result => result.error || result.reason).join(', ')}`);
+      }
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Failed to enrich restaurants');
     } finally {
@@ -251,8 +292,25 @@ export function RestaurantListView() {
                 </p>
               </div>
             </div>
-            <div className="w-full md:w-auto">
+            <div className="w-full md:w-auto flex items-center gap-3">
               <SearchBar value={searchQuery} onChange={handleQueryChange} onSearch={handleSearch} />
+              {signedIn && (
+                <button
+                  onClick={() => setShowMine(mine => !mine)}
+                  className={`px-3 py-2 text-sm rounded-lg whitespace-nowrap transition-colors ${showMine ? 'bg-sky-500/30 text-sky-300' : 'bg-zinc-800 text-zinc-400 hover:text-zinc-200'}`}
+                >
+                  My restaurants
+                </button>
+              )}
+              {loginEnabled && (signedIn ? (
+                <button onClick={handleLogout} className="flex items-center gap-1 px-3 py-2 text-sm text-zinc-400 hover:text-zinc-200 whitespace-nowrap" aria-label="Sign out">
+                  <LogOut className="w-4 h-4" /> Sign out
+                </button>
+              ) : (
+                <button onClick={handleLogin} className="flex items-center gap-1 px-3 py-2 text-sm text-sky-400 hover:text-sky-300 whitespace-nowrap" aria-label="Sign in">
+                  <LogIn className="w-4 h-4" /> Sign in
+                </button>
+              ))}
             </div>
           </div>
 
@@ -314,7 +372,7 @@ export function RestaurantListView() {
       </main>
 
       {/* Floating Enrich Button */}
-      {selectedIds.size > 0 && <div className="fixed bottom-8 left-1/2 transform -translate-x-1/2 z-20 animate-in slide-in-from-bottom-4 duration-300">
+      {selectedIds.size > 0 && signedIn && <div className="fixed bottom-8 left-1/2 transform -translate-x-1/2 z-20 animate-in slide-in-from-bottom-4 duration-300">
           <button 
             onClick={handleEnrich} 
             disabled={enriching}
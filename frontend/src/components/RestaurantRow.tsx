@@ -1,14 +1,14 @@
 import { useState, useMemo } from 'react';
 import { Phone, MapPin, Star, Droplet, ShieldAlert, Leaf, Salad, ChevronDown, ChevronUp, Clock } from 'lucide-react';
 
-// TimePoint represents a specific time on a weekday (from API, in UTC)
+// TimePoint represents a specific time on a weekday (from API, in the restaurant's time zone or UTC if it has none)
 export interface TimePoint {
   weekday: number; // 0 = Sunday, 1 = Monday, etc.
   hour: number;
   minute: number;
 }
 
-// TimeRange represents an open/close time range (from API, in UTC)
+// TimeRange represents an open/close time range (from API, see TimePoint)
 export interface TimeRange {
   open: TimePoint;
   close: TimePoint;
@@ -21,13 +21,15 @@ export interface Restaurant {
   address: string;
   rating: number | null;
   openHours: TimeRange[] | null;
+  timeZone: string | null;
+  openNow: boolean | null;
   nutrition: {
     oil: string;
     nutFree: boolean;
     accommodations: string;
     vegetablesUsed: string;
   };
-  enrichment_status: 'pending' | 'in_progress' | 'queued' | 'completed' | 'failed';
+  enrichment_status: 'pending' | 'in_progress' | 'queued' | 'completed' | 'failed' | 'cancelled';
 }
 
 const WEEKDAY_NAMES = ['Sun', 'Mon', 'Tue', 'Wed', 'Thu', 'Fri', 'Sat'];
@@ -59,13 +61,14 @@ function formatTime(hour: number, minute: number): string {
   return `${displayHour}:${displayMinute} ${period}`;
 }
 
-// Group time ranges by weekday (after converting to local time)
-function groupHoursByWeekday(openHours: TimeRange[]): Map<number, { open: string; close: string; openMinutes: number; closeMinutes: number }[]> {
+// Group time ranges by weekday. Hours with a time zone are shown in the restaurant's local time,
+// legacy UTC hours are converted to the browser's local time.
+function groupHoursByWeekday(openHours: TimeRange[], timeZone: string | null): Map<number, { open: string; close: string; openMinutes: number; closeMinutes: number }[]> {
   const grouped = new Map<number, { open: string; close: string; openMinutes: number; closeMinutes: number }[]>();
   
   for (const range of openHours) {
-    const localOpen = utcToLocal(range.open.weekday, range.open.hour, range.open.minute);
-    const localClose = utcToLocal(range.close.weekday, range.close.hour, range.close.minute);
+    const localOpen = timeZone ? range.open : utcToLocal(range.open.weekday, range.open.hour, range.open.minute);
+    const localClose = timeZone ? range.close : utcToLocal(range.close.weekday, range.close.hour, range.close.minute);
     
     const weekday = localOpen.weekday;
     if (!grouped.has(weekday)) {
@@ -82,25 +85,6 @@ function groupHoursByWeekday(openHours: TimeRange[]): Map<number, { open: string
   return grouped;
 }
 
-// Check if the restaurant is currently open based on grouped hours
-function isCurrentlyOpen(groupedHours: Map<number, { open: string; close: string; openMinutes: number; closeMinutes: number }[]> | null): boolean {
-  if (!groupedHours) return false;
-  
-  const now = new Date();
-  const currentWeekday = now.getDay();
-  const currentMinutes = now.getHours() * 60 + now.getMinutes();
-  
-  const todayHours = groupedHours.get(currentWeekday);
-  if (!todayHours) return false;
-  
-  return todayHours.some(({ openMinutes, closeMinutes }) => {
-    // Handle overnight hours (e.g., 10 PM - 2 AM)
-    if (closeMinutes < openMinutes) {
-      return currentMinutes >= openMinutes || currentMinutes < closeMinutes;
-    }
-    return currentMinutes >= openMinutes && currentMinutes < closeMinutes;
-  });
-}
 interface RestaurantRowProps {
   restaurant: Restaurant;
   isSelected: boolean;
@@ -151,11 +135,11 @@ export function RestaurantRow({
   // Memoize the grouped hours to avoid recalculating on every render
   const groupedHours = useMemo(() => {
     if (!restaurant.openHours || restaurant.openHours.length === 0) return null;
-    return groupHoursByWeekday(restaurant.openHours);
-  }, [restaurant.openHours]);
+    return groupHoursByWeekday(restaurant.openHours, restaurant.timeZone);
+  }, [restaurant.openHours, restaurant.timeZone]);
 
-  // Check if currently open
-  const isOpen = useMemo(() => isCurrentlyOpen(groupedHours), [groupedHours]);
+  // Open now is computed by the API
+  const isOpen = restaurant.openNow ?? false;
 
   const statusColors: Record<Restaurant['enrichment_status'], string> = {
     completed: 'bg-green-500/10 text-green-400 border-green-500/20',
@@ -163,6 +147,7 @@ export function RestaurantRow({
     queued: 'bg-blue-500/10 text-blue-400 border-blue-500/20',
     pending: 'bg-zinc-500/10 text-zinc-400 border-zinc-500/20',
     failed: 'bg-red-500/10 text-red-400 border-red-500/20',
+    cancelled: 'bg-zinc-500/10 text-zinc-500 border-zinc-500/20',
   };
 
   const displayStatus = restaurant.enrichment_status || 'pending';
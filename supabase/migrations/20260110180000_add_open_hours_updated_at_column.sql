@@ -0,0 +1 @@
+alter table if exists public.restaurants add column if not exists open_hours_updated_at timestamp with time zone;
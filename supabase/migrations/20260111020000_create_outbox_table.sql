@@ -0,0 +1,13 @@
+create table if not exists public.outbox (
+    id bigserial primary key,
+    queue_name text not null,
+    message_id text not null,
+    payload jsonb not null,
+    attempts int not null default 0,
+    last_error text,
+    available_at timestamp with time zone not null default now(),
+    published_at timestamp with time zone,
+    created_at timestamp with time zone not null default now()
+);
+
+create index if not exists outbox_pending_idx on public.outbox (available_at) where published_at is null;
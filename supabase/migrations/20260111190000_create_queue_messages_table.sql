@@ -0,0 +1,14 @@
+create table if not exists public.queue_messages (
+    id bigserial primary key,
+    queue_name text not null,
+    message_id text,
+    content_type text,
+    headers jsonb,
+    body bytea not null,
+    run_at timestamp with time zone not null default now(),
+    locked_until timestamp with time zone,
+    attempts int not null default 0,
+    created_at timestamp with time zone not null default now()
+);
+
+create index if not exists queue_messages_ready_idx on public.queue_messages (queue_name, run_at);
@@ -0,0 +1,2 @@
+alter table if exists public.restaurants add column if not exists enrichment_job_id text;
+alter table if exists public.restaurants add column if not exists enrichment_job_due_at timestamp with time zone;
@@ -0,0 +1,3 @@
+alter table if exists public.restaurants add column if not exists search_count int not null default 0;
+alter table if exists public.restaurants add column if not exists last_searched_at timestamp with time zone;
+alter table if exists public.restaurants add column if not exists refresh_scheduled_at timestamp with time zone;
@@ -0,0 +1 @@
+alter table if exists public.restaurants add column if not exists special_days jsonb;
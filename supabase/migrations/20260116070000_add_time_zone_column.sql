@@ -0,0 +1,2 @@
+-- IANA time zone of the restaurant. When set, open_hours are stored in local time; rows without it hold UTC hours.
+alter table if exists public.restaurants add column if not exists time_zone text;
@@ -0,0 +1,34 @@
+-- Publishes enrichment status and nutrition info changes on the restaurant_events channel, so every API replica
+-- listening with LISTEN can push them to its clients. Notifications are only delivered once the change commits.
+create or replace function public.notify_restaurant_event() returns trigger
+language plpgsql as $$
+declare
+    payload jsonb;
+begin
+    if tg_op = 'UPDATE'
+        and new.enrichment_status is not distinct from old.enrichment_status
+        and new.nutrition_info is not distinct from old.nutrition_info then
+        return new;
+    end if;
+
+    payload := jsonb_build_object(
+        'id', new.places_id,
+        'enrichmentStatus', new.enrichment_status,
+        'previousEnrichmentStatus', case when tg_op = 'UPDATE' then old.enrichment_status end,
+        'nutritionInfo', new.nutrition_info,
+        'updatedAt', new.updated_at
+    );
+    -- Notification payloads are limited to 8000 bytes; clients refetch the restaurant when nutrition info is left out
+    if octet_length(payload::text) > 7900 then
+        payload := (payload - 'nutritionInfo') || jsonb_build_object('truncated', true);
+    end if;
+
+    perform pg_notify('restaurant_events', payload::text);
+    return new;
+end;
+$$;
+
+drop trigger if exists restaurant_events on public.restaurants;
+create trigger restaurant_events
+    after insert or update on public.restaurants
+    for each row execute function public.notify_restaurant_event();
@@ -0,0 +1,25 @@
+create table if not exists public.webhook_subscriptions (
+    id uuid primary key default gen_random_uuid(),
+    url text not null,
+    secret text not null,
+    event_types text[] not null,
+    created_at timestamp with time zone not null default now()
+);
+
+create table if not exists public.webhook_deliveries (
+    id bigserial primary key,
+    subscription_id uuid not null references public.webhook_subscriptions (id) on delete cascade,
+    event_id uuid not null,
+    event_type text not null,
+    payload jsonb not null,
+    status text not null default 'pending',
+    attempts int not null default 0,
+    last_status_code int,
+    last_error text,
+    next_attempt_at timestamp with time zone not null default now(),
+    delivered_at timestamp with time zone,
+    created_at timestamp with time zone not null default now()
+);
+
+create index if not exists webhook_deliveries_pending_idx on public.webhook_deliveries (next_attempt_at) where status = 'pending';
+create index if not exists webhook_deliveries_subscription_idx on public.webhook_deliveries (subscription_id, created_at desc);
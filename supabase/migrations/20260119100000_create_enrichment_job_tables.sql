@@ -0,0 +1,20 @@
+create table if not exists public.enrichment_jobs (
+    id uuid primary key default gen_random_uuid(),
+    requested_by text not null,
+    cancelled_at timestamp with time zone,
+    created_at timestamp with time zone not null default now(),
+    updated_at timestamp with time zone not null default now()
+);
+
+create table if not exists public.enrichment_job_items (
+    job_id uuid not null references public.enrichment_jobs (id) on delete cascade,
+    places_id text not null,
+    status text not null,
+    reason text,
+    created_at timestamp with time zone not null default now(),
+    updated_at timestamp with time zone not null default now(),
+    primary key (job_id, places_id)
+);
+
+-- A restaurant has at most one queued or in progress item, which the worker and call reports update
+create index if not exists enrichment_job_items_active_idx on public.enrichment_job_items (places_id) where status in ('queued', 'in_progress');
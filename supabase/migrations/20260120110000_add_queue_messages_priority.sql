@@ -0,0 +1,4 @@
+alter table if exists public.queue_messages add column if not exists priority smallint not null default 0;
+
+drop index if exists public.queue_messages_ready_idx;
+create index if not exists queue_messages_ready_idx on public.queue_messages (queue_name, priority desc, run_at);
@@ -0,0 +1,11 @@
+create table if not exists public.api_keys (
+    id uuid primary key default gen_random_uuid(),
+    name text not null,
+    prefix text not null,
+    key_hash text not null unique,
+    scopes text[] not null,
+    expires_at timestamp with time zone,
+    revoked_at timestamp with time zone,
+    last_used_at timestamp with time zone,
+    created_at timestamp with time zone not null default now()
+);
@@ -0,0 +1,17 @@
+create table if not exists public.users (
+    id uuid primary key default gen_random_uuid(),
+    issuer text not null,
+    subject text not null,
+    email text,
+    name text,
+    created_at timestamp with time zone not null default now(),
+    last_seen_at timestamp with time zone not null default now(),
+    unique (issuer, subject)
+);
+
+alter table public.enrichment_jobs
+    add column if not exists requested_by_user_id uuid references public.users (id) on delete set null;
+
+create index if not exists enrichment_jobs_requested_by_user_id_idx
+    on public.enrichment_jobs (requested_by_user_id, created_at desc)
+    where requested_by_user_id is not null;
@@ -0,0 +1,13 @@
+create table if not exists public.dietary_profiles (
+    id uuid primary key default gen_random_uuid(),
+    owner_user_id uuid references public.users (id) on delete cascade,
+    name text not null,
+    allergies text[] not null default '{}',
+    avoided_oils text[] not null default '{}',
+    diet text,
+    must_have_vegetables text[] not null default '{}',
+    created_at timestamp with time zone not null default now(),
+    updated_at timestamp with time zone not null default now()
+);
+
+create index if not exists dietary_profiles_owner_user_id_idx on public.dietary_profiles (owner_user_id);
@@ -0,0 +1,17 @@
+alter table public.enrichment_jobs
+    add column if not exists requested_by_key_id uuid references public.api_keys (id) on delete set null;
+
+create table if not exists public.usage_ledger (
+    id bigserial primary key,
+    key_id uuid references public.api_keys (id) on delete set null,
+    user_id uuid references public.users (id) on delete set null,
+    enrichment_job_id uuid references public.enrichment_jobs (id) on delete set null,
+    kind text not null,
+    places_id text,
+    reference text,
+    cost_usd numeric(12, 6) not null default 0,
+    created_at timestamp with time zone not null default now()
+);
+
+create index if not exists usage_ledger_key_id_idx on public.usage_ledger (key_id, created_at) where key_id is not null;
+create index if not exists usage_ledger_user_id_idx on public.usage_ledger (user_id, created_at) where user_id is not null;
@@ -0,0 +1,6 @@
+-- Shared rate limit state for API replicas. tat is the bucket's theoretical arrival time (see internal/ratelimit);
+-- rows whose tat has passed are equivalent to a full bucket and can be deleted.
+create unlogged table if not exists public.rate_limit_buckets (
+    key text primary key,
+    tat timestamp with time zone not null
+);
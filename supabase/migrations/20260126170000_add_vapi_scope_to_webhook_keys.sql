@@ -0,0 +1,4 @@
+-- /process-eocr moved from the webhook scope to its own vapi scope. Existing webhook keys keep working until
+-- they are replaced with a vapi key for Vapi and a webhook key for managing subscriptions.
+update public.api_keys set scopes = array_append(scopes, 'vapi')
+    where 'webhook' = any(scopes) and not 'vapi' = any(scopes);
@@ -0,0 +1,2 @@
+-- The outbox relay deletes rows once they have been published for OUTBOX_RETENTION
+create index if not exists outbox_published_idx on public.outbox (published_at) where published_at is not null;